@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"anime-watchlist/backend/application"
+	"anime-watchlist/backend/domain"
+)
+
+type AdminHandlers struct {
+	backupService *application.BackupService
+}
+
+func NewAdminHandlers(backupService *application.BackupService) *AdminHandlers {
+	return &AdminHandlers{backupService: backupService}
+}
+
+// Backup handles POST /api/admin/backup, which snapshots the database into
+// the backup directory while the server keeps running.
+func (h *AdminHandlers) Backup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	backup, err := h.backupService.Backup()
+	if errors.Is(err, domain.ErrBackupPostgres) {
+		respondWithError(w, http.StatusConflict, "Backups are not available with PostgreSQL", err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to back up database", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, backup)
+}
+
+// GetBackups handles GET /api/admin/backups.
+func (h *AdminHandlers) GetBackups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	backups, err := h.backupService.ListBackups()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to list backups", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, backups)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"anime-watchlist/backend/application"
+	"anime-watchlist/backend/domain"
+)
+
+// maxAnilistDump caps uploaded MediaListCollection dumps.
+const maxAnilistDump = 32 << 20
+
+type AnilistImportHandlers struct {
+	importService *application.AnilistImportService
+}
+
+func NewAnilistImportHandlers(importService *application.AnilistImportService) *AnilistImportHandlers {
+	return &AnilistImportHandlers{importService: importService}
+}
+
+// Import handles POST /api/import/anilist?username=&strategy=&dry_run=. With
+// a username the list is fetched from AniList; otherwise the body is a saved
+// MediaListCollection.
+func (h *AnilistImportHandlers) Import(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	strategy, dryRun, ok := listImportOptions(w, r)
+	if !ok {
+		return
+	}
+
+	var result *domain.ListImportResult
+	var err error
+	if userName := r.URL.Query().Get("username"); userName != "" {
+		result, err = h.importService.ImportUser(userName, strategy, dryRun)
+	} else {
+		data, readErr := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAnilistDump))
+		if readErr != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", readErr.Error())
+			return
+		}
+		result, err = h.importService.ImportDump(data, strategy, dryRun)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrAnilistUserNotFound) {
+			respondWithError(w, http.StatusNotFound, "AniList user not found", err.Error())
+			return
+		}
+		respondWithListImportError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// listImportOptions reads the strategy and dry_run parameters of list
+// imports, responding with an error when dry_run is invalid.
+func listImportOptions(w http.ResponseWriter, r *http.Request) (string, bool, bool) {
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid dry_run", "dry_run must be true or false")
+			return "", false, false
+		}
+		dryRun = parsed
+	}
+	return r.URL.Query().Get("strategy"), dryRun, true
+}
+
+func respondWithListImportError(w http.ResponseWriter, err error) {
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondWithError(w, http.StatusBadRequest, "Invalid import", validationErr.Message)
+	case errors.Is(err, domain.ErrRateLimited):
+		respondWithError(w, http.StatusTooManyRequests, "Rate limited by AniList", "try again in a minute")
+	default:
+		respondWithError(w, http.StatusInternalServerError, "Failed to import", err.Error())
+	}
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"anime-watchlist/backend/application"
+	"anime-watchlist/backend/domain"
+)
+
+type AnilistSyncHandlers struct {
+	syncService *application.AnilistSyncService
+}
+
+func NewAnilistSyncHandlers(syncService *application.AnilistSyncService) *AnilistSyncHandlers {
+	return &AnilistSyncHandlers{syncService: syncService}
+}
+
+// Authorize handles GET /api/anilist/authorize, which sends the user to
+// AniList to connect their account.
+func (h *AnilistSyncHandlers) Authorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	authorizeURL, err := h.syncService.AuthorizeURL()
+	if err != nil {
+		respondWithAnilistSyncError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, authorizeURL, http.StatusFound)
+}
+
+// Callback handles GET /api/anilist/callback?code=&state=, where AniList
+// sends the user back after they log in. The first sync starts in the
+// background and the user lands back on the app.
+func (h *AnilistSyncHandlers) Callback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		respondWithError(w, http.StatusBadRequest, "AniList login failed", query.Get("error_description"))
+		return
+	}
+
+	account, err := h.syncService.Connect(query.Get("code"), query.Get("state"))
+	if err != nil {
+		respondWithAnilistSyncError(w, err)
+		return
+	}
+
+	go func() {
+		if _, err := h.syncService.Sync(); err != nil {
+			log.Printf("First AniList sync for %s failed: %v", account.UserName, err)
+		}
+	}()
+
+	http.Redirect(w, r, "/?anilist=connected", http.StatusFound)
+}
+
+// HandleAccount handles GET and DELETE /api/anilist/account. DELETE
+// disconnects the account and drops changes that were not pushed.
+func (h *AnilistSyncHandlers) HandleAccount(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		status, err := h.syncService.Status()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to get AniList account", err.Error())
+			return
+		}
+		respondWithJSON(w, http.StatusOK, status)
+
+	case http.MethodDelete:
+		if err := h.syncService.Disconnect(); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to disconnect AniList account", err.Error())
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Disconnected AniList account"})
+
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+	}
+}
+
+// Sync handles POST /api/anilist/sync, which syncs with the connected
+// account now instead of waiting for the schedule.
+func (h *AnilistSyncHandlers) Sync(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	result, err := h.syncService.Sync()
+	if err != nil {
+		respondWithAnilistSyncError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func respondWithAnilistSyncError(w http.ResponseWriter, err error) {
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		respondWithError(w, http.StatusBadRequest, "Invalid request", validationErr.Message)
+	case errors.Is(err, domain.ErrAnilistOAuthDisabled):
+		respondWithError(w, http.StatusServiceUnavailable, "AniList login is not configured", "set ANILIST_CLIENT_ID and ANILIST_CLIENT_SECRET")
+	case errors.Is(err, domain.ErrInvalidOAuthState):
+		respondWithError(w, http.StatusBadRequest, "Invalid login state", "start connecting the account again")
+	case errors.Is(err, domain.ErrAnilistNotConnected):
+		respondWithError(w, http.StatusConflict, "No AniList account connected", "")
+	case errors.Is(err, domain.ErrAnilistUnauthorized):
+		respondWithError(w, http.StatusUnauthorized, "AniList rejected the access token", "connect the account again")
+	case errors.Is(err, domain.ErrRateLimited):
+		respondWithError(w, http.StatusTooManyRequests, "Rate limited by AniList", "try again in a minute")
+	default:
+		respondWithError(w, http.StatusBadGateway, "AniList sync failed", err.Error())
+	}
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -113,6 +114,29 @@ func (h *Handlers) HandleWatchlist(w http.ResponseWriter, r *http.Request) {
 
 		respondWithJSON(w, http.StatusOK, map[string]string{"message": "Removed from watchlist"})
 
+	case "PUT":
+		var update domain.WatchlistUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		item, err := h.animeService.UpdateWatchlistItem(anilistID, update)
+		if err != nil {
+			var validationErr *domain.ValidationError
+			switch {
+			case errors.As(err, &validationErr):
+				respondWithError(w, http.StatusBadRequest, "Invalid watchlist entry", validationErr.Message)
+			case err.Error() == "anime not in watchlist":
+				respondWithError(w, http.StatusNotFound, "Anime not in watchlist", err.Error())
+			default:
+				respondWithError(w, http.StatusInternalServerError, "Failed to update watchlist entry", err.Error())
+			}
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, item)
+
 	default:
 		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
 	}
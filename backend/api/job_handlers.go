@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"anime-watchlist/backend/application"
+	"anime-watchlist/backend/domain"
+)
+
+type JobHandlers struct {
+	jobRunner *application.JobRunner
+}
+
+func NewJobHandlers(jobRunner *application.JobRunner) *JobHandlers {
+	return &JobHandlers{jobRunner: jobRunner}
+}
+
+// StartBulkAutoMap queues a background auto-map job and returns immediately.
+// A limit of zero (the default) processes the whole unmapped backlog.
+func (h *JobHandlers) StartBulkAutoMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	var req struct {
+		Limit int `json:"limit"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		req.Limit = 0
+	}
+
+	if req.Limit < 0 {
+		req.Limit = 0
+	}
+
+	job, err := h.jobRunner.StartBulkAutoMap(req.Limit)
+	if errors.Is(err, domain.ErrJobAlreadyRunning) {
+		respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":  "Bulk auto-map already running",
+			"job_id": job.ID,
+			"job":    job,
+		})
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to start bulk auto-map", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message": "Bulk auto-map started",
+		"job_id":  job.ID,
+		"job":     job,
+	})
+}
+
+func (h *JobHandlers) GetJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.jobRunner.GetRecentJobs()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get jobs", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, jobs)
+}
+
+// HandleJob serves GET (progress and per-show outcomes) and DELETE (cancel)
+// on /api/jobs/{id}.
+func (h *JobHandlers) HandleJob(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 3 {
+		respondWithError(w, http.StatusBadRequest, "Invalid path", "Expected /api/jobs/{id}")
+		return
+	}
+
+	jobID := pathParts[2]
+
+	switch r.Method {
+	case http.MethodGet:
+		job, err := h.jobRunner.GetJob(jobID)
+		if err != nil {
+			respondWithError(w, jobErrorStatus(err), "Failed to get job", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, job)
+
+	case http.MethodDelete:
+		job, err := h.jobRunner.CancelJob(jobID)
+		if err != nil {
+			respondWithError(w, jobErrorStatus(err), "Failed to cancel job", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Job cancellation requested",
+			"job":     job,
+		})
+
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+	}
+}
+
+func jobErrorStatus(err error) int {
+	if errors.Is(err, domain.ErrJobNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"anime-watchlist/backend/application"
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/mal"
+)
+
+// maxMALUpload caps uploaded MAL exports. mal.Read applies the same cap to
+// gzipped ones once unpacked.
+const maxMALUpload = mal.MaxExportSize
+
+type MALHandlers struct {
+	malService *application.MALService
+}
+
+func NewMALHandlers(malService *application.MALService) *MALHandlers {
+	return &MALHandlers{malService: malService}
+}
+
+// Import handles POST /api/import/mal?strategy=&dry_run=. The MAL export,
+// gzipped or not, is either the request body or the "file" field of a form
+// upload.
+func (h *MALHandlers) Import(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	strategy, dryRun, ok := listImportOptions(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxMALUpload)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", "upload the export as the file field")
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	result, err := h.malService.Import(body, strategy, dryRun)
+	if err != nil {
+		respondWithListImportError(w, err)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// Export handles GET /api/export/mal, which downloads the watchlist as a MAL
+// export that MAL's import page accepts.
+func (h *MALHandlers) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	entries, err := h.malService.Export()
+	if err != nil {
+		if errors.Is(err, domain.ErrRateLimited) {
+			respondWithError(w, http.StatusTooManyRequests, "Rate limited by AniList", "try again in a minute")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to export", err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := mal.Write(&buf, entries); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export", err.Error())
+		return
+	}
+
+	filename := "animelist-" + time.Now().Format("20060102") + ".xml"
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
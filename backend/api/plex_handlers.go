@@ -2,23 +2,37 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"anime-watchlist/backend/application"
 	"anime-watchlist/backend/domain"
 	"anime-watchlist/backend/infrastructure/database"
+	"anime-watchlist/backend/infrastructure/mediaserver"
 )
 
 type PlexHandlers struct {
-	plexService *application.PlexService
-	plexRepo    *database.PlexRepository
+	plexService       *application.PlexService
+	mappingService    *application.MappingService
+	syncService       *application.SyncService
+	collectionService *application.CollectionService
+	plexRepo          domain.PlexShowStore
+	qualityRepo       *database.ShowQualityRepository
+	events            *application.EventBus
 }
 
-func NewPlexHandlers(plexService *application.PlexService, plexRepo *database.PlexRepository) *PlexHandlers {
+func NewPlexHandlers(plexService *application.PlexService, mappingService *application.MappingService, syncService *application.SyncService, collectionService *application.CollectionService, plexRepo domain.PlexShowStore, qualityRepo *database.ShowQualityRepository, events *application.EventBus) *PlexHandlers {
 	return &PlexHandlers{
-		plexService: plexService,
-		plexRepo:    plexRepo,
+		plexService:       plexService,
+		mappingService:    mappingService,
+		syncService:       syncService,
+		collectionService: collectionService,
+		plexRepo:          plexRepo,
+		qualityRepo:       qualityRepo,
+		events:            events,
 	}
 }
 
@@ -33,32 +47,159 @@ func (h *PlexHandlers) GetServerStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PlexHandlers) SyncPlexShows(w http.ResponseWriter, r *http.Request) {
-	shows, err := h.plexService.FetchShowsFromPlex()
+	result, err := h.syncService.Sync(r.URL.Query().Get("mode"))
+	if errors.Is(err, domain.ErrSyncInProgress) {
+		respondWithError(w, http.StatusConflict, "Sync already in progress", err.Error())
+		return
+	}
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		respondWithError(w, http.StatusBadRequest, "Invalid sync mode", err.Error())
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to sync plex shows", err.Error())
 		return
 	}
 
-	for _, show := range shows {
-		if err := h.plexRepo.UpsertPlexShow(&show); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to save plex show", err.Error())
-			return
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":  "Plex shows synced successfully",
+		"mode":     result.Mode,
+		"since":    result.Since,
+		"count":    result.Count,
+		"changed":  result.Changed,
+		"duration": result.Duration,
+	})
+}
+
+// SyncWatchlistCollection serves POST /api/plex/collections/watchlist.
+func (h *PlexHandlers) SyncWatchlistCollection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	result, err := h.collectionService.SyncWatchlistCollection()
+	if errors.Is(err, domain.ErrCollectionsUnsupported) {
+		respondWithError(w, http.StatusNotImplemented, "Collections not supported", err.Error())
+		return
+	}
+	if errors.Is(err, domain.ErrLibraryNotSet) {
+		respondWithError(w, http.StatusBadRequest, "No library configured", err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Failed to sync watchlist collection", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// WriteBackMappings serves POST /api/plex/writeback. Without a plex_id every
+// mapped show is written back; dry_run reports the changes only.
+func (h *PlexHandlers) WriteBackMappings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	var req struct {
+		PlexID int  `json:"plex_id"`
+		DryRun bool `json:"dry_run"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	results, err := h.mappingService.WriteBackMappings(req.PlexID, req.DryRun)
+	switch {
+	case errors.Is(err, domain.ErrWritebackUnsupported):
+		respondWithError(w, http.StatusNotImplemented, "Writeback not supported", err.Error())
+		return
+	case errors.Is(err, domain.ErrWritebackDisabled):
+		respondWithError(w, http.StatusForbidden, "Writeback disabled", "Set PLEX_WRITEBACK_ENABLED=true or use dry_run")
+		return
+	case err != nil:
+		respondWithError(w, mappingErrorStatus(err), "Failed to write back mappings", err.Error())
+		return
+	}
+
+	applied, failed := 0, 0
+	for _, result := range results {
+		if result.Applied {
+			applied++
+		}
+		if result.Error != "" {
+			failed++
 		}
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Plex shows synced successfully",
-		"count":   len(shows),
+		"dry_run": req.DryRun,
+		"total":   len(results),
+		"applied": applied,
+		"failed":  failed,
+		"results": results,
 	})
 }
 
+// GetShowsOnServer lists synced shows with their media quality. The
+// has_subs, has_audio, min_resolution and codec parameters filter by quality,
+// e.g. ?has_subs=eng&has_audio=jpn&min_resolution=1080.
 func (h *PlexHandlers) GetShowsOnServer(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := domain.QualityFilter{
+		SubtitleLanguage: query.Get("has_subs"),
+		AudioLanguage:    query.Get("has_audio"),
+		VideoCodec:       query.Get("codec"),
+	}
+	if resolution := query.Get("min_resolution"); resolution != "" {
+		filter.MinHeight = mediaserver.ResolutionHeight(resolution)
+		if filter.MinHeight == 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid min_resolution", "Use a line count such as 720 or 1080p, or sd, hd or 4k")
+			return
+		}
+	}
+
 	shows, err := h.plexRepo.GetAllPlexShows()
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get shows on server", err.Error())
 		return
 	}
 
+	if !filter.IsEmpty() {
+		ids, err := h.qualityRepo.GetPlexIDsByQuality(filter)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to filter shows by quality", err.Error())
+			return
+		}
+
+		matches := make(map[int]bool, len(ids))
+		for _, id := range ids {
+			matches[id] = true
+		}
+
+		filtered := make([]domain.PlexShow, 0, len(ids))
+		for _, show := range shows {
+			if matches[show.PlexID] {
+				filtered = append(filtered, show)
+			}
+		}
+		shows = filtered
+	}
+
+	qualities, err := h.qualityRepo.GetShowQualities()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get show quality", err.Error())
+		return
+	}
+	for i := range shows {
+		shows[i].Quality = qualities[shows[i].PlexID]
+	}
+
 	respondWithJSON(w, http.StatusOK, shows)
 }
 
@@ -104,16 +245,75 @@ func (h *PlexHandlers) MapShowToAnilist(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.plexRepo.UpdateShowMapping(req.PlexID, req.AnilistID); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to map show", err.Error())
+	show, err := h.mappingService.MapShow(req.PlexID, req.AnilistID, requestActor(r))
+	if err != nil {
+		respondWithError(w, mappingErrorStatus(err), "Failed to map show", err.Error())
 		return
 	}
 
+	h.events.Publish(domain.EventShowMapped, show)
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
 		"message": "Show mapped successfully",
+		"show":    show,
 	})
 }
 
+// HandleShowMapping serves /api/plex/map/{plex_id} (DELETE to unmap),
+// /api/plex/map/{plex_id}/undo and /api/plex/map/{plex_id}/history.
+func (h *PlexHandlers) HandleShowMapping(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) < 4 || len(pathParts) > 5 {
+		respondWithError(w, http.StatusBadRequest, "Invalid path", "Expected /api/plex/map/{plex_id}")
+		return
+	}
+
+	plexID, err := strconv.Atoi(pathParts[3])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid plex ID", "Plex ID must be a valid integer")
+		return
+	}
+
+	action := ""
+	if len(pathParts) == 5 {
+		action = pathParts[4]
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodDelete:
+		if err := h.mappingService.UnmapShow(plexID, requestActor(r)); err != nil {
+			respondWithError(w, mappingErrorStatus(err), "Failed to unmap show", err.Error())
+			return
+		}
+		h.publishMapping(plexID)
+
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Show unmapped successfully",
+		})
+
+	case action == "undo" && r.Method == http.MethodPost:
+		entry, err := h.mappingService.UndoMapping(plexID, requestActor(r))
+		if err != nil {
+			respondWithError(w, mappingErrorStatus(err), "Failed to undo mapping", err.Error())
+			return
+		}
+		h.publishMapping(plexID)
+
+		respondWithJSON(w, http.StatusOK, entry)
+
+	case action == "history" && r.Method == http.MethodGet:
+		history, err := h.mappingService.GetMappingHistory(plexID)
+		if err != nil {
+			respondWithError(w, mappingErrorStatus(err), "Failed to get mapping history", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, history)
+
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+	}
+}
+
 func (h *PlexHandlers) AutoMapShow(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
@@ -135,76 +335,106 @@ func (h *PlexHandlers) AutoMapShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.plexService.MapShowToAnilist(show); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to map show", err.Error())
+	result, err := h.mappingService.AutoMapShow(show)
+	if err != nil {
+		respondWithError(w, mappingErrorStatus(err), "Failed to map show", err.Error())
 		return
 	}
 
-	if show.AnilistID != nil {
-		if err := h.plexRepo.UpsertPlexShow(show); err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Failed to save mapping", err.Error())
-			return
-		}
+	if result.Outcome == domain.AutoMapOutcomeMapped {
+		h.events.Publish(domain.EventShowMapped, result.Show)
+	}
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+func (h *PlexHandlers) GetReviewQueue(w http.ResponseWriter, r *http.Request) {
+	candidates, err := h.mappingService.GetReviewQueue()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get review queue", err.Error())
+		return
 	}
 
-	respondWithJSON(w, http.StatusOK, show)
+	respondWithJSON(w, http.StatusOK, candidates)
 }
 
-func (h *PlexHandlers) BulkAutoMapShows(w http.ResponseWriter, r *http.Request) {
+func (h *PlexHandlers) ResolveReview(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
 		return
 	}
 
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(pathParts) != 4 {
+		respondWithError(w, http.StatusBadRequest, "Invalid path", "Expected /api/plex/review/{plex_id}")
+		return
+	}
+
+	plexID, err := strconv.Atoi(pathParts[3])
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid plex ID", "Plex ID must be a valid integer")
+		return
+	}
+
 	var req struct {
-		Limit int `json:"limit"`
+		Action    string `json:"action"`
+		AnilistID int    `json:"anilist_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		req.Limit = 50 // Default limit
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
 	}
 
-	if req.Limit <= 0 || req.Limit > 100 {
-		req.Limit = 50
+	candidate, err := h.mappingService.ResolveReview(plexID, req.Action, req.AnilistID, requestActor(r))
+	if err != nil {
+		respondWithError(w, mappingErrorStatus(err), "Failed to resolve review", err.Error())
+		return
 	}
 
-	shows, err := h.plexRepo.GetUnmappedShows()
+	if candidate.Status == domain.CandidateStatusAccepted {
+		h.publishMapping(plexID)
+	}
+	respondWithJSON(w, http.StatusOK, candidate)
+}
+
+func (h *PlexHandlers) GetIgnoredShows(w http.ResponseWriter, r *http.Request) {
+	shows, err := h.plexRepo.GetIgnoredShows()
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get unmapped shows", err.Error())
+		respondWithError(w, http.StatusInternalServerError, "Failed to get ignored shows", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, shows)
+}
+
+func (h *PlexHandlers) IgnoreShow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
 		return
 	}
 
-	if len(shows) > req.Limit {
-		shows = shows[:req.Limit]
+	var req struct {
+		PlexID  int  `json:"plex_id"`
+		Ignored bool `json:"ignored"`
 	}
 
-	// Convert to pointers for the service
-	showPointers := make([]*domain.PlexShow, len(shows))
-	for i := range shows {
-		showPointers[i] = &shows[i]
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
 	}
 
-	mappedCount, err := h.plexService.BulkAutoMapShows(showPointers)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to bulk map shows", err.Error())
+	if err := h.plexRepo.SetShowIgnored(req.PlexID, req.Ignored); err != nil {
+		respondWithError(w, mappingErrorStatus(err), "Failed to update show", err.Error())
 		return
 	}
 
-	// Save all successfully mapped shows
-	for _, show := range showPointers {
-		if show.AnilistID != nil {
-			if err := h.plexRepo.UpsertPlexShow(show); err != nil {
-				// Log error but continue
-				continue
-			}
-		}
+	message := "Show is no longer ignored"
+	if req.Ignored {
+		message = "Show ignored"
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"message":      "Bulk auto-map completed",
-		"processed":    len(shows),
-		"mapped":       mappedCount,
-		"failed":       len(shows) - mappedCount,
+		"message": message,
 	})
 }
 
@@ -221,22 +451,160 @@ func (h *PlexHandlers) CheckShowOnServer(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	shows, err := h.plexRepo.GetAllPlexShows()
+	availability, err := h.mappingService.FindOnServer(anilistID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to get shows", err.Error())
 		return
 	}
 
-	var foundShow *domain.PlexShow
-	for _, show := range shows {
-		if show.AnilistID != nil && *show.AnilistID == anilistID {
-			foundShow = &show
-			break
+	// Watched state needs a round trip to the media server, so it is opt-in
+	if availability.Show != nil && r.URL.Query().Get("watch_state") == "true" {
+		state, err := h.plexService.FetchWatchState(availability.Show)
+		if err != nil {
+			respondWithError(w, http.StatusBadGateway, "Failed to get watch state", err.Error())
+			return
 		}
+		availability.WatchState = state
 	}
 
-	respondWithJSON(w, http.StatusOK, map[string]interface{}{
-		"on_server": foundShow != nil,
-		"show":      foundShow,
-	})
-} 
\ No newline at end of file
+	respondWithJSON(w, http.StatusOK, availability)
+}
+
+func (h *PlexHandlers) GetLibraries(w http.ResponseWriter, r *http.Request) {
+	libraries, err := h.plexService.FetchLibraries()
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Failed to get libraries", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, libraries)
+}
+
+func (h *PlexHandlers) GetEpisodes(w http.ResponseWriter, r *http.Request) {
+	plexID, err := strconv.Atoi(r.URL.Query().Get("plex_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid plex ID", "plex_id must be a valid integer")
+		return
+	}
+
+	show, err := h.plexRepo.GetPlexShowByPlexID(plexID)
+	if err != nil {
+		respondWithError(w, mappingErrorStatus(err), "Failed to get show", err.Error())
+		return
+	}
+
+	episodes, err := h.plexService.FetchEpisodes(show)
+	if err != nil {
+		respondWithError(w, http.StatusBadGateway, "Failed to get episodes", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, episodes)
+}
+
+func (h *PlexHandlers) HandleSeasonMappings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		plexID, err := strconv.Atoi(r.URL.Query().Get("plex_id"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid plex ID", "plex_id must be a valid integer")
+			return
+		}
+
+		seasons, err := h.mappingService.GetSeasonMappings(plexID)
+		if err != nil {
+			respondWithError(w, mappingErrorStatus(err), "Failed to get season mappings", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, seasons)
+
+	case http.MethodPost:
+		var req domain.SeasonMapping
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+
+		if err := h.mappingService.SetSeasonMapping(&req); err != nil {
+			respondWithError(w, mappingErrorStatus(err), "Failed to map season", err.Error())
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, req)
+
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+	}
+}
+
+func (h *PlexHandlers) ProposeSeasonMappings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	var req struct {
+		PlexID int `json:"plex_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	show, err := h.plexRepo.GetPlexShowByPlexID(req.PlexID)
+	if err != nil {
+		respondWithError(w, mappingErrorStatus(err), "Show not found", err.Error())
+		return
+	}
+
+	seasons, err := h.mappingService.ProposeSeasonMappings(show)
+	if err != nil {
+		respondWithError(w, mappingErrorStatus(err), "Failed to propose season mappings", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, seasons)
+}
+
+// mappingErrorStatus maps errors from mapping operations to HTTP statuses.
+func mappingErrorStatus(err error) int {
+	var validationErr *domain.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrShowNotFound), errors.Is(err, domain.ErrCandidateNotFound), errors.Is(err, domain.ErrNoMappingHistory):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrMappingLocked), errors.Is(err, domain.ErrShowIgnored), errors.Is(err, domain.ErrShowNotMapped), errors.Is(err, domain.ErrCandidateResolved):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrAnimeNotFound):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// requestActor identifies who made a change for the mapping history. There is
+// no authentication, so clients may name themselves with X-User.
+func requestActor(r *http.Request) string {
+	if user := r.Header.Get("X-User"); user != "" {
+		return user
+	}
+	return "user"
+}
+
+// publishMapping tells event subscribers about the current mapping of a show
+// after it was changed.
+func (h *PlexHandlers) publishMapping(plexID int) {
+	show, err := h.plexRepo.GetPlexShowByPlexID(plexID)
+	if err != nil {
+		return
+	}
+
+	if show.AnilistID == nil {
+		h.events.Publish(domain.EventShowUnmapped, show)
+	} else {
+		h.events.Publish(domain.EventShowMapped, show)
+	}
+}
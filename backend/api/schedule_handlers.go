@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"anime-watchlist/backend/application"
+)
+
+type ScheduleHandlers struct {
+	scheduler *application.Scheduler
+}
+
+func NewScheduleHandlers(scheduler *application.Scheduler) *ScheduleHandlers {
+	return &ScheduleHandlers{scheduler: scheduler}
+}
+
+func (h *ScheduleHandlers) GetSchedules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	schedules, err := h.scheduler.GetSchedules()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get schedules", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, schedules)
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"anime-watchlist/backend/application"
+	"anime-watchlist/backend/domain"
+)
+
+type SearchHandlers struct {
+	searchService *application.SearchService
+}
+
+func NewSearchHandlers(searchService *application.SearchService) *SearchHandlers {
+	return &SearchHandlers{searchService: searchService}
+}
+
+// Search handles GET /api/search?q=...&limit=..., the local search over shows
+// on the server and cached AniList metadata.
+func (h *SearchHandlers) Search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid limit", "limit must be a positive number")
+			return
+		}
+		limit = parsed
+	}
+
+	results, err := h.searchService.Search(r.URL.Query().Get("q"), limit)
+	if err != nil {
+		var validationErr *domain.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, http.StatusBadRequest, "Invalid search", validationErr.Message)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to search", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"results": results,
+		"count":   len(results),
+	})
+}
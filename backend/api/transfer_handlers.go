@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"anime-watchlist/backend/application"
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+type TransferHandlers struct {
+	transferService *application.TransferService
+	settingsRepo    *database.SettingsRepository
+}
+
+func NewTransferHandlers(transferService *application.TransferService, settingsRepo *database.SettingsRepository) *TransferHandlers {
+	return &TransferHandlers{
+		transferService: transferService,
+		settingsRepo:    settingsRepo,
+	}
+}
+
+// Export handles GET /api/export, which downloads all user data as one JSON
+// document.
+func (h *TransferHandlers) Export(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	doc, err := h.transferService.Export()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to export", err.Error())
+		return
+	}
+
+	filename := "anime-watchlist-" + time.Now().Format("20060102") + ".json"
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	respondWithJSON(w, http.StatusOK, doc)
+}
+
+// Import handles POST /api/import?mode=merge|replace&dry_run=true with an
+// export document as the body.
+func (h *TransferHandlers) Import(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid dry_run", "dry_run must be true or false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	var doc domain.ExportDocument
+	if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.transferService.Import(&doc, r.URL.Query().Get("mode"), dryRun)
+	if err != nil {
+		var validationErr *domain.ValidationError
+		if errors.As(err, &validationErr) {
+			respondWithError(w, http.StatusBadRequest, "Invalid import", validationErr.Message)
+			return
+		}
+		if result != nil && result.Applied > 0 {
+			// Part of the import was written; say what
+			respondWithJSON(w, http.StatusInternalServerError, result)
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Failed to import", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// HandleSettings handles GET and PUT /api/settings. PUT takes an object of
+// keys to set; a null value deletes the key.
+func (h *TransferHandlers) HandleSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var changes map[string]*string
+		if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+			return
+		}
+		if err := h.settingsRepo.UpdateSettings(changes); err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to update settings", err.Error())
+			return
+		}
+	default:
+		respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed", "")
+		return
+	}
+
+	settings, err := h.settingsRepo.GetSettings()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get settings", err.Error())
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, settings)
+}
@@ -0,0 +1,99 @@
+package application
+
+import (
+	"fmt"
+
+	"anime-watchlist/backend/domain"
+)
+
+// AnilistImportService seeds the watchlist from an AniList account, either
+// fetched by user name or from a saved MediaListCollection dump.
+type AnilistImportService struct {
+	anilistService *AnilistService
+	watchlistRepo  domain.WatchlistStore
+	events         *EventBus
+}
+
+func NewAnilistImportService(anilistService *AnilistService, watchlistRepo domain.WatchlistStore, events *EventBus) *AnilistImportService {
+	return &AnilistImportService{
+		anilistService: anilistService,
+		watchlistRepo:  watchlistRepo,
+		events:         events,
+	}
+}
+
+// ImportUser imports the anime list of a public AniList user.
+func (s *AnilistImportService) ImportUser(userName string, strategy string, dryRun bool) (*domain.ListImportResult, error) {
+	if userName == "" {
+		return nil, &domain.ValidationError{Field: "username", Message: "username is required"}
+	}
+	strategy, err := listMergeStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.anilistService.GetMediaListCollection(userName)
+	if err != nil {
+		return nil, err
+	}
+	return s.importEntries(entries, strategy, dryRun)
+}
+
+// ImportDump imports a MediaListCollection saved from the AniList API.
+func (s *AnilistImportService) ImportDump(data []byte, strategy string, dryRun bool) (*domain.ListImportResult, error) {
+	strategy, err := listMergeStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ParseMediaListCollection(data)
+	if err != nil {
+		return nil, &domain.ValidationError{Field: "body", Message: err.Error()}
+	}
+	return s.importEntries(entries, strategy, dryRun)
+}
+
+// importEntries adds the entries and merges the ones already on the
+// watchlist with strategy. AniList statuses carry over unchanged.
+func (s *AnilistImportService) importEntries(entries []domain.AnilistListEntry, strategy string, dryRun bool) (*domain.ListImportResult, error) {
+	result := newListImportResult("anilist", strategy, dryRun, len(entries))
+	seen := make(map[int]bool, len(entries))
+	var resolved []resolvedListEntry
+	for _, entry := range entries {
+		report := domain.ListImportEntry{SourceID: entry.MediaID, AnilistID: entry.MediaID, Title: entry.Title, Status: entry.Status}
+
+		switch {
+		case entry.MediaID <= 0:
+			report.Reason = "entry has no AniList ID"
+			result.Unresolved = append(result.Unresolved, report)
+			continue
+		case !domain.ValidWatchStatus(entry.Status):
+			report.Reason = fmt.Sprintf("unknown status %q", entry.Status)
+			result.Unresolved = append(result.Unresolved, report)
+			continue
+		case seen[entry.MediaID]:
+			// Only dumps edited by hand list an anime twice
+			report.Reason = "listed more than once"
+			result.Conflicts = append(result.Conflicts, report)
+			continue
+		}
+		seen[entry.MediaID] = true
+
+		resolved = append(resolved, resolvedListEntry{
+			report: report,
+			item: domain.WatchlistItem{
+				AnilistID:   entry.MediaID,
+				Status:      entry.Status,
+				Score:       entry.Score,
+				Progress:    entry.Progress,
+				StartedAt:   entry.StartedAt,
+				CompletedAt: entry.CompletedAt,
+			},
+		})
+	}
+
+	if err := importListEntries(s.watchlistRepo, s.events, resolved, strategy, dryRun, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -3,8 +3,10 @@ package application
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"time"
 
@@ -16,12 +18,14 @@ type AnilistService struct {
 	baseURL string
 }
 
-func NewAnilistService() *AnilistService {
+// NewAnilistService talks to the GraphQL API at baseURL, which is
+// https://graphql.anilist.co except when testing against a stand-in.
+func NewAnilistService(baseURL string) *AnilistService {
 	return &AnilistService{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		baseURL: "https://graphql.anilist.co",
+		baseURL: baseURL,
 	}
 }
 
@@ -111,7 +115,9 @@ func (s *AnilistService) SearchAnime(filter domain.AnimeSearchFilter) ([]domain.
 				title {
 					romaji
 					english
+					native
 				}
+				synonyms
 				description
 				coverImage {
 					large
@@ -150,7 +156,9 @@ func (s *AnilistService) GetAnimeByID(id int) (*domain.Anime, error) {
 			title {
 				romaji
 				english
+				native
 			}
+			synonyms
 			description
 			coverImage {
 				large
@@ -182,11 +190,88 @@ func (s *AnilistService) GetAnimeByID(id int) (*domain.Anime, error) {
 		return nil, err
 	}
 
+	if response.Data.Media.ID == 0 {
+		return nil, domain.ErrAnimeNotFound
+	}
+
 	anime := response.Data.Media.ToDomain()
 	return &anime, nil
 }
 
+// GetSequels returns the anime that AniList lists as direct SEQUEL relations
+// of the given entry.
+func (s *AnilistService) GetSequels(id int) ([]domain.Anime, error) {
+	query := `query GetRelations($id: Int) {
+		Media(id: $id) {
+			id
+			relations {
+				edges {
+					relationType
+					node {
+						id
+						type
+						title {
+							romaji
+							english
+							native
+						}
+						format
+						episodes
+						season
+						seasonYear
+						popularity
+					}
+				}
+			}
+		}
+	}`
+
+	variables := map[string]interface{}{
+		"id": id,
+	}
+
+	var response struct {
+		Data struct {
+			Media struct {
+				ID        int `json:"id"`
+				Relations struct {
+					Edges []struct {
+						RelationType string `json:"relationType"`
+						Node         struct {
+							domain.AnilistAnime
+							Type string `json:"type"`
+						} `json:"node"`
+					} `json:"edges"`
+				} `json:"relations"`
+			} `json:"Media"`
+		} `json:"data"`
+	}
+
+	if err := s.makeRequest(query, variables, &response); err != nil {
+		return nil, err
+	}
+
+	if response.Data.Media.ID == 0 {
+		return nil, domain.ErrAnimeNotFound
+	}
+
+	var sequels []domain.Anime
+	for _, edge := range response.Data.Media.Relations.Edges {
+		if edge.RelationType == "SEQUEL" && edge.Node.Type == "ANIME" {
+			sequels = append(sequels, edge.Node.ToDomain())
+		}
+	}
+
+	return sequels, nil
+}
+
 func (s *AnilistService) makeRequest(query string, variables map[string]interface{}, response interface{}) error {
+	return s.makeAuthorizedRequest("", query, variables, response)
+}
+
+// makeAuthorizedRequest makes a request as the user the access token belongs
+// to, or anonymously when it is empty.
+func (s *AnilistService) makeAuthorizedRequest(token string, query string, variables map[string]interface{}, response interface{}) error {
 	req := GraphQLRequest{
 		Query:     query,
 		Variables: variables,
@@ -204,6 +289,9 @@ func (s *AnilistService) makeRequest(query string, variables map[string]interfac
 
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("Accept", "application/json")
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	resp, err := s.client.Do(httpReq)
 	if err != nil {
@@ -216,6 +304,18 @@ func (s *AnilistService) makeRequest(query string, variables map[string]interfac
 		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusNotFound {
+		return domain.ErrAnimeNotFound
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return domain.ErrRateLimited
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return domain.ErrAnilistUnauthorized
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("anilist API returned status %d: %s", resp.StatusCode, string(body))
 	}
@@ -225,4 +325,382 @@ func (s *AnilistService) makeRequest(query string, variables map[string]interfac
 	}
 
 	return nil
-} 
\ No newline at end of file
+} 
+
+// MaxMediaLinksPerRequest is how many IDs one GetMediaLinks call can look
+// up, AniList's page size limit.
+const MaxMediaLinksPerRequest = 50
+
+const mediaLinksQuery = `
+query ($ids: [Int], $perPage: Int) {
+  Page(perPage: $perPage) {
+    media(%s: $ids, type: ANIME) {
+      id
+      idMal
+      title {
+        romaji
+        english
+      }
+      format
+      episodes
+    }
+  }
+}
+`
+
+// GetMediaLinksByMalIDs looks up the AniList entries for up to
+// MaxMediaLinksPerRequest MyAnimeList IDs in one request. IDs AniList does not
+// know are left out.
+func (s *AnilistService) GetMediaLinksByMalIDs(malIDs []int) ([]domain.MediaLink, error) {
+	return s.getMediaLinks("idMal_in", malIDs)
+}
+
+// GetMediaLinks looks up the MyAnimeList IDs of up to
+// MaxMediaLinksPerRequest AniList entries in one request.
+func (s *AnilistService) GetMediaLinks(ids []int) ([]domain.MediaLink, error) {
+	return s.getMediaLinks("id_in", ids)
+}
+
+func (s *AnilistService) getMediaLinks(filter string, ids []int) ([]domain.MediaLink, error) {
+	if len(ids) > MaxMediaLinksPerRequest {
+		return nil, fmt.Errorf("cannot look up more than %d anime per request", MaxMediaLinksPerRequest)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	variables := map[string]interface{}{
+		"ids":     ids,
+		"perPage": MaxMediaLinksPerRequest,
+	}
+
+	var response struct {
+		Data struct {
+			Page struct {
+				Media []struct {
+					ID    int `json:"id"`
+					IDMal int `json:"idMal"`
+					Title struct {
+						Romaji  string `json:"romaji"`
+						English string `json:"english"`
+					} `json:"title"`
+					Format   string `json:"format"`
+					Episodes int    `json:"episodes"`
+				} `json:"media"`
+			} `json:"Page"`
+		} `json:"data"`
+	}
+
+	if err := s.makeRequest(fmt.Sprintf(mediaLinksQuery, filter), variables, &response); err != nil {
+		return nil, err
+	}
+
+	links := make([]domain.MediaLink, 0, len(response.Data.Page.Media))
+	for _, media := range response.Data.Page.Media {
+		title := media.Title.Romaji
+		if title == "" {
+			title = media.Title.English
+		}
+		links = append(links, domain.MediaLink{
+			AnilistID: media.ID,
+			MalID:     media.IDMal,
+			Title:     title,
+			Format:    media.Format,
+			Episodes:  media.Episodes,
+		})
+	}
+
+	return links, nil
+}
+
+// mediaListFields are the MediaList fields the watchlist keeps.
+const mediaListFields = `
+        id
+        mediaId
+        status
+        score(format: POINT_10_DECIMAL)
+        progress
+        startedAt { year month day }
+        completedAt { year month day }
+        updatedAt
+        media {
+          title {
+            romaji
+            english
+          }
+        }
+`
+
+const mediaListCollectionQuery = `
+query ($userName: String, $userId: Int) {
+  MediaListCollection(userName: $userName, userId: $userId, type: ANIME) {
+    lists {
+      isCustomList
+      entries {` + mediaListFields + `      }
+    }
+  }
+}
+`
+
+// mediaListCollection is AniList's MediaListCollection object, as returned
+// by the API and found in saved dumps.
+type mediaListCollection struct {
+	Lists []struct {
+		IsCustomList bool             `json:"isCustomList"`
+		Entries      []mediaListEntry `json:"entries"`
+	} `json:"lists"`
+}
+
+type mediaListEntry struct {
+	ID          int       `json:"id"`
+	MediaID     int       `json:"mediaId"`
+	Status      string    `json:"status"`
+	Score       float64   `json:"score"`
+	Progress    int       `json:"progress"`
+	StartedAt   fuzzyDate `json:"startedAt"`
+	CompletedAt fuzzyDate `json:"completedAt"`
+	UpdatedAt   int64     `json:"updatedAt"`
+	Media       struct {
+		Title struct {
+			Romaji  string `json:"romaji"`
+			English string `json:"english"`
+		} `json:"title"`
+	} `json:"media"`
+}
+
+func (e *mediaListEntry) toDomain() domain.AnilistListEntry {
+	title := e.Media.Title.Romaji
+	if title == "" {
+		title = e.Media.Title.English
+	}
+
+	entry := domain.AnilistListEntry{
+		ListEntryID: e.ID,
+		MediaID:     e.MediaID,
+		Title:       title,
+		Status:      e.Status,
+		Score:       e.Score,
+		Progress:    e.Progress,
+		StartedAt:   e.StartedAt.time(),
+		CompletedAt: e.CompletedAt.time(),
+	}
+	if e.UpdatedAt > 0 {
+		entry.UpdatedAt = time.Unix(e.UpdatedAt, 0)
+	}
+	return entry
+}
+
+// fuzzyDate is AniList's date type, in which any part can be missing.
+type fuzzyDate struct {
+	Year  *int `json:"year"`
+	Month *int `json:"month"`
+	Day   *int `json:"day"`
+}
+
+// time returns nil unless the date is complete.
+func (d fuzzyDate) time() *time.Time {
+	if d.Year == nil || d.Month == nil || d.Day == nil {
+		return nil
+	}
+	date := time.Date(*d.Year, time.Month(*d.Month), *d.Day, 0, 0, 0, 0, time.UTC)
+	return &date
+}
+
+// GetMediaListCollection returns the anime list of an AniList user, with
+// scores converted to 10 points whatever format the user rates in. Private
+// and unknown users fail with ErrAnilistUserNotFound.
+func (s *AnilistService) GetMediaListCollection(userName string) ([]domain.AnilistListEntry, error) {
+	variables := map[string]interface{}{
+		"userName": userName,
+	}
+
+	var response struct {
+		Data struct {
+			MediaListCollection *mediaListCollection `json:"MediaListCollection"`
+		} `json:"data"`
+	}
+
+	err := s.makeRequest(mediaListCollectionQuery, variables, &response)
+	if errors.Is(err, domain.ErrAnimeNotFound) {
+		return nil, domain.ErrAnilistUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if response.Data.MediaListCollection == nil {
+		return nil, domain.ErrAnilistUserNotFound
+	}
+
+	return response.Data.MediaListCollection.entries(), nil
+}
+
+// ParseMediaListCollection reads a saved MediaListCollection: either the
+// whole API response, its data object, or the collection itself. Dumps may
+// use any score format, so scores above 10 are taken to be out of 100.
+func ParseMediaListCollection(data []byte) ([]domain.AnilistListEntry, error) {
+	var dump struct {
+		Data struct {
+			MediaListCollection *mediaListCollection `json:"MediaListCollection"`
+		} `json:"data"`
+		MediaListCollection *mediaListCollection `json:"MediaListCollection"`
+		mediaListCollection
+	}
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return nil, fmt.Errorf("failed to parse list: %w", err)
+	}
+
+	collection := &dump.mediaListCollection
+	if dump.Data.MediaListCollection != nil {
+		collection = dump.Data.MediaListCollection
+	} else if dump.MediaListCollection != nil {
+		collection = dump.MediaListCollection
+	}
+	if collection.Lists == nil {
+		return nil, fmt.Errorf("no MediaListCollection lists found")
+	}
+
+	entries := collection.entries()
+	for i := range entries {
+		if entries[i].Score > 10 {
+			entries[i].Score /= 10
+		}
+	}
+	return entries, nil
+}
+
+// entries flattens the lists. Custom lists repeat entries from the status
+// lists, so they are skipped.
+func (c *mediaListCollection) entries() []domain.AnilistListEntry {
+	var entries []domain.AnilistListEntry
+	for _, list := range c.Lists {
+		if list.IsCustomList {
+			continue
+		}
+		for i := range list.Entries {
+			entries = append(entries, list.Entries[i].toDomain())
+		}
+	}
+	return entries
+}
+
+// GetViewer returns the ID and name of the user an access token belongs to.
+func (s *AnilistService) GetViewer(token string) (int, string, error) {
+	query := `query { Viewer { id name } }`
+
+	var response struct {
+		Data struct {
+			Viewer struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"Viewer"`
+		} `json:"data"`
+	}
+
+	if err := s.makeAuthorizedRequest(token, query, nil, &response); err != nil {
+		return 0, "", err
+	}
+	if response.Data.Viewer.ID == 0 {
+		return 0, "", domain.ErrAnilistUnauthorized
+	}
+
+	return response.Data.Viewer.ID, response.Data.Viewer.Name, nil
+}
+
+// GetUserList returns the anime list of the user an access token belongs
+// to, private or not, with scores out of 10.
+func (s *AnilistService) GetUserList(token string, userID int) ([]domain.AnilistListEntry, error) {
+	variables := map[string]interface{}{
+		"userId": userID,
+	}
+
+	var response struct {
+		Data struct {
+			MediaListCollection *mediaListCollection `json:"MediaListCollection"`
+		} `json:"data"`
+	}
+
+	if err := s.makeAuthorizedRequest(token, mediaListCollectionQuery, variables, &response); err != nil {
+		return nil, err
+	}
+	if response.Data.MediaListCollection == nil {
+		return nil, domain.ErrAnilistUserNotFound
+	}
+
+	return response.Data.MediaListCollection.entries(), nil
+}
+
+const saveMediaListEntryMutation = `
+mutation ($mediaId: Int, $status: MediaListStatus, $scoreRaw: Int, $progress: Int, $startedAt: FuzzyDateInput, $completedAt: FuzzyDateInput) {
+  SaveMediaListEntry(mediaId: $mediaId, status: $status, scoreRaw: $scoreRaw, progress: $progress, startedAt: $startedAt, completedAt: $completedAt) {` + mediaListFields + `  }
+}
+`
+
+// SaveMediaListEntry creates or updates the user's list entry for a
+// watchlist item and returns the entry as AniList saved it.
+func (s *AnilistService) SaveMediaListEntry(token string, item domain.WatchlistItem) (*domain.AnilistListEntry, error) {
+	variables := map[string]interface{}{
+		"mediaId":  item.AnilistID,
+		"status":   item.Status,
+		"scoreRaw": int(math.Round(item.Score * 10)),
+		"progress": item.Progress,
+		// Null parts clear a date on AniList
+		"startedAt":   fuzzyDateInput(item.StartedAt),
+		"completedAt": fuzzyDateInput(item.CompletedAt),
+	}
+
+	var response struct {
+		Data struct {
+			SaveMediaListEntry *mediaListEntry `json:"SaveMediaListEntry"`
+		} `json:"data"`
+	}
+
+	if err := s.makeAuthorizedRequest(token, saveMediaListEntryMutation, variables, &response); err != nil {
+		return nil, err
+	}
+	if response.Data.SaveMediaListEntry == nil {
+		return nil, fmt.Errorf("anilist did not save anime %d", item.AnilistID)
+	}
+
+	entry := response.Data.SaveMediaListEntry.toDomain()
+	return &entry, nil
+}
+
+// DeleteMediaListEntry deletes a list entry by its own ID. Entries that are
+// already gone fail with ErrAnimeNotFound.
+func (s *AnilistService) DeleteMediaListEntry(token string, listEntryID int) error {
+	query := `
+mutation ($id: Int) {
+  DeleteMediaListEntry(id: $id) {
+    deleted
+  }
+}
+`
+
+	variables := map[string]interface{}{
+		"id": listEntryID,
+	}
+
+	var response struct {
+		Data struct {
+			DeleteMediaListEntry struct {
+				Deleted bool `json:"deleted"`
+			} `json:"DeleteMediaListEntry"`
+		} `json:"data"`
+	}
+
+	if err := s.makeAuthorizedRequest(token, query, variables, &response); err != nil {
+		return err
+	}
+	if !response.Data.DeleteMediaListEntry.Deleted {
+		return domain.ErrAnimeNotFound
+	}
+
+	return nil
+}
+
+func fuzzyDateInput(date *time.Time) map[string]interface{} {
+	if date == nil {
+		return map[string]interface{}{"year": nil, "month": nil, "day": nil}
+	}
+	return map[string]interface{}{"year": date.Year(), "month": int(date.Month()), "day": date.Day()}
+}
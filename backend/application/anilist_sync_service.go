@@ -0,0 +1,740 @@
+package application
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+// oauthStateTTL is how long a user has to finish logging in on AniList.
+const oauthStateTTL = 10 * time.Minute
+
+// maxPushesPerSync caps the anime one sync pushes. The rest stay queued for
+// the next sync, so a long outbox cannot keep a sync running for minutes.
+const maxPushesPerSync = 50
+
+// AnilistSyncService keeps the watchlist and a connected AniList account in
+// step. Local changes are queued in an outbox while an account is connected
+// and pushed on the next sync; remote changes are pulled first. A field
+// changed on both sides since the last sync keeps the newer change.
+type AnilistSyncService struct {
+	anilistService *AnilistService
+	// watchlistRepo is the untracked store, so pulled changes are not queued
+	// to be pushed back
+	watchlistRepo domain.WatchlistStore
+	syncRepo      *database.AnilistSyncRepository
+	oauth         domain.AnilistOAuthConfig
+	events        *EventBus
+	client        *http.Client
+	// pushDelay spaces out pushed changes to stay under the rate limit
+	pushDelay time.Duration
+
+	// syncMu lets one sync run at a time. mu guards the account and the
+	// sync state, and is released while a push waits between changes so
+	// connecting and disconnecting are not held up.
+	syncMu    sync.Mutex
+	mu        sync.Mutex
+	connected atomic.Bool
+
+	statesMu sync.Mutex
+	states   map[string]time.Time
+}
+
+func NewAnilistSyncService(anilistService *AnilistService, watchlistRepo domain.WatchlistStore, syncRepo *database.AnilistSyncRepository, oauth domain.AnilistOAuthConfig, events *EventBus) *AnilistSyncService {
+	s := &AnilistSyncService{
+		anilistService: anilistService,
+		watchlistRepo:  watchlistRepo,
+		syncRepo:       syncRepo,
+		oauth:          oauth,
+		events:         events,
+		client:         &http.Client{Timeout: 30 * time.Second},
+		pushDelay:      refreshDelay,
+		states:         make(map[string]time.Time),
+	}
+
+	account, err := syncRepo.GetAccount()
+	if err != nil {
+		log.Printf("Failed to load AniList account: %v", err)
+	}
+	s.connected.Store(account != nil)
+
+	return s
+}
+
+// Track wraps a watchlist store so that changes made through it are queued
+// for AniList while an account is connected.
+func (s *AnilistSyncService) Track(store domain.WatchlistStore) domain.WatchlistStore {
+	return &trackedWatchlistStore{WatchlistStore: store, sync: s}
+}
+
+func (s *AnilistSyncService) Status() (*domain.AnilistAccountStatus, error) {
+	account, err := s.syncRepo.GetAccount()
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.syncRepo.CountOutbox()
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AnilistAccountStatus{
+		Configured: s.oauthConfigured(),
+		Connected:  account != nil,
+		Account:    account,
+		Pending:    pending,
+	}, nil
+}
+
+// AuthorizeURL starts connecting an account: the user logs in on AniList at
+// the returned URL, which sends them back to the redirect URL with a code.
+func (s *AnilistSyncService) AuthorizeURL() (string, error) {
+	if !s.oauthConfigured() {
+		return "", domain.ErrAnilistOAuthDisabled
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	state := hex.EncodeToString(buf)
+
+	s.statesMu.Lock()
+	now := time.Now()
+	for key, expires := range s.states {
+		if now.After(expires) {
+			delete(s.states, key)
+		}
+	}
+	s.states[state] = now.Add(oauthStateTTL)
+	s.statesMu.Unlock()
+
+	query := url.Values{
+		"client_id":     {s.oauth.ClientID},
+		"redirect_uri":  {s.oauth.RedirectURL},
+		"response_type": {"code"},
+		"state":         {state},
+	}
+	return s.oauth.URL + "/authorize?" + query.Encode(), nil
+}
+
+// Connect finishes connecting an account with the code AniList redirected
+// back with. Connecting a different user drops the sync state of the
+// previous one.
+func (s *AnilistSyncService) Connect(code, state string) (*domain.AnilistAccount, error) {
+	if !s.oauthConfigured() {
+		return nil, domain.ErrAnilistOAuthDisabled
+	}
+	if !s.takeState(state) {
+		return nil, domain.ErrInvalidOAuthState
+	}
+	if code == "" {
+		return nil, &domain.ValidationError{Field: "code", Message: "code is required"}
+	}
+
+	token, expiresIn, err := s.exchangeCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, userName, err := s.anilistService.GetViewer(token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anilist user: %w", err)
+	}
+
+	account := &domain.AnilistAccount{
+		UserID:      userID,
+		UserName:    userName,
+		AccessToken: token,
+		ConnectedAt: time.Now(),
+	}
+	if expiresIn > 0 {
+		expiresAt := account.ConnectedAt.Add(time.Duration(expiresIn) * time.Second)
+		account.ExpiresAt = &expiresAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.syncRepo.SaveAccount(account); err != nil {
+		return nil, err
+	}
+	s.connected.Store(true)
+
+	log.Printf("Connected AniList account %s", userName)
+	return account, nil
+}
+
+// Disconnect forgets the account, dropping changes that were not pushed.
+func (s *AnilistSyncService) Disconnect() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.syncRepo.DeleteAccount(); err != nil {
+		return err
+	}
+	s.connected.Store(false)
+	return nil
+}
+
+func (s *AnilistSyncService) oauthConfigured() bool {
+	return s.oauth.ClientID != "" && s.oauth.ClientSecret != ""
+}
+
+func (s *AnilistSyncService) takeState(state string) bool {
+	s.statesMu.Lock()
+	defer s.statesMu.Unlock()
+
+	expires, ok := s.states[state]
+	delete(s.states, state)
+	return ok && time.Now().Before(expires)
+}
+
+// exchangeCode trades an authorization code for an access token and the
+// seconds until it expires.
+func (s *AnilistSyncService) exchangeCode(code string) (string, int, error) {
+	body, err := json.Marshal(map[string]string{
+		"grant_type":    "authorization_code",
+		"client_id":     s.oauth.ClientID,
+		"client_secret": s.oauth.ClientSecret,
+		"redirect_uri":  s.oauth.RedirectURL,
+		"code":          code,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.oauth.URL+"/token", bytes.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get anilist token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read anilist token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("anilist token request returned status %d: %s", resp.StatusCode, string(data))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &token); err != nil {
+		return "", 0, fmt.Errorf("failed to parse anilist token: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", 0, fmt.Errorf("anilist returned no access token")
+	}
+
+	return token.AccessToken, token.ExpiresIn, nil
+}
+
+// Sync pulls the AniList list into the watchlist, then pushes the outbox.
+// Changes that fail to push, and any beyond maxPushesPerSync, stay queued
+// for the next sync.
+func (s *AnilistSyncService) Sync() (*domain.AnilistSyncResult, error) {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, err := s.syncRepo.GetAccount()
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, domain.ErrAnilistNotConnected
+	}
+
+	result := &domain.AnilistSyncResult{
+		Added:     []int{},
+		Updated:   []int{},
+		Removed:   []int{},
+		Conflicts: []domain.AnilistSyncConflict{},
+	}
+
+	err = s.pull(account, result)
+	if err == nil {
+		err = s.push(account, result)
+	}
+
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+	if recordErr := s.syncRepo.RecordSync(time.Now(), message); recordErr != nil {
+		log.Printf("Failed to record AniList sync: %v", recordErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Pending, err = s.syncRepo.CountOutbox()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// pendingChange sums up the queued changes to one anime.
+type pendingChange struct {
+	// fields holds when each field was last changed
+	fields map[string]time.Time
+	// deletedAt is set when the newest change removed the anime
+	deletedAt *time.Time
+	lastID    int64
+}
+
+// pendingChanges groups the outbox by anime, in the order each anime was
+// first changed.
+func pendingChanges(outbox []domain.OutboxEntry) (map[int]*pendingChange, []int) {
+	changes := make(map[int]*pendingChange)
+	var order []int
+	for _, entry := range outbox {
+		change, ok := changes[entry.AnilistID]
+		if !ok {
+			change = &pendingChange{fields: make(map[string]time.Time)}
+			changes[entry.AnilistID] = change
+			order = append(order, entry.AnilistID)
+		}
+
+		change.lastID = entry.ID
+		if entry.Action == domain.OutboxActionDelete {
+			changedAt := entry.ChangedAt
+			change.deletedAt = &changedAt
+			continue
+		}
+		change.deletedAt = nil
+		for _, field := range entry.Fields {
+			change.fields[field] = entry.ChangedAt
+		}
+	}
+	return changes, order
+}
+
+// pull applies what changed on AniList since the last sync. A field only
+// counts as changed remotely when it differs from the state saved at the
+// last sync; entries that were never synced take AniList's values unless
+// they were changed here more recently.
+func (s *AnilistSyncService) pull(account *domain.AnilistAccount, result *domain.AnilistSyncResult) error {
+	remote, err := s.anilistService.GetUserList(account.AccessToken, account.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get anilist list: %w", err)
+	}
+
+	items, err := s.watchlistRepo.GetWatchlist()
+	if err != nil {
+		return err
+	}
+	local := make(map[int]domain.WatchlistItem, len(items))
+	for _, item := range items {
+		local[item.AnilistID] = item
+	}
+
+	synced, err := s.syncRepo.GetSyncedEntries()
+	if err != nil {
+		return err
+	}
+
+	outbox, err := s.syncRepo.GetOutbox()
+	if err != nil {
+		return err
+	}
+	pending, _ := pendingChanges(outbox)
+
+	onAnilist := make(map[int]bool, len(remote))
+	for i := range remote {
+		entry := &remote[i]
+		if !domain.ValidWatchStatus(entry.Status) || onAnilist[entry.MediaID] {
+			continue
+		}
+		onAnilist[entry.MediaID] = true
+
+		if err := s.pullEntry(entry, local, synced, pending, result); err != nil {
+			return err
+		}
+		if err := s.syncRepo.SaveSyncedEntry(entry); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range items {
+		if onAnilist[item.AnilistID] {
+			continue
+		}
+		change := pending[item.AnilistID]
+
+		if _, ok := synced[item.AnilistID]; !ok {
+			// Never synced, such as entries from before the account was
+			// connected: push it unless that is already queued
+			if change == nil {
+				s.queue(item.AnilistID, domain.OutboxActionSave, domain.SyncFields)
+			}
+			continue
+		}
+
+		// Removed on AniList. Local changes since the last sync win and the
+		// push adds it back.
+		if err := s.syncRepo.DeleteSyncedEntry(item.AnilistID); err != nil {
+			return err
+		}
+		if change != nil && change.deletedAt == nil && len(change.fields) > 0 {
+			result.Conflicts = append(result.Conflicts, domain.AnilistSyncConflict{AnilistID: item.AnilistID, Field: "entry", Winner: domain.SyncWinnerLocal})
+			continue
+		}
+		if err := s.watchlistRepo.RemoveFromWatchlist(item.AnilistID); err != nil {
+			return err
+		}
+		if change != nil {
+			if err := s.syncRepo.DeleteOutboxEntries(item.AnilistID, change.lastID); err != nil {
+				return err
+			}
+		}
+		result.Removed = append(result.Removed, item.AnilistID)
+		s.events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: "removed", AnilistID: item.AnilistID})
+	}
+
+	// Entries removed on both sides need no base any more
+	for anilistID := range synced {
+		if _, ok := local[anilistID]; !ok && !onAnilist[anilistID] {
+			if err := s.syncRepo.DeleteSyncedEntry(anilistID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *AnilistSyncService) pullEntry(entry *domain.AnilistListEntry, local map[int]domain.WatchlistItem, synced map[int]domain.AnilistListEntry, pending map[int]*pendingChange, result *domain.AnilistSyncResult) error {
+	remoteItem := watchlistItemFromAnilist(*entry)
+	change := pending[entry.MediaID]
+
+	item, ok := local[entry.MediaID]
+	if !ok {
+		if change != nil && change.deletedAt != nil {
+			if !entry.UpdatedAt.After(*change.deletedAt) {
+				// Removed here after the last change on AniList; the push
+				// deletes it there
+				return nil
+			}
+			result.Conflicts = append(result.Conflicts, domain.AnilistSyncConflict{AnilistID: entry.MediaID, Field: "entry", Winner: domain.SyncWinnerAnilist})
+			if err := s.syncRepo.DeleteOutboxEntries(entry.MediaID, change.lastID); err != nil {
+				return err
+			}
+			delete(pending, entry.MediaID)
+		}
+
+		if err := s.watchlistRepo.SaveWatchlistItem(&remoteItem); err != nil {
+			return err
+		}
+		result.Added = append(result.Added, entry.MediaID)
+		s.events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: "added", AnilistID: entry.MediaID})
+		return nil
+	}
+
+	base, wasSynced := synced[entry.MediaID]
+	baseItem := watchlistItemFromAnilist(base)
+	merged := item
+	for _, field := range domain.SyncFields {
+		if sameSyncField(item, remoteItem, field) {
+			continue
+		}
+		if wasSynced && sameSyncField(baseItem, remoteItem, field) {
+			continue
+		}
+
+		if change != nil {
+			if changedAt, ok := change.fields[field]; ok {
+				winner := domain.SyncWinnerAnilist
+				if !entry.UpdatedAt.After(changedAt) {
+					winner = domain.SyncWinnerLocal
+				}
+				result.Conflicts = append(result.Conflicts, domain.AnilistSyncConflict{AnilistID: entry.MediaID, Field: field, Winner: winner})
+				if winner == domain.SyncWinnerLocal {
+					continue
+				}
+			}
+		}
+		copySyncField(&merged, remoteItem, field)
+	}
+
+	if sameWatchState(item, merged) {
+		return nil
+	}
+	if err := s.watchlistRepo.SaveWatchlistItem(&merged); err != nil {
+		return err
+	}
+	result.Updated = append(result.Updated, entry.MediaID)
+	s.events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: "updated", AnilistID: entry.MediaID})
+	return nil
+}
+
+// push sends the queued changes, oldest first. Rate limiting, a rejected
+// token or a network failure stops the push until the next sync; other
+// failures are recorded on the entry and the rest go ahead. It is called
+// with s.mu held and unlocks it while waiting between changes; the push
+// stops if the account was disconnected or replaced in the meantime.
+func (s *AnilistSyncService) push(account *domain.AnilistAccount, result *domain.AnilistSyncResult) error {
+	outbox, err := s.syncRepo.GetOutbox()
+	if err != nil {
+		return err
+	}
+	_, order := pendingChanges(outbox)
+	if len(order) > maxPushesPerSync {
+		order = order[:maxPushesPerSync]
+	}
+
+	synced, err := s.syncRepo.GetSyncedEntries()
+	if err != nil {
+		return err
+	}
+
+	for i, anilistID := range order {
+		if i > 0 {
+			s.mu.Unlock()
+			time.Sleep(s.pushDelay)
+			s.mu.Lock()
+
+			current, err := s.syncRepo.GetAccount()
+			if err != nil {
+				return err
+			}
+			if current == nil || current.UserID != account.UserID {
+				return nil
+			}
+		}
+
+		// Read the change again, as the watchlist may have changed while
+		// waiting
+		change, item, err := s.pendingChange(anilistID)
+		if err != nil {
+			return err
+		}
+		if change == nil {
+			continue
+		}
+
+		err = s.pushChange(account, anilistID, change, item, synced, result)
+		if err != nil {
+			if markErr := s.syncRepo.MarkOutboxFailed(anilistID, change.lastID, err.Error()); markErr != nil {
+				return markErr
+			}
+			var urlErr *url.Error
+			if errors.Is(err, domain.ErrRateLimited) || errors.Is(err, domain.ErrAnilistUnauthorized) || errors.As(err, &urlErr) {
+				return fmt.Errorf("failed to push to anilist: %w", err)
+			}
+			log.Printf("Failed to push anime %d to AniList: %v", anilistID, err)
+			continue
+		}
+
+		if err := s.syncRepo.DeleteOutboxEntries(anilistID, change.lastID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pendingChange returns the queued change to one anime and its watchlist
+// entry, which is nil when it is not on the watchlist. The change is nil
+// when nothing is queued any more.
+func (s *AnilistSyncService) pendingChange(anilistID int) (*pendingChange, *domain.WatchlistItem, error) {
+	outbox, err := s.syncRepo.GetOutbox()
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, _ := pendingChanges(outbox)
+	change := changes[anilistID]
+	if change == nil {
+		return nil, nil, nil
+	}
+
+	items, err := s.watchlistRepo.GetWatchlist()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range items {
+		if items[i].AnilistID == anilistID {
+			return change, &items[i], nil
+		}
+	}
+	return change, nil, nil
+}
+
+func (s *AnilistSyncService) pushChange(account *domain.AnilistAccount, anilistID int, change *pendingChange, item *domain.WatchlistItem, synced map[int]domain.AnilistListEntry, result *domain.AnilistSyncResult) error {
+	// The whole entry is sent, as the fields not changed here already match
+	// AniList after the pull
+	if item != nil && change.deletedAt == nil {
+		entry, err := s.anilistService.SaveMediaListEntry(account.AccessToken, *item)
+		if err != nil {
+			return err
+		}
+		if err := s.syncRepo.SaveSyncedEntry(entry); err != nil {
+			return err
+		}
+		result.Pushed++
+		return nil
+	}
+
+	base, ok := synced[anilistID]
+	if !ok {
+		// Never reached AniList, so there is nothing to delete
+		return nil
+	}
+	err := s.anilistService.DeleteMediaListEntry(account.AccessToken, base.ListEntryID)
+	if err != nil && !errors.Is(err, domain.ErrAnimeNotFound) {
+		return err
+	}
+	if err := s.syncRepo.DeleteSyncedEntry(anilistID); err != nil {
+		return err
+	}
+	result.Deleted++
+	return nil
+}
+
+// queue records a local change while an account is connected. The change
+// itself is already saved, so failing to queue it is only logged.
+func (s *AnilistSyncService) queue(anilistID int, action string, fields []string) {
+	if !s.connected.Load() {
+		return
+	}
+
+	entry := &domain.OutboxEntry{
+		AnilistID: anilistID,
+		Action:    action,
+		Fields:    fields,
+		ChangedAt: time.Now(),
+	}
+	if err := s.syncRepo.AddOutboxEntry(entry); err != nil {
+		log.Printf("Failed to queue anime %d for AniList: %v", anilistID, err)
+	}
+}
+
+// trackedWatchlistStore queues every change made through it for AniList.
+type trackedWatchlistStore struct {
+	domain.WatchlistStore
+	sync *AnilistSyncService
+}
+
+func (t *trackedWatchlistStore) AddToWatchlist(anilistID int) error {
+	if err := t.WatchlistStore.AddToWatchlist(anilistID); err != nil {
+		return err
+	}
+	t.sync.queue(anilistID, domain.OutboxActionSave, domain.SyncFields)
+	return nil
+}
+
+func (t *trackedWatchlistStore) AddToWatchlistAt(anilistID int, addedAt time.Time) error {
+	if err := t.WatchlistStore.AddToWatchlistAt(anilistID, addedAt); err != nil {
+		return err
+	}
+	t.sync.queue(anilistID, domain.OutboxActionSave, domain.SyncFields)
+	return nil
+}
+
+func (t *trackedWatchlistStore) SaveWatchlistItem(item *domain.WatchlistItem) error {
+	fields := domain.SyncFields
+	if t.sync.connected.Load() {
+		items, err := t.WatchlistStore.GetWatchlist()
+		if err != nil {
+			return err
+		}
+		for _, existing := range items {
+			if existing.AnilistID == item.AnilistID {
+				fields = changedSyncFields(existing, *item)
+				break
+			}
+		}
+	}
+
+	if err := t.WatchlistStore.SaveWatchlistItem(item); err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		t.sync.queue(item.AnilistID, domain.OutboxActionSave, fields)
+	}
+	return nil
+}
+
+func (t *trackedWatchlistStore) RemoveFromWatchlist(anilistID int) error {
+	if err := t.WatchlistStore.RemoveFromWatchlist(anilistID); err != nil {
+		return err
+	}
+	t.sync.queue(anilistID, domain.OutboxActionDelete, nil)
+	return nil
+}
+
+func watchlistItemFromAnilist(entry domain.AnilistListEntry) domain.WatchlistItem {
+	return domain.WatchlistItem{
+		AnilistID:   entry.MediaID,
+		Status:      entry.Status,
+		Score:       entry.Score,
+		Progress:    entry.Progress,
+		StartedAt:   entry.StartedAt,
+		CompletedAt: entry.CompletedAt,
+	}
+}
+
+func changedSyncFields(old, new domain.WatchlistItem) []string {
+	var fields []string
+	for _, field := range domain.SyncFields {
+		if !sameSyncField(old, new, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func sameSyncField(a, b domain.WatchlistItem, field string) bool {
+	switch field {
+	case domain.SyncFieldStatus:
+		return a.Status == b.Status
+	case domain.SyncFieldScore:
+		return a.Score == b.Score
+	case domain.SyncFieldProgress:
+		return a.Progress == b.Progress
+	case domain.SyncFieldStartedAt:
+		return sameDate(a.StartedAt, b.StartedAt)
+	case domain.SyncFieldCompletedAt:
+		return sameDate(a.CompletedAt, b.CompletedAt)
+	}
+	return true
+}
+
+func copySyncField(dst *domain.WatchlistItem, src domain.WatchlistItem, field string) {
+	switch field {
+	case domain.SyncFieldStatus:
+		dst.Status = src.Status
+	case domain.SyncFieldScore:
+		dst.Score = src.Score
+	case domain.SyncFieldProgress:
+		dst.Progress = src.Progress
+	case domain.SyncFieldStartedAt:
+		dst.StartedAt = src.StartedAt
+	case domain.SyncFieldCompletedAt:
+		dst.CompletedAt = src.CompletedAt
+	}
+}
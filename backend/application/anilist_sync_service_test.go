@@ -0,0 +1,399 @@
+package application
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+	"anime-watchlist/backend/infrastructure/memory"
+)
+
+const fakeAnilistToken = "token"
+
+// fakeAnilist serves the list queries and mutations the sync uses, keeping
+// one user's list in memory.
+type fakeAnilist struct {
+	mu      sync.Mutex
+	entries map[int]*fakeListEntry // by media ID
+	nextID  int
+	// failSaves answers saves of these media IDs with a status code
+	failSaves map[int]int
+	saves     int
+	deletes   int
+}
+
+type fakeListEntry struct {
+	id        int
+	status    string
+	score     float64
+	progress  int
+	updatedAt time.Time
+}
+
+func (f *fakeAnilist) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+fakeAnilistToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	number := func(name string) int {
+		value, _ := req.Variables[name].(float64)
+		return int(value)
+	}
+
+	switch {
+	case strings.Contains(req.Query, "SaveMediaListEntry("):
+		mediaID := number("mediaId")
+		if status, ok := f.failSaves[mediaID]; ok {
+			w.WriteHeader(status)
+			return
+		}
+		f.saves++
+		entry := f.entries[mediaID]
+		if entry == nil {
+			f.nextID++
+			entry = &fakeListEntry{id: f.nextID}
+			f.entries[mediaID] = entry
+		}
+		entry.status, _ = req.Variables["status"].(string)
+		entry.score = float64(number("scoreRaw")) / 10
+		entry.progress = number("progress")
+		entry.updatedAt = time.Now()
+		writeAnilistJSON(w, map[string]interface{}{"SaveMediaListEntry": f.entryJSON(mediaID, entry)})
+
+	case strings.Contains(req.Query, "DeleteMediaListEntry("):
+		deleted := false
+		for mediaID, entry := range f.entries {
+			if entry.id == number("id") {
+				delete(f.entries, mediaID)
+				deleted = true
+				f.deletes++
+			}
+		}
+		writeAnilistJSON(w, map[string]interface{}{"DeleteMediaListEntry": map[string]bool{"deleted": deleted}})
+
+	case strings.Contains(req.Query, "MediaListCollection("):
+		entries := []interface{}{}
+		for mediaID, entry := range f.entries {
+			entries = append(entries, f.entryJSON(mediaID, entry))
+		}
+		writeAnilistJSON(w, map[string]interface{}{"MediaListCollection": map[string]interface{}{
+			"lists": []interface{}{map[string]interface{}{"isCustomList": false, "entries": entries}},
+		}})
+
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *fakeAnilist) entryJSON(mediaID int, entry *fakeListEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          entry.id,
+		"mediaId":     mediaID,
+		"status":      entry.status,
+		"score":       entry.score,
+		"progress":    entry.progress,
+		"startedAt":   map[string]interface{}{},
+		"completedAt": map[string]interface{}{},
+		"updatedAt":   entry.updatedAt.Unix(),
+	}
+}
+
+// edit changes an entry as if the user did on AniList at updatedAt.
+func (f *fakeAnilist) edit(mediaID int, updatedAt time.Time, change func(entry *fakeListEntry)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry := f.entries[mediaID]
+	if entry == nil {
+		f.nextID++
+		entry = &fakeListEntry{id: f.nextID, status: domain.WatchStatusCurrent}
+		f.entries[mediaID] = entry
+	}
+	change(entry)
+	entry.updatedAt = updatedAt
+}
+
+func (f *fakeAnilist) entry(mediaID int) *fakeListEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if entry := f.entries[mediaID]; entry != nil {
+		copied := *entry
+		return &copied
+	}
+	return nil
+}
+
+func writeAnilistJSON(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+type syncTest struct {
+	fake    *fakeAnilist
+	service *AnilistSyncService
+	repo    *database.AnilistSyncRepository
+	// watchlist queues its changes like the app's watchlist does
+	watchlist domain.WatchlistStore
+}
+
+// newSyncTest connects an account whose AniList list and watchlist both
+// hold anime 1 and 2, already synced.
+func newSyncTest(t *testing.T) *syncTest {
+	t.Helper()
+
+	fake := &fakeAnilist{entries: make(map[int]*fakeListEntry), failSaves: make(map[int]int)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	db, err := database.New(database.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	repo := database.NewAnilistSyncRepository(db.DB)
+	err = repo.SaveAccount(&domain.AnilistAccount{UserID: 7, UserName: "tester", AccessToken: fakeAnilistToken, ConnectedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := memory.NewWatchlistStore()
+	service := NewAnilistSyncService(NewAnilistService(srv.URL), store, repo, domain.AnilistOAuthConfig{}, nil)
+	service.pushDelay = 0
+
+	test := &syncTest{fake: fake, service: service, repo: repo, watchlist: service.Track(store)}
+
+	past := time.Now().Add(-time.Hour)
+	fake.edit(1, past, func(e *fakeListEntry) { e.progress = 3 })
+	fake.edit(2, past, func(e *fakeListEntry) { e.score = 7 })
+	if _, err := service.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if len(test.items()) != 2 {
+		t.Fatalf("first sync pulled %+v", test.items())
+	}
+	return test
+}
+
+func (st *syncTest) sync(t *testing.T) *domain.AnilistSyncResult {
+	t.Helper()
+	result, err := st.service.Sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func (st *syncTest) items() map[int]domain.WatchlistItem {
+	items, _ := st.watchlist.GetWatchlist()
+	byID := make(map[int]domain.WatchlistItem, len(items))
+	for _, item := range items {
+		byID[item.AnilistID] = item
+	}
+	return byID
+}
+
+// editLocal changes a watchlist entry through the tracked store.
+func (st *syncTest) editLocal(t *testing.T, anilistID int, change func(item *domain.WatchlistItem)) {
+	t.Helper()
+	item, ok := st.items()[anilistID]
+	if !ok {
+		t.Fatalf("anime %d not on the watchlist", anilistID)
+	}
+	change(&item)
+	if err := st.watchlist.SaveWatchlistItem(&item); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAnilistSyncPullsRemoteChanges(t *testing.T) {
+	st := newSyncTest(t)
+
+	st.fake.edit(1, time.Now(), func(e *fakeListEntry) { e.progress = 8 })
+	st.fake.edit(3, time.Now(), func(e *fakeListEntry) { e.status = domain.WatchStatusPlanning })
+
+	result := st.sync(t)
+	if len(result.Updated) != 1 || result.Updated[0] != 1 || len(result.Added) != 1 || result.Added[0] != 3 {
+		t.Errorf("result = %+v", result)
+	}
+	if result.Pushed != 0 || st.fake.saves != 0 {
+		t.Errorf("pulled changes were pushed back: %+v", result)
+	}
+
+	items := st.items()
+	if items[1].Progress != 8 || items[3].Status != domain.WatchStatusPlanning {
+		t.Errorf("watchlist = %+v", items)
+	}
+}
+
+func TestAnilistSyncPushesLocalChanges(t *testing.T) {
+	st := newSyncTest(t)
+
+	st.editLocal(t, 2, func(item *domain.WatchlistItem) { item.Score = 9.5 })
+	if err := st.watchlist.AddToWatchlist(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.watchlist.RemoveFromWatchlist(1); err != nil {
+		t.Fatal(err)
+	}
+
+	result := st.sync(t)
+	if result.Pushed != 2 || result.Deleted != 1 || result.Pending != 0 || len(result.Conflicts) != 0 {
+		t.Errorf("result = %+v", result)
+	}
+	if entry := st.fake.entry(2); entry == nil || entry.score != 9.5 {
+		t.Errorf("anime 2 on AniList = %+v", entry)
+	}
+	if st.fake.entry(4) == nil || st.fake.entry(1) != nil {
+		t.Error("added or removed anime not pushed")
+	}
+
+	// Nothing is left to do
+	result = st.sync(t)
+	if result.Pushed != 0 || len(result.Updated)+len(result.Added)+len(result.Removed) != 0 {
+		t.Errorf("second sync = %+v", result)
+	}
+}
+
+func TestAnilistSyncFieldChangedOnBothSides(t *testing.T) {
+	st := newSyncTest(t)
+
+	// Anime 1: progress changed on both sides, AniList later. Score only
+	// changed here, so it is kept either way.
+	st.editLocal(t, 1, func(item *domain.WatchlistItem) { item.Progress = 5; item.Score = 6 })
+	st.fake.edit(1, time.Now().Add(time.Minute), func(e *fakeListEntry) { e.progress = 9 })
+
+	// Anime 2: score changed on both sides, here later
+	st.fake.edit(2, time.Now().Add(-time.Minute), func(e *fakeListEntry) { e.score = 4 })
+	st.editLocal(t, 2, func(item *domain.WatchlistItem) { item.Score = 8 })
+
+	result := st.sync(t)
+
+	winners := make(map[int]string)
+	for _, conflict := range result.Conflicts {
+		winners[conflict.AnilistID] = conflict.Field + ":" + conflict.Winner
+	}
+	if winners[1] != "progress:"+domain.SyncWinnerAnilist || winners[2] != "score:"+domain.SyncWinnerLocal || len(result.Conflicts) != 2 {
+		t.Errorf("conflicts = %+v", result.Conflicts)
+	}
+
+	items := st.items()
+	if items[1].Progress != 9 || items[1].Score != 6 {
+		t.Errorf("anime 1 = %+v, want AniList's progress and the local score", items[1])
+	}
+	if items[2].Score != 8 {
+		t.Errorf("anime 2 = %+v, want the local score", items[2])
+	}
+	if entry := st.fake.entry(1); entry.progress != 9 || entry.score != 6 {
+		t.Errorf("anime 1 on AniList = %+v", entry)
+	}
+	if entry := st.fake.entry(2); entry.score != 8 {
+		t.Errorf("anime 2 on AniList = %+v", entry)
+	}
+}
+
+func TestAnilistSyncRemoteDeleteAgainstLocalEdit(t *testing.T) {
+	st := newSyncTest(t)
+
+	// Removed on AniList while edited here: the edit wins and adds it back
+	st.fake.mu.Lock()
+	delete(st.fake.entries, 1)
+	delete(st.fake.entries, 2)
+	st.fake.mu.Unlock()
+	st.editLocal(t, 1, func(item *domain.WatchlistItem) { item.Progress = 6 })
+
+	result := st.sync(t)
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != (domain.AnilistSyncConflict{AnilistID: 1, Field: "entry", Winner: domain.SyncWinnerLocal}) {
+		t.Errorf("conflicts = %+v", result.Conflicts)
+	}
+
+	// Anime 2 was not changed here, so the removal is pulled
+	items := st.items()
+	if _, ok := items[2]; ok || len(result.Removed) != 1 || result.Removed[0] != 2 {
+		t.Errorf("anime 2 not removed: %+v", result)
+	}
+	if items[1].Progress != 6 {
+		t.Errorf("anime 1 = %+v", items[1])
+	}
+	if entry := st.fake.entry(1); entry == nil || entry.progress != 6 {
+		t.Errorf("anime 1 not pushed back: %+v", entry)
+	}
+}
+
+func TestAnilistSyncFailedPushStaysQueued(t *testing.T) {
+	st := newSyncTest(t)
+
+	st.editLocal(t, 1, func(item *domain.WatchlistItem) { item.Progress = 4 })
+	st.editLocal(t, 2, func(item *domain.WatchlistItem) { item.Score = 3 })
+	st.fake.failSaves[1] = http.StatusInternalServerError
+
+	// Other failures are recorded and the rest of the outbox goes ahead
+	result := st.sync(t)
+	if result.Pushed != 1 || result.Pending != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	outbox, err := st.repo.GetOutbox()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outbox) != 1 || outbox[0].AnilistID != 1 || outbox[0].Attempts != 1 || outbox[0].LastError == "" {
+		t.Errorf("outbox = %+v", outbox)
+	}
+
+	// Rate limiting stops the push and keeps the change
+	st.fake.failSaves[1] = http.StatusTooManyRequests
+	if _, err := st.service.Sync(); err == nil {
+		t.Error("rate limited sync succeeded")
+	}
+	if count, _ := st.repo.CountOutbox(); count != 1 {
+		t.Errorf("%d changes queued, want 1", count)
+	}
+
+	delete(st.fake.failSaves, 1)
+	result = st.sync(t)
+	if result.Pushed != 1 || result.Pending != 0 {
+		t.Errorf("retry = %+v", result)
+	}
+	if entry := st.fake.entry(1); entry.progress != 4 {
+		t.Errorf("anime 1 on AniList = %+v", entry)
+	}
+}
+
+func TestAnilistSyncCapsPushesPerSync(t *testing.T) {
+	st := newSyncTest(t)
+
+	for id := 100; id < 100+maxPushesPerSync+2; id++ {
+		if err := st.watchlist.AddToWatchlist(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := st.sync(t)
+	if result.Pushed != maxPushesPerSync || result.Pending != 2 {
+		t.Errorf("result = %+v", result)
+	}
+
+	result = st.sync(t)
+	if result.Pushed != 2 || result.Pending != 0 {
+		t.Errorf("second sync = %+v", result)
+	}
+}
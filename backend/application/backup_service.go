@@ -0,0 +1,228 @@
+package application
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+const (
+	backupPrefix     = "anime-"
+	backupSuffix     = ".db"
+	backupTimeLayout = "20060102-150405"
+)
+
+// BackupService writes snapshots of the SQLite database to a directory and
+// prunes old ones. Retention keeps the newest snapshot of each of the last
+// keepDaily days and of each of the last keepWeekly weeks.
+type BackupService struct {
+	db         *database.Database
+	dir        string
+	keepDaily  int
+	keepWeekly int
+	// usesPostgres is set when the watchlist and shows are kept in
+	// PostgreSQL, which a snapshot of the SQLite file would miss
+	usesPostgres bool
+	mu           sync.Mutex
+}
+
+func NewBackupService(db *database.Database, dir string, keepDaily, keepWeekly int, usesPostgres bool) *BackupService {
+	return &BackupService{
+		db:           db,
+		dir:          dir,
+		keepDaily:    keepDaily,
+		keepWeekly:   keepWeekly,
+		usesPostgres: usesPostgres,
+	}
+}
+
+// Backup writes a new snapshot while the server keeps running. It refuses
+// when the watchlist is kept in PostgreSQL, as the snapshot would look
+// complete while missing it.
+func (s *BackupService) Backup() (*domain.BackupInfo, error) {
+	if s.usesPostgres {
+		return nil, domain.ErrBackupPostgres
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := time.Now()
+	name, err := s.freeBackupName(now)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(s.dir, name)
+	if err := s.db.Backup(path); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.BackupInfo{Name: name, Size: info.Size(), CreatedAt: now}, nil
+}
+
+// Snapshot is the scheduled backup: it writes a snapshot and then applies
+// retention.
+func (s *BackupService) Snapshot() (*domain.BackupInfo, int, error) {
+	backup, err := s.Backup()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	removed, err := s.Prune()
+	if err != nil {
+		return backup, removed, fmt.Errorf("failed to prune backups: %w", err)
+	}
+	return backup, removed, nil
+}
+
+// ListBackups returns the snapshots in the backup directory, newest first.
+// Other files in the directory are ignored.
+func (s *BackupService) ListBackups() ([]domain.BackupInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return []domain.BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	backups := []domain.BackupInfo{}
+	sequence := make(map[string]int)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		created, seq, ok := parseBackupName(name)
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, domain.BackupInfo{Name: name, Size: info.Size(), CreatedAt: created})
+		sequence[name] = seq
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			return backups[i].CreatedAt.After(backups[j].CreatedAt)
+		}
+		return sequence[backups[i].Name] > sequence[backups[j].Name]
+	})
+	return backups, nil
+}
+
+// freeBackupName names a snapshot taken at now. Names have one-second
+// resolution, so later snapshots in the same second get a -2, -3... suffix.
+func (s *BackupService) freeBackupName(now time.Time) (string, error) {
+	stamp := backupPrefix + now.Format(backupTimeLayout)
+	for seq := 1; ; seq++ {
+		name := stamp + backupSuffix
+		if seq > 1 {
+			name = fmt.Sprintf("%s-%d%s", stamp, seq, backupSuffix)
+		}
+
+		_, err := os.Stat(filepath.Join(s.dir, name))
+		if os.IsNotExist(err) {
+			return name, nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to check backup %s: %w", name, err)
+		}
+	}
+}
+
+// parseBackupName reads the time and same-second sequence number from a
+// snapshot name.
+func parseBackupName(name string) (time.Time, int, bool) {
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+
+	seq := 1
+	if len(stamp) > len(backupTimeLayout) {
+		n, err := strconv.Atoi(strings.TrimPrefix(stamp[len(backupTimeLayout):], "-"))
+		if err != nil || n < 2 || stamp[len(backupTimeLayout)] != '-' {
+			return time.Time{}, 0, false
+		}
+		seq = n
+		stamp = stamp[:len(backupTimeLayout)]
+	}
+
+	created, err := time.ParseInLocation(backupTimeLayout, stamp, time.Local)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return created, seq, true
+}
+
+// Prune deletes snapshots that retention no longer keeps. The newest
+// snapshot is always kept.
+func (s *BackupService) Prune() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backups, err := s.ListBackups()
+	if err != nil {
+		return 0, err
+	}
+
+	keep := retainedBackups(backups, s.keepDaily, s.keepWeekly)
+
+	removed := 0
+	for _, backup := range backups {
+		if keep[backup.Name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, backup.Name)); err != nil {
+			log.Printf("Failed to remove backup %s: %v", backup.Name, err)
+			continue
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// retainedBackups picks the names to keep from backups sorted newest first.
+func retainedBackups(backups []domain.BackupInfo, keepDaily, keepWeekly int) map[string]bool {
+	keep := make(map[string]bool)
+	if len(backups) > 0 {
+		keep[backups[0].Name] = true
+	}
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	for _, backup := range backups {
+		day := backup.CreatedAt.Format("2006-01-02")
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep[backup.Name] = true
+		}
+
+		year, week := backup.CreatedAt.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[weekKey] && len(weeks) < keepWeekly {
+			weeks[weekKey] = true
+			keep[backup.Name] = true
+		}
+	}
+	return keep
+}
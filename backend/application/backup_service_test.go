@@ -0,0 +1,90 @@
+package application
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+func TestBackupNamesWithinOneSecond(t *testing.T) {
+	dir := t.TempDir()
+	service := NewBackupService(nil, dir, 7, 4, false)
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.Local)
+	var names []string
+	for i := 0; i < 3; i++ {
+		name, err := service.freeBackupName(now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	want := []string{"anime-20240506-070809.db", "anime-20240506-070809-2.db", "anime-20240506-070809-3.db"}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("backup %d named %s, want %s", i, names[i], want[i])
+		}
+	}
+
+	// Not snapshots
+	for _, name := range []string{"anime-20240506-070809-x.db", "anime-20240506-070809-1.db", "anime-latest.db"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	backups, err := service.ListBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 3 {
+		t.Fatalf("listed %+v", backups)
+	}
+	for i, backup := range backups {
+		if backup.Name != want[len(want)-1-i] || !backup.CreatedAt.Equal(now) {
+			t.Errorf("backups[%d] = %+v, want %s", i, backup, want[len(want)-1-i])
+		}
+	}
+}
+
+func TestBackupRefusedWithPostgres(t *testing.T) {
+	db, err := database.New(database.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dir := t.TempDir()
+	if _, err := NewBackupService(db, dir, 7, 4, true).Backup(); !errors.Is(err, domain.ErrBackupPostgres) {
+		t.Fatalf("err = %v, want ErrBackupPostgres", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("wrote %d files", len(entries))
+	}
+
+	backup, err := NewBackupService(db, dir, 7, 4, false).Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup.Size == 0 {
+		t.Errorf("empty backup %+v", backup)
+	}
+
+	// Usually within the same second as the first
+	again, err := NewBackupService(db, dir, 7, 4, false).Backup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Name == backup.Name {
+		t.Errorf("both backups named %s", backup.Name)
+	}
+}
@@ -0,0 +1,148 @@
+package application
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+	"anime-watchlist/backend/infrastructure/mediaserver"
+)
+
+// CollectionService keeps a collection on the media server in step with the
+// watchlist: every mapped show whose AniList entry, or one of whose seasons,
+// is on the watchlist.
+type CollectionService struct {
+	server        mediaserver.MediaServer
+	plexRepo      domain.PlexShowStore
+	seasonRepo    *database.SeasonMappingRepository
+	watchlistRepo domain.WatchlistStore
+	libraryID     string
+	name          string
+	mu            sync.Mutex
+}
+
+func NewCollectionService(server mediaserver.MediaServer, plexRepo domain.PlexShowStore, seasonRepo *database.SeasonMappingRepository, watchlistRepo domain.WatchlistStore, libraryID string, name string) *CollectionService {
+	return &CollectionService{
+		server:        server,
+		plexRepo:      plexRepo,
+		seasonRepo:    seasonRepo,
+		watchlistRepo: watchlistRepo,
+		libraryID:     libraryID,
+		name:          name,
+	}
+}
+
+// SyncWatchlistCollection adds shows that joined the watchlist and removes
+// those that left it. Shows already in the right state are left untouched,
+// so repeated runs are safe.
+func (s *CollectionService) SyncWatchlistCollection() (*domain.CollectionSyncResult, error) {
+	manager, ok := s.server.(mediaserver.CollectionManager)
+	if !ok {
+		return nil, domain.ErrCollectionsUnsupported
+	}
+	if s.libraryID == "" {
+		return nil, domain.ErrLibraryNotSet
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted, err := s.watchlistShows()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := manager.GetCollectionItems(s.libraryID, s.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection %s: %w", s.name, err)
+	}
+
+	inCollection := make(map[string]bool, len(current))
+	for _, id := range current {
+		inCollection[id] = true
+	}
+
+	var toAdd, toRemove []string
+	for id := range wanted {
+		if !inCollection[id] {
+			toAdd = append(toAdd, id)
+		}
+	}
+	for _, id := range current {
+		if !wanted[id] {
+			toRemove = append(toRemove, id)
+		}
+	}
+
+	if err := manager.AddToCollection(s.libraryID, s.name, toAdd); err != nil {
+		return nil, fmt.Errorf("failed to add shows to collection %s: %w", s.name, err)
+	}
+	if err := manager.RemoveFromCollection(s.libraryID, s.name, toRemove); err != nil {
+		return nil, fmt.Errorf("failed to remove shows from collection %s: %w", s.name, err)
+	}
+
+	return &domain.CollectionSyncResult{
+		Collection: s.name,
+		Total:      len(wanted),
+		Added:      len(toAdd),
+		Removed:    len(toRemove),
+	}, nil
+}
+
+// SyncAfterLibrarySync runs after a library sync and only logs failures, so
+// a collection problem never fails the sync itself.
+func (s *CollectionService) SyncAfterLibrarySync() {
+	result, err := s.SyncWatchlistCollection()
+	if err != nil {
+		log.Printf("Failed to sync watchlist collection: %v", err)
+		return
+	}
+	log.Printf("Synced watchlist collection %s: %d shows, %d added, %d removed", result.Collection, result.Total, result.Added, result.Removed)
+}
+
+// watchlistShows returns the server IDs of the shows that belong in the
+// collection.
+func (s *CollectionService) watchlistShows() (map[string]bool, error) {
+	items, err := s.watchlistRepo.GetWatchlist()
+	if err != nil {
+		return nil, err
+	}
+
+	onWatchlist := make(map[int]bool, len(items))
+	for _, item := range items {
+		onWatchlist[item.AnilistID] = true
+	}
+
+	shows, err := s.plexRepo.GetAllPlexShows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shows: %w", err)
+	}
+
+	wanted := make(map[string]bool)
+	for i := range shows {
+		show := &shows[i]
+		if show.Ignored || show.Source != s.server.Type() {
+			continue
+		}
+
+		if show.AnilistID != nil && onWatchlist[*show.AnilistID] {
+			wanted[serverItemID(show)] = true
+			continue
+		}
+
+		seasons, err := s.seasonRepo.GetSeasonMappings(show.PlexID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get season mappings: %w", err)
+		}
+		for _, season := range seasons {
+			if onWatchlist[season.AnilistID] {
+				wanted[serverItemID(show)] = true
+				break
+			}
+		}
+	}
+
+	return wanted, nil
+}
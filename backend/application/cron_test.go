@@ -0,0 +1,97 @@
+package application
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Monday
+	monday := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec  string
+		after time.Time
+		want  time.Time
+	}{
+		{"6h", monday, monday.Add(6 * time.Hour)},
+		{"* * * * *", monday.Add(15 * time.Second), at(2024, 1, 1, 10, 31)},
+
+		// Steps and ranges
+		{"*/15 * * * *", monday.Add(time.Minute), at(2024, 1, 1, 10, 45)},
+		{"0 */6 * * *", monday, at(2024, 1, 1, 12, 0)},
+		{"5/20 * * * *", monday, at(2024, 1, 1, 10, 45)},
+		{"0 9-17/4 * * *", monday, at(2024, 1, 1, 13, 0)},
+		{"0 9-17/4 * * *", at(2024, 1, 1, 17, 0), at(2024, 1, 2, 9, 0)},
+		{"15,45 8-9 * * *", monday, at(2024, 1, 2, 8, 15)},
+		{"0 12 1 1,7 *", at(2024, 1, 1, 12, 0), at(2024, 7, 1, 12, 0)},
+
+		// Sunday is 0 or 7
+		{"0 0 * * 0", monday, at(2024, 1, 7, 0, 0)},
+		{"0 0 * * 7", monday, at(2024, 1, 7, 0, 0)},
+		{"0 0 * * 6-7", at(2024, 1, 6, 12, 0), at(2024, 1, 7, 0, 0)},
+		{"0 0 * * 1-5", at(2024, 1, 5, 12, 0), at(2024, 1, 8, 0, 0)},
+
+		// Either the day of the month or the weekday
+		{"0 0 15 * *", monday, at(2024, 1, 15, 0, 0)},
+		{"0 0 15 * 5", monday, at(2024, 1, 5, 0, 0)},
+		{"0 0 15 * 5", at(2024, 1, 12, 12, 0), at(2024, 1, 15, 0, 0)},
+		{"0 0 */10 * *", monday, at(2024, 1, 11, 0, 0)},
+
+		// Leap days
+		{"0 0 29 2 *", at(2024, 3, 1, 0, 0), at(2028, 2, 29, 0, 0)},
+		{"0 0 29 2 *", at(2096, 3, 1, 0, 0), at(2104, 2, 29, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.spec)
+		if err != nil {
+			t.Errorf("%q: %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%q after %s = %s, want %s", tt.spec, tt.after.Format(time.RFC3339), got.Format(time.RFC3339), tt.want.Format(time.RFC3339))
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"", "empty"},
+		{"30s", "shorter than a minute"},
+		{"* * * *", "5 cron fields"},
+		{"60 * * * *", "out of range"},
+		{"* 24 * * *", "out of range"},
+		{"* * 0 * *", "out of range"},
+		{"* * * 13 *", "out of range"},
+		{"* * * * 8", "out of range"},
+		{"5-1 * * * *", "out of range"},
+		{"*/0 * * * *", "invalid step"},
+		{"a * * * *", "invalid value"},
+		{"1-a * * * *", "invalid range"},
+
+		// Never matches
+		{"0 0 30 2 *", "no date"},
+		{"0 0 31 2 *", "no date"},
+		{"0 0 31 4,6,9,11 *", "no date"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseSchedule(tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: err = %v, want %q", tt.spec, err, tt.want)
+		}
+	}
+
+	// A restricted weekday makes an impossible date possible
+	if _, err := ParseSchedule("0 0 30 2 1"); err != nil {
+		t.Errorf("0 0 30 2 1: %v", err)
+	}
+}
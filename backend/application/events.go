@@ -0,0 +1,90 @@
+package application
+
+import (
+	"sync"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind before
+// further events are dropped for it.
+const subscriberBuffer = 64
+
+// EventBus fans events out to subscribers such as SSE clients. Publish never
+// blocks: a subscriber that is not keeping up misses events instead of
+// holding up the publisher.
+type EventBus struct {
+	mu          sync.Mutex
+	nextID      uint64
+	subscribers map[chan domain.Event]struct{}
+	closed      bool
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[chan domain.Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel of events and a function that must be called
+// to unsubscribe once the caller stops reading.
+func (b *EventBus) Subscribe() (<-chan domain.Event, func()) {
+	ch := make(chan domain.Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every subscription, which lets long-lived streams finish when
+// the server shuts down.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (b *EventBus) Publish(eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := domain.Event{
+		ID:   b.nextID,
+		Type: eventType,
+		Time: time.Now(),
+		Data: data,
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
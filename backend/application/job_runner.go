@@ -0,0 +1,366 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+// rateLimitBackoff is how long a job pauses after AniList answers 429.
+const rateLimitBackoff = time.Minute
+
+var (
+	errJobCancelled  = errors.New("job cancelled")
+	errRunnerStopped = errors.New("job runner stopped")
+)
+
+// JobRunner runs auto-map jobs in the background. Job state and per-show
+// outcomes are persisted after every show so jobs survive restarts.
+type JobRunner struct {
+	jobRepo        *database.JobRepository
+	plexRepo       domain.PlexShowStore
+	mappingService *MappingService
+	events         *EventBus
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelCauseFunc
+	wg      sync.WaitGroup
+}
+
+func NewJobRunner(jobRepo *database.JobRepository, plexRepo domain.PlexShowStore, mappingService *MappingService, events *EventBus) *JobRunner {
+	return &JobRunner{
+		jobRepo:        jobRepo,
+		plexRepo:       plexRepo,
+		mappingService: mappingService,
+		events:         events,
+		cancels:        make(map[string]context.CancelCauseFunc),
+	}
+}
+
+// StartBulkAutoMap queues a job over the unmapped backlog. A limit of zero
+// processes every unmapped show.
+func (r *JobRunner) StartBulkAutoMap(limit int) (*domain.Job, error) {
+	return r.start(domain.JobTypeBulkAutoMap, limit)
+}
+
+// StartAutoMapNew queues a job over unmapped shows that auto-map has not
+// looked at yet, such as those picked up by the latest sync.
+func (r *JobRunner) StartAutoMapNew() (*domain.Job, error) {
+	return r.start(domain.JobTypeAutoMapNew, 0)
+}
+
+func (r *JobRunner) start(jobType string, limit int) (*domain.Job, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Both job types walk the same unmapped shows, so only one may run
+	unfinished, err := r.jobRepo.GetUnfinishedJobs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check running jobs: %w", err)
+	}
+	if len(unfinished) > 0 {
+		return &unfinished[0], domain.ErrJobAlreadyRunning
+	}
+
+	job := &domain.Job{
+		ID:     newJobID(),
+		Type:   jobType,
+		Status: domain.JobStatusPending,
+		Limit:  limit,
+	}
+
+	shows, err := r.pendingShows(job)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Total = len(shows)
+	if limit > 0 && job.Total > limit {
+		job.Total = limit
+	}
+
+	if err := r.jobRepo.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	r.launch(job)
+	return job, nil
+}
+
+func (r *JobRunner) GetJob(id string) (*domain.Job, error) {
+	job, err := r.jobRepo.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := r.jobRepo.GetJobItems(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job items: %w", err)
+	}
+	job.Items = items
+
+	return job, nil
+}
+
+func (r *JobRunner) GetRecentJobs() ([]domain.Job, error) {
+	return r.jobRepo.GetRecentJobs(20)
+}
+
+// CancelJob stops a running job, or marks a job that is not running in this
+// process as cancelled so it is not resumed.
+func (r *JobRunner) CancelJob(id string) (*domain.Job, error) {
+	job, err := r.jobRepo.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.IsFinished() {
+		return job, nil
+	}
+
+	r.mu.Lock()
+	cancel, running := r.cancels[id]
+	r.mu.Unlock()
+
+	if running {
+		cancel(errJobCancelled)
+		return job, nil
+	}
+
+	r.finish(job, domain.JobStatusCancelled, "")
+	return job, nil
+}
+
+// Resume restarts jobs that were interrupted by a shutdown or crash from
+// their last checkpoint.
+func (r *JobRunner) Resume() error {
+	jobs, err := r.jobRepo.GetUnfinishedJobs()
+	if err != nil {
+		return fmt.Errorf("failed to get unfinished jobs: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range jobs {
+		log.Printf("Resuming job %s from checkpoint %d", jobs[i].ID, jobs[i].Checkpoint)
+		r.launch(&jobs[i])
+	}
+
+	return nil
+}
+
+// Shutdown stops all running jobs without marking them cancelled, so they
+// resume on the next start.
+func (r *JobRunner) Shutdown() {
+	r.mu.Lock()
+	for _, cancel := range r.cancels {
+		cancel(errRunnerStopped)
+	}
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+// launch must be called with r.mu held.
+func (r *JobRunner) launch(job *domain.Job) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	r.cancels[job.ID] = cancel
+	r.wg.Add(1)
+
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mu.Lock()
+			delete(r.cancels, job.ID)
+			r.mu.Unlock()
+			cancel(nil)
+		}()
+
+		r.run(ctx, job)
+	}()
+}
+
+func (r *JobRunner) run(ctx context.Context, job *domain.Job) {
+	if job.ResumeAfter != nil && !r.wait(ctx, job, time.Until(*job.ResumeAfter)) {
+		return
+	}
+
+	job.Status = domain.JobStatusRunning
+	job.ResumeAfter = nil
+	r.save(job)
+
+	shows, err := r.pendingShows(job)
+	if err != nil {
+		r.finish(job, domain.JobStatusFailed, err.Error())
+		return
+	}
+
+	for i := 0; i < len(shows); i++ {
+		if job.Limit > 0 && job.Processed >= job.Limit {
+			break
+		}
+		if !r.checkContext(ctx, job) {
+			return
+		}
+
+		show := &shows[i]
+		result, err := r.mappingService.AutoMapShow(show)
+		if errors.Is(err, domain.ErrRateLimited) {
+			resumeAfter := time.Now().Add(rateLimitBackoff)
+			job.Status = domain.JobStatusPaused
+			job.ResumeAfter = &resumeAfter
+			r.save(job)
+
+			if !r.wait(ctx, job, rateLimitBackoff) {
+				return
+			}
+
+			job.Status = domain.JobStatusRunning
+			job.ResumeAfter = nil
+			r.save(job)
+			i-- // Retry the same show
+			continue
+		}
+
+		item := &domain.JobItem{
+			JobID:  job.ID,
+			PlexID: show.PlexID,
+			Title:  show.Title,
+		}
+
+		switch {
+		case err != nil:
+			item.Outcome = domain.AutoMapOutcomeError
+			item.Message = err.Error()
+			job.Failed++
+		default:
+			item.Outcome = result.Outcome
+			if len(result.Candidates) > 0 {
+				item.AnilistID = &result.Candidates[0].Anime.AnilistID
+				item.Score = result.Candidates[0].Score
+			}
+			// Shows without a match are a normal outcome and only show up
+			// in the job items, so Failed counts just errors
+			switch result.Outcome {
+			case domain.AutoMapOutcomeMapped:
+				job.Mapped++
+				r.events.Publish(domain.EventShowMapped, result.Show)
+			case domain.AutoMapOutcomeQueued:
+				job.Queued++
+			}
+		}
+
+		if err := r.jobRepo.AddJobItem(item); err != nil {
+			log.Printf("Failed to save outcome of %s for job %s: %v", show.Title, job.ID, err)
+		}
+		if err := r.plexRepo.MarkAutoMapped(show.PlexID); err != nil {
+			log.Printf("Failed to mark %s as auto-mapped: %v", show.Title, err)
+		}
+
+		job.Processed++
+		job.Checkpoint = show.PlexID
+		r.save(job)
+	}
+
+	r.finish(job, domain.JobStatusCompleted, "")
+}
+
+// pendingShows returns the unmapped shows after the job's checkpoint in Plex
+// ID order, which is the order jobs process them in. Shows whose candidate
+// the user rejected are left out, so the total counts only shows the job
+// will process.
+func (r *JobRunner) pendingShows(job *domain.Job) ([]domain.PlexShow, error) {
+	shows, err := r.plexRepo.GetUnmappedShows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unmapped shows: %w", err)
+	}
+
+	rejected, err := r.mappingService.RejectedPlexIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]domain.PlexShow, 0, len(shows))
+	for _, show := range shows {
+		if job.Type == domain.JobTypeAutoMapNew && show.AutoMappedAt != nil {
+			continue
+		}
+		if rejected[show.PlexID] {
+			continue
+		}
+		if show.PlexID > job.Checkpoint {
+			pending = append(pending, show)
+		}
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].PlexID < pending[j].PlexID
+	})
+
+	return pending, nil
+}
+
+// wait sleeps for d unless the job is cancelled or the runner stops first.
+func (r *JobRunner) wait(ctx context.Context, job *domain.Job, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return r.checkContext(ctx, job)
+	}
+}
+
+// checkContext reports whether the job should keep going. A cancelled job is
+// marked as such; a job stopped by shutdown keeps its status for resuming.
+func (r *JobRunner) checkContext(ctx context.Context, job *domain.Job) bool {
+	if ctx.Err() == nil {
+		return true
+	}
+
+	if errors.Is(context.Cause(ctx), errJobCancelled) {
+		r.finish(job, domain.JobStatusCancelled, "")
+	}
+	return false
+}
+
+func (r *JobRunner) finish(job *domain.Job, status string, message string) {
+	now := time.Now()
+	job.Status = status
+	job.Error = message
+	job.ResumeAfter = nil
+	job.FinishedAt = &now
+	r.save(job)
+}
+
+// save persists the job and tells subscribers about its progress.
+func (r *JobRunner) save(job *domain.Job) {
+	if err := r.jobRepo.UpdateJob(job); err != nil {
+		log.Printf("Failed to save job %s: %v", job.ID, err)
+	}
+
+	progress := *job
+	progress.Items = nil
+	r.events.Publish(domain.EventJobProgress, progress)
+}
+
+func newJobID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
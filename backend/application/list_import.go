@@ -0,0 +1,142 @@
+package application
+
+import (
+	"fmt"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+// resolvedListEntry is an entry of another site's list whose AniList ID is
+// known.
+type resolvedListEntry struct {
+	report domain.ListImportEntry
+	item   domain.WatchlistItem
+}
+
+func newListImportResult(source, strategy string, dryRun bool, total int) *domain.ListImportResult {
+	return &domain.ListImportResult{
+		Source:     source,
+		Strategy:   strategy,
+		DryRun:     dryRun,
+		Total:      total,
+		Added:      []domain.ListImportEntry{},
+		Updated:    []domain.ListImportEntry{},
+		Unresolved: []domain.ListImportEntry{},
+		Conflicts:  []domain.ListImportEntry{},
+	}
+}
+
+// listMergeStrategy validates a merge strategy, which defaults to keeping
+// local entries.
+func listMergeStrategy(strategy string) (string, error) {
+	switch strategy {
+	case "":
+		return domain.ListMergeKeep, nil
+	case domain.ListMergeKeep, domain.ListMergeOverwrite, domain.ListMergeFill:
+		return strategy, nil
+	}
+	return "", &domain.ValidationError{Field: "strategy", Message: "strategy must be keep, overwrite or fill"}
+}
+
+// importListEntries adds entries that are not on the watchlist and merges
+// the others into it with strategy. Entries must have distinct AniList IDs.
+// With dryRun nothing is written.
+func importListEntries(repo domain.WatchlistStore, events *EventBus, entries []resolvedListEntry, strategy string, dryRun bool, result *domain.ListImportResult) error {
+	items, err := repo.GetWatchlist()
+	if err != nil {
+		return err
+	}
+	current := make(map[int]domain.WatchlistItem, len(items))
+	for _, item := range items {
+		current[item.AnilistID] = item
+	}
+
+	var added, updated []domain.WatchlistItem
+	for _, entry := range entries {
+		existing, ok := current[entry.item.AnilistID]
+		if !ok {
+			result.Added = append(result.Added, entry.report)
+			added = append(added, entry.item)
+			continue
+		}
+
+		merged := mergeWatchState(existing, entry.item, strategy)
+		switch {
+		case !sameWatchState(existing, merged):
+			result.Updated = append(result.Updated, entry.report)
+			updated = append(updated, merged)
+		case !sameWatchState(existing, entry.item):
+			entry.report.Reason = "watchlist has it as " + describeWatchState(existing)
+			result.Conflicts = append(result.Conflicts, entry.report)
+		default:
+			result.Unchanged++
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	for i := range added {
+		if err := repo.SaveWatchlistItem(&added[i]); err != nil {
+			return err
+		}
+		events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: "added", AnilistID: added[i].AnilistID})
+	}
+	for i := range updated {
+		if err := repo.SaveWatchlistItem(&updated[i]); err != nil {
+			return err
+		}
+		events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: "updated", AnilistID: updated[i].AnilistID})
+	}
+	return nil
+}
+
+// mergeWatchState returns the state a local entry should have after
+// importing another one.
+func mergeWatchState(local, imported domain.WatchlistItem, strategy string) domain.WatchlistItem {
+	merged := local
+	switch strategy {
+	case domain.ListMergeOverwrite:
+		merged.Status = imported.Status
+		merged.Score = imported.Score
+		merged.Progress = imported.Progress
+		merged.StartedAt = imported.StartedAt
+		merged.CompletedAt = imported.CompletedAt
+	case domain.ListMergeFill:
+		if merged.Score == 0 {
+			merged.Score = imported.Score
+		}
+		if merged.StartedAt == nil {
+			merged.StartedAt = imported.StartedAt
+		}
+		if merged.CompletedAt == nil {
+			merged.CompletedAt = imported.CompletedAt
+		}
+		merged.Progress = max(merged.Progress, imported.Progress)
+	}
+	return merged
+}
+
+// sameWatchState compares the list state of two entries, ignoring when they
+// were added.
+func sameWatchState(a, b domain.WatchlistItem) bool {
+	return a.Status == b.Status && a.Score == b.Score && a.Progress == b.Progress &&
+		sameDate(a.StartedAt, b.StartedAt) && sameDate(a.CompletedAt, b.CompletedAt)
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
+
+func describeWatchState(item domain.WatchlistItem) string {
+	description := fmt.Sprintf("%s with %d episodes watched", item.Status, item.Progress)
+	if item.Score > 0 {
+		description += fmt.Sprintf(", scored %g", item.Score)
+	}
+	return description
+}
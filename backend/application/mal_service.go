@@ -0,0 +1,226 @@
+package application
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/mal"
+)
+
+// MALService imports MyAnimeList exports into the watchlist and exports the
+// watchlist in the same format. MAL IDs are resolved through AniList, which
+// records the MAL ID of most entries.
+type MALService struct {
+	anilistService *AnilistService
+	watchlistRepo  domain.WatchlistStore
+	events         *EventBus
+}
+
+func NewMALService(anilistService *AnilistService, watchlistRepo domain.WatchlistStore, events *EventBus) *MALService {
+	return &MALService{
+		anilistService: anilistService,
+		watchlistRepo:  watchlistRepo,
+		events:         events,
+	}
+}
+
+// Import adds the entries of a MAL export, gzipped or not, that are not on
+// the watchlist yet and merges the others into it with strategy. Entries
+// without an AniList match are reported as unresolved. With dryRun nothing
+// is written.
+func (s *MALService) Import(r io.Reader, strategy string, dryRun bool) (*domain.ListImportResult, error) {
+	strategy, err := listMergeStrategy(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := mal.Read(r)
+	if err != nil {
+		return nil, &domain.ValidationError{Field: "file", Message: err.Error()}
+	}
+
+	malIDs := make([]int, 0, len(entries))
+	seenMalIDs := make(map[int]bool, len(entries))
+	for _, entry := range entries {
+		if !seenMalIDs[entry.MalID] {
+			seenMalIDs[entry.MalID] = true
+			malIDs = append(malIDs, entry.MalID)
+		}
+	}
+
+	links, err := lookupMediaLinks(malIDs, s.anilistService.GetMediaLinksByMalIDs)
+	if err != nil {
+		return nil, err
+	}
+	byMalID := make(map[int]domain.MediaLink, len(links))
+	for _, link := range links {
+		// AniList occasionally links one MAL ID to several entries; keep the
+		// oldest, which is the one MAL means
+		if existing, ok := byMalID[link.MalID]; !ok || link.AnilistID < existing.AnilistID {
+			byMalID[link.MalID] = link
+		}
+	}
+
+	result := newListImportResult("myanimelist", strategy, dryRun, len(entries))
+	imported := make(map[int]int, len(entries))
+	var resolved []resolvedListEntry
+	for _, entry := range entries {
+		item := watchlistItemFromMAL(entry)
+		report := domain.ListImportEntry{SourceID: entry.MalID, Title: entry.Title, Status: item.Status}
+
+		link, ok := byMalID[entry.MalID]
+		if !ok {
+			report.Reason = "AniList has no anime with this MyAnimeList ID"
+			result.Unresolved = append(result.Unresolved, report)
+			continue
+		}
+		item.AnilistID = link.AnilistID
+		report.AnilistID = link.AnilistID
+
+		if malID, ok := imported[link.AnilistID]; ok {
+			report.Reason = fmt.Sprintf("same AniList anime as MyAnimeList ID %d", malID)
+			result.Conflicts = append(result.Conflicts, report)
+			continue
+		}
+		imported[link.AnilistID] = entry.MalID
+
+		resolved = append(resolved, resolvedListEntry{report: report, item: item})
+	}
+
+	if err := importListEntries(s.watchlistRepo, s.events, resolved, strategy, dryRun, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Export converts the watchlist to MAL export entries. Anime AniList has no
+// MAL ID for cannot be imported by MAL and are left out.
+func (s *MALService) Export() ([]mal.Entry, error) {
+	items, err := s.watchlistRepo.GetWatchlist()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.AnilistID)
+	}
+
+	links, err := lookupMediaLinks(ids, s.anilistService.GetMediaLinks)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]domain.MediaLink, len(links))
+	for _, link := range links {
+		byID[link.AnilistID] = link
+	}
+
+	entries := make([]mal.Entry, 0, len(items))
+	skipped := 0
+	for _, item := range items {
+		link, ok := byID[item.AnilistID]
+		if !ok || link.MalID == 0 {
+			skipped++
+			continue
+		}
+		entries = append(entries, malEntry(item, link))
+	}
+
+	if skipped > 0 {
+		log.Printf("MyAnimeList export left out %d anime without a MyAnimeList ID", skipped)
+	}
+	return entries, nil
+}
+
+// lookupMediaLinks calls lookup in batches AniList accepts, pausing between
+// them to stay under the rate limit.
+func lookupMediaLinks(ids []int, lookup func([]int) ([]domain.MediaLink, error)) ([]domain.MediaLink, error) {
+	var links []domain.MediaLink
+	for start := 0; start < len(ids); start += MaxMediaLinksPerRequest {
+		if start > 0 {
+			time.Sleep(refreshDelay)
+		}
+
+		end := min(start+MaxMediaLinksPerRequest, len(ids))
+		batch, err := lookup(ids[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up anime on AniList: %w", err)
+		}
+		links = append(links, batch...)
+	}
+	return links, nil
+}
+
+// watchlistItemFromMAL converts everything but the AniList ID. MAL marks a
+// rewatch with a flag rather than a status.
+func watchlistItemFromMAL(entry mal.Entry) domain.WatchlistItem {
+	status := domain.WatchStatusCurrent
+	switch {
+	case entry.Rewatching:
+		status = domain.WatchStatusRepeating
+	case entry.Status == mal.StatusCompleted:
+		status = domain.WatchStatusCompleted
+	case entry.Status == mal.StatusOnHold:
+		status = domain.WatchStatusPaused
+	case entry.Status == mal.StatusDropped:
+		status = domain.WatchStatusDropped
+	case entry.Status == mal.StatusPlanToWatch:
+		status = domain.WatchStatusPlanning
+	}
+
+	return domain.WatchlistItem{
+		Status:      status,
+		Score:       float64(entry.Score),
+		Progress:    entry.WatchedEpisodes,
+		StartedAt:   entry.StartDate,
+		CompletedAt: entry.FinishDate,
+	}
+}
+
+func malEntry(item domain.WatchlistItem, link domain.MediaLink) mal.Entry {
+	status := mal.StatusWatching
+	switch item.Status {
+	case domain.WatchStatusCompleted, domain.WatchStatusRepeating:
+		status = mal.StatusCompleted
+	case domain.WatchStatusPaused:
+		status = mal.StatusOnHold
+	case domain.WatchStatusDropped:
+		status = mal.StatusDropped
+	case domain.WatchStatusPlanning:
+		status = mal.StatusPlanToWatch
+	}
+
+	return mal.Entry{
+		MalID:           link.MalID,
+		Title:           link.Title,
+		Type:            malType(link.Format),
+		Episodes:        link.Episodes,
+		Status:          status,
+		Score:           int(math.Round(item.Score)),
+		WatchedEpisodes: item.Progress,
+		StartDate:       item.StartedAt,
+		FinishDate:      item.CompletedAt,
+		Rewatching:      item.Status == domain.WatchStatusRepeating,
+	}
+}
+
+// malType converts an AniList format to MAL's series_type.
+func malType(format string) string {
+	switch format {
+	case "TV", "TV_SHORT":
+		return "TV"
+	case "MOVIE":
+		return "Movie"
+	case "SPECIAL":
+		return "Special"
+	case "OVA", "ONA":
+		return format
+	case "MUSIC":
+		return "Music"
+	}
+	return "Unknown"
+}
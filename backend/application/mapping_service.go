@@ -0,0 +1,567 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+// reviewAlternatives is how many runner-up candidates are kept for review.
+const reviewAlternatives = 5
+
+const (
+	ReviewActionAccept = "accept"
+	ReviewActionSelect = "select"
+	ReviewActionReject = "reject"
+)
+
+// autoMapActor is recorded in mapping history for changes made by auto-map.
+const autoMapActor = "auto-map"
+
+// seasonFormats are the AniList formats that make up the seasons of a series.
+var seasonFormats = map[string]bool{"TV": true, "TV_SHORT": true, "ONA": true}
+
+// maxSequelHops bounds how far the SEQUEL chain is followed through movies and
+// OVAs looking for the next season.
+const maxSequelHops = 5
+
+type MappingService struct {
+	plexService        *PlexService
+	anilistService     *AnilistService
+	plexRepo           domain.PlexShowStore
+	candidateRepo      *database.MappingCandidateRepository
+	seasonRepo         *database.SeasonMappingRepository
+	metadataService    *MetadataService
+	autoApplyThreshold float64
+}
+
+func NewMappingService(plexService *PlexService, anilistService *AnilistService, plexRepo domain.PlexShowStore, candidateRepo *database.MappingCandidateRepository, seasonRepo *database.SeasonMappingRepository, metadataService *MetadataService, autoApplyThreshold float64) *MappingService {
+	return &MappingService{
+		plexService:        plexService,
+		anilistService:     anilistService,
+		metadataService:    metadataService,
+		plexRepo:           plexRepo,
+		candidateRepo:      candidateRepo,
+		seasonRepo:         seasonRepo,
+		autoApplyThreshold: autoApplyThreshold,
+	}
+}
+
+// MapShow manually maps a show after checking the AniList ID exists. Manual
+// mappings lock the show against auto-map and sync.
+func (s *MappingService) MapShow(plexID int, anilistID int, actor string) (*domain.PlexShow, error) {
+	if anilistID <= 0 {
+		return nil, domain.ErrInvalidAnilistID
+	}
+
+	show, err := s.plexRepo.GetPlexShowByPlexID(plexID)
+	if err != nil {
+		return nil, err
+	}
+
+	anime, err := s.anilistService.GetAnimeByID(anilistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate anilist ID %d: %w", anilistID, err)
+	}
+
+	if err := s.plexRepo.UpdateShowMapping(plexID, anilistID, domain.MappingSourceManual, actor); err != nil {
+		return nil, err
+	}
+
+	show.AnilistID = &anime.AnilistID
+	show.MappingSource = domain.MappingSourceManual
+	show.MappingLocked = true
+	show.Anime = anime
+	s.refreshSeasonMappings(show)
+	s.writeBack(show)
+	return show, nil
+}
+
+// UnmapShow clears a show's mapping and its season mappings, and removes
+// the AniList label written back to the server.
+func (s *MappingService) UnmapShow(plexID int, actor string) error {
+	if err := s.plexRepo.ClearShowMapping(plexID, actor); err != nil {
+		return err
+	}
+	if err := s.seasonRepo.DeleteSeasonMappings(plexID); err != nil {
+		return err
+	}
+
+	if show, err := s.plexRepo.GetPlexShowByPlexID(plexID); err == nil {
+		s.writeBack(show)
+	}
+	return nil
+}
+
+// UndoMapping restores the mapping before the last change. Season mappings
+// proposed for the undone entry are dropped and proposed again for the
+// restored one.
+func (s *MappingService) UndoMapping(plexID int, actor string) (*domain.MappingHistoryEntry, error) {
+	entry, err := s.plexRepo.UndoLastMapping(plexID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.seasonRepo.DeleteProposedMappings(plexID); err != nil {
+		return nil, fmt.Errorf("failed to clear season mappings: %w", err)
+	}
+	if show, err := s.plexRepo.GetPlexShowByPlexID(plexID); err == nil {
+		if show.AnilistID != nil {
+			s.refreshSeasonMappings(show)
+		}
+		s.writeBack(show)
+	}
+
+	return entry, nil
+}
+
+func (s *MappingService) GetMappingHistory(plexID int) ([]domain.MappingHistoryEntry, error) {
+	if _, err := s.plexRepo.GetPlexShowByPlexID(plexID); err != nil {
+		return nil, err
+	}
+	return s.plexRepo.GetMappingHistory(plexID)
+}
+
+// AutoMapShow ranks AniList candidates for a show. Matches at or above the
+// auto-apply threshold are saved straight away; weaker matches are queued
+// for review instead of being written to the show.
+func (s *MappingService) AutoMapShow(show *domain.PlexShow) (*domain.AutoMapResult, error) {
+	if show.MappingLocked {
+		return nil, domain.ErrMappingLocked
+	}
+	if show.Ignored {
+		return nil, domain.ErrShowIgnored
+	}
+
+	candidates, err := s.plexService.RankAnilistCandidates(show.Title, show.Year, show.EpisodeCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search anilist for %s: %w", show.Title, err)
+	}
+
+	result := &domain.AutoMapResult{
+		Show:       show,
+		Outcome:    domain.AutoMapOutcomeNoMatch,
+		Candidates: candidates,
+	}
+
+	if len(candidates) == 0 || candidates[0].Score < minConfidence {
+		return result, nil
+	}
+
+	top := candidates[0]
+	candidate := &domain.MappingCandidate{
+		PlexID:       show.PlexID,
+		AnilistID:    top.Anime.AnilistID,
+		Score:        top.Score,
+		Strategy:     top.Strategy,
+		Alternatives: candidates[1:min(len(candidates), reviewAlternatives+1)],
+		Status:       domain.CandidateStatusPending,
+	}
+
+	if top.Score >= s.autoApplyThreshold {
+		if err := s.plexRepo.UpdateShowMapping(show.PlexID, top.Anime.AnilistID, domain.MappingSourceAuto, autoMapActor); err != nil {
+			return nil, fmt.Errorf("failed to save mapping: %w", err)
+		}
+		anime := top.Anime
+		show.AnilistID = &anime.AnilistID
+		show.MappingSource = domain.MappingSourceAuto
+		show.Anime = &anime
+		candidate.Status = domain.CandidateStatusAccepted
+		result.Outcome = domain.AutoMapOutcomeMapped
+		s.refreshSeasonMappings(show)
+		s.writeBack(show)
+	} else {
+		result.Outcome = domain.AutoMapOutcomeQueued
+	}
+
+	if err := s.candidateRepo.UpsertCandidate(candidate); err != nil {
+		return nil, fmt.Errorf("failed to save mapping candidate: %w", err)
+	}
+
+	return result, nil
+}
+
+// RejectedPlexIDs returns the shows whose candidate was rejected in review,
+// which bulk auto-map leaves alone.
+func (s *MappingService) RejectedPlexIDs() (map[int]bool, error) {
+	rejected, err := s.candidateRepo.GetCandidatesByStatus(domain.CandidateStatusRejected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rejected candidates: %w", err)
+	}
+
+	ids := make(map[int]bool, len(rejected))
+	for _, candidate := range rejected {
+		ids[candidate.PlexID] = true
+	}
+
+	return ids, nil
+}
+
+func (s *MappingService) GetReviewQueue() ([]domain.MappingCandidate, error) {
+	candidates, err := s.candidateRepo.GetCandidatesByStatus(domain.CandidateStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review queue: %w", err)
+	}
+
+	queue := make([]domain.MappingCandidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		show, err := s.plexRepo.GetPlexShowByPlexID(candidate.PlexID)
+		if err != nil {
+			continue // Show was removed from Plex since it was queued
+		}
+		if show.Ignored || show.MappingLocked {
+			continue
+		}
+		candidate.Show = show
+		queue = append(queue, candidate)
+	}
+
+	return queue, nil
+}
+
+// ResolveReview accepts the top candidate, selects a different AniList ID, or
+// rejects the candidate so bulk auto-map leaves the show alone. Accepted
+// mappings count as manual and lock the show. Only pending candidates can be
+// resolved.
+func (s *MappingService) ResolveReview(plexID int, action string, anilistID int, actor string) (*domain.MappingCandidate, error) {
+	candidate, err := s.candidateRepo.GetCandidate(plexID)
+	if err != nil {
+		return nil, err
+	}
+	if candidate.Status != domain.CandidateStatusPending {
+		return nil, domain.ErrCandidateResolved
+	}
+
+	switch action {
+	case ReviewActionAccept:
+		anilistID = candidate.AnilistID
+	case ReviewActionSelect:
+		if anilistID <= 0 {
+			return nil, domain.ErrInvalidAnilistID
+		}
+		if _, err := s.anilistService.GetAnimeByID(anilistID); err != nil {
+			return nil, fmt.Errorf("failed to validate anilist ID %d: %w", anilistID, err)
+		}
+	case ReviewActionReject:
+		if err := s.candidateRepo.UpdateCandidateStatus(plexID, domain.CandidateStatusRejected); err != nil {
+			return nil, err
+		}
+		candidate.Status = domain.CandidateStatusRejected
+		return candidate, nil
+	default:
+		return nil, &domain.ValidationError{Field: "action", Message: "action must be accept, select or reject"}
+	}
+
+	if err := s.plexRepo.UpdateShowMapping(plexID, anilistID, domain.MappingSourceManual, actor); err != nil {
+		return nil, fmt.Errorf("failed to save mapping: %w", err)
+	}
+
+	candidate.AnilistID = anilistID
+	candidate.Status = domain.CandidateStatusAccepted
+	if err := s.candidateRepo.UpsertCandidate(candidate); err != nil {
+		return nil, fmt.Errorf("failed to save mapping candidate: %w", err)
+	}
+
+	if show, err := s.plexRepo.GetPlexShowByPlexID(plexID); err == nil {
+		s.refreshSeasonMappings(show)
+		s.writeBack(show)
+	}
+
+	return candidate, nil
+}
+
+func (s *MappingService) GetSeasonMappings(plexID int) ([]domain.SeasonMapping, error) {
+	if _, err := s.plexRepo.GetPlexShowByPlexID(plexID); err != nil {
+		return nil, err
+	}
+	return s.seasonRepo.GetSeasonMappings(plexID)
+}
+
+// SetSeasonMapping manually maps a run of episodes in a Plex season to an
+// AniList entry. Manual season mappings survive later proposals.
+func (s *MappingService) SetSeasonMapping(mapping *domain.SeasonMapping) error {
+	if mapping.AnilistID <= 0 {
+		return domain.ErrInvalidAnilistID
+	}
+	if mapping.SeasonNumber < 0 || mapping.EpisodeOffset < 0 || mapping.EpisodeCount < 0 {
+		return &domain.ValidationError{Field: "season_number", Message: "season, offset and episode count must not be negative"}
+	}
+
+	if _, err := s.plexRepo.GetPlexShowByPlexID(mapping.PlexID); err != nil {
+		return err
+	}
+
+	anime, err := s.anilistService.GetAnimeByID(mapping.AnilistID)
+	if err != nil {
+		return fmt.Errorf("failed to validate anilist ID %d: %w", mapping.AnilistID, err)
+	}
+
+	mapping.AnilistEpisodes = anime.Episodes
+	mapping.Source = domain.MappingSourceManual
+	return s.seasonRepo.SetSeasonMapping(mapping)
+}
+
+// ProposeSeasonMappings walks the AniList SEQUEL chain from the show's mapped
+// entry and lines the entries up with the Plex seasons by episode count. A
+// Plex season longer than one entry absorbs the following entries (split
+// cours); an entry that does not fit in what is left of a season starts the
+// next one.
+func (s *MappingService) ProposeSeasonMappings(show *domain.PlexShow) ([]domain.SeasonMapping, error) {
+	if show.AnilistID == nil {
+		return nil, domain.ErrShowNotMapped
+	}
+
+	seasons, err := s.plexService.FetchSeasons(show)
+	if err != nil {
+		return nil, err
+	}
+
+	s.plexService.throttle()
+	first, err := s.anilistService.GetAnimeByID(*show.AnilistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anilist entry %d: %w", *show.AnilistID, err)
+	}
+
+	chain := newSequelChain(s.anilistService, *first, s.plexService.throttle)
+	mappings := []domain.SeasonMapping{}
+	next := 0
+
+	for _, season := range seasons {
+		if season.SeasonNumber == 0 {
+			continue // Specials rarely line up with a single AniList entry
+		}
+
+		offset := 0
+		for offset < season.EpisodeCount {
+			entry, err := chain.get(next)
+			if err != nil {
+				return nil, err
+			}
+			if entry == nil {
+				break
+			}
+
+			remaining := season.EpisodeCount - offset
+			if offset > 0 && entry.Episodes > remaining {
+				break // Belongs to the next season
+			}
+
+			covered := entry.Episodes
+			if covered == 0 || covered > remaining {
+				covered = remaining // Still airing, or episodes missing on Plex
+			}
+
+			mappings = append(mappings, domain.SeasonMapping{
+				PlexID:          show.PlexID,
+				SeasonNumber:    season.SeasonNumber,
+				AnilistID:       entry.AnilistID,
+				EpisodeOffset:   offset,
+				EpisodeCount:    covered,
+				AnilistEpisodes: entry.Episodes,
+				Source:          domain.MappingSourceAuto,
+				Anime:           entry,
+			})
+
+			next++
+			offset += covered
+		}
+	}
+
+	if err := s.seasonRepo.ReplaceProposedMappings(show.PlexID, mappings); err != nil {
+		return nil, fmt.Errorf("failed to save season mappings: %w", err)
+	}
+
+	return mappings, nil
+}
+
+// refreshSeasonMappings proposes season mappings after a show is mapped. It
+// only logs failures since the show-level mapping has already been saved.
+func (s *MappingService) refreshSeasonMappings(show *domain.PlexShow) {
+	if _, err := s.ProposeSeasonMappings(show); err != nil {
+		log.Printf("Failed to propose season mappings for %s: %v", show.Title, err)
+	}
+}
+
+// FindOnServer reports where an AniList entry is on the server, preferring a
+// season mapping over the show-level mapping.
+func (s *MappingService) FindOnServer(anilistID int) (*domain.ServerAvailability, error) {
+	seasons, err := s.seasonRepo.GetSeasonMappingsByAnilistID(anilistID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(seasons) > 0 {
+		show, err := s.plexRepo.GetPlexShowByPlexID(seasons[0].PlexID)
+		if err == nil && !show.Ignored {
+			return &domain.ServerAvailability{OnServer: true, Show: show, Seasons: seasons}, nil
+		}
+	}
+
+	shows, err := s.plexRepo.GetAllPlexShows()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range shows {
+		if shows[i].AnilistID != nil && *shows[i].AnilistID == anilistID && !shows[i].Ignored {
+			return &domain.ServerAvailability{OnServer: true, Show: &shows[i], Seasons: []domain.SeasonMapping{}}, nil
+		}
+	}
+
+	return &domain.ServerAvailability{OnServer: false, Seasons: []domain.SeasonMapping{}}, nil
+}
+
+// WriteBackMappings writes AniList IDs into the media server metadata of one
+// show, or of every mapped show when plexID is zero. Failures are reported
+// per show rather than stopping the run.
+func (s *MappingService) WriteBackMappings(plexID int, dryRun bool) ([]domain.WritebackResult, error) {
+	var shows []domain.PlexShow
+	if plexID != 0 {
+		show, err := s.plexRepo.GetPlexShowByPlexID(plexID)
+		if err != nil {
+			return nil, err
+		}
+		if show.AnilistID == nil {
+			return nil, domain.ErrShowNotMapped
+		}
+		shows = []domain.PlexShow{*show}
+	} else {
+		all, err := s.plexRepo.GetAllPlexShows()
+		if err != nil {
+			return nil, err
+		}
+		for _, show := range all {
+			if show.AnilistID != nil && !show.Ignored && show.Source == s.plexService.ServerType() {
+				shows = append(shows, show)
+			}
+		}
+	}
+
+	results := make([]domain.WritebackResult, 0, len(shows))
+	for i := range shows {
+		show := &shows[i]
+		result, err := s.writeBackShow(show, dryRun)
+		if errors.Is(err, domain.ErrWritebackUnsupported) || errors.Is(err, domain.ErrWritebackDisabled) {
+			return nil, err
+		}
+		if err != nil {
+			results = append(results, domain.WritebackResult{
+				PlexID:    show.PlexID,
+				Title:     show.Title,
+				AnilistID: *show.AnilistID,
+				Error:     err.Error(),
+			})
+			continue
+		}
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// writeBack updates the media server after a mapping change when writeback
+// is enabled, removing the AniList label from a show that is no longer
+// mapped. Failures are logged; the mapping itself is already saved.
+func (s *MappingService) writeBack(show *domain.PlexShow) {
+	if !s.plexService.WritebackEnabled() {
+		return
+	}
+
+	s.plexService.throttle()
+	if show.AnilistID == nil {
+		if err := s.plexService.ClearWriteback(show); err != nil {
+			log.Printf("Failed to clear written back mapping for %s: %v", show.Title, err)
+		}
+		return
+	}
+
+	if _, err := s.writeBackShow(show, false); err != nil {
+		log.Printf("Failed to write back mapping for %s: %v", show.Title, err)
+	}
+}
+
+func (s *MappingService) writeBackShow(show *domain.PlexShow, dryRun bool) (*domain.WritebackResult, error) {
+	var anime *domain.Anime
+	if s.plexService.WritebackNeedsAnime() {
+		var err error
+		anime, err = s.metadataService.GetAnime(*show.AnilistID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get anime %d: %w", *show.AnilistID, err)
+		}
+	}
+
+	return s.plexService.WriteBackShow(show, anime, dryRun)
+}
+
+// sequelChain lazily follows AniList SEQUEL relations, fetching the next
+// season only when the proposal needs it. wait is called before each
+// request to stay under the rate limit.
+type sequelChain struct {
+	anilist *AnilistService
+	wait    func()
+	entries []domain.Anime
+	cursor  int
+	seen    map[int]bool
+	done    bool
+}
+
+func newSequelChain(anilist *AnilistService, first domain.Anime, wait func()) *sequelChain {
+	return &sequelChain{
+		anilist: anilist,
+		wait:    wait,
+		entries: []domain.Anime{first},
+		cursor:  first.AnilistID,
+		seen:    map[int]bool{first.AnilistID: true},
+	}
+}
+
+func (c *sequelChain) get(i int) (*domain.Anime, error) {
+	for len(c.entries) <= i && !c.done {
+		if err := c.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if i >= len(c.entries) {
+		return nil, nil
+	}
+	return &c.entries[i], nil
+}
+
+func (c *sequelChain) advance() error {
+	for hop := 0; hop < maxSequelHops; hop++ {
+		c.wait()
+		sequels, err := c.anilist.GetSequels(c.cursor)
+		if err != nil {
+			return fmt.Errorf("failed to get sequels of %d: %w", c.cursor, err)
+		}
+		if len(sequels) == 0 {
+			break
+		}
+
+		next := sequels[0]
+		for _, sequel := range sequels {
+			if seasonFormats[sequel.Format] {
+				next = sequel
+				break
+			}
+		}
+
+		if c.seen[next.AnilistID] {
+			break
+		}
+		c.seen[next.AnilistID] = true
+		c.cursor = next.AnilistID
+
+		if seasonFormats[next.Format] {
+			c.entries = append(c.entries, next)
+			return nil
+		}
+	}
+
+	c.done = true
+	return nil
+}
@@ -0,0 +1,62 @@
+package application
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+	"anime-watchlist/backend/infrastructure/mediaserver"
+)
+
+// labelServer is a media server whose shows only carry labels.
+type labelServer struct {
+	mediaserver.MediaServer
+	labels map[string][]string
+}
+
+func (s *labelServer) GetItemMetadata(itemID string) (*domain.ItemMetadata, error) {
+	return &domain.ItemMetadata{Labels: s.labels[itemID]}, nil
+}
+
+func (s *labelServer) UpdateItemMetadata(libraryID string, itemID string, update domain.MetadataUpdate) error {
+	var kept []string
+	for _, label := range s.labels[itemID] {
+		removed := false
+		for _, remove := range update.RemoveLabels {
+			removed = removed || strings.EqualFold(label, remove)
+		}
+		if !removed {
+			kept = append(kept, label)
+		}
+	}
+	s.labels[itemID] = append(kept, update.AddLabels...)
+	return nil
+}
+
+func TestUnmapShowClearsWrittenBackLabel(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	server := &labelServer{labels: map[string][]string{"5": {"favourite", "anilist-21"}}}
+	plexService := NewPlexService(domain.PlexConfig{LibraryID: "1", WritebackEnabled: true}, server, "", nil)
+	plexRepo := database.NewPlexRepository(db.DB)
+	service := NewMappingService(plexService, nil, plexRepo, database.NewMappingCandidateRepository(db.DB), database.NewSeasonMappingRepository(db.DB), nil, 0)
+
+	anilistID := 21
+	show := &domain.PlexShow{PlexID: 5, Source: "plex", ExternalID: "5", Title: "Show", AnilistID: &anilistID}
+	if err := plexRepo.UpsertPlexShow(show); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.UnmapShow(5, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(server.labels["5"], ","); got != "favourite" {
+		t.Errorf("labels after unmap = %s, want favourite", got)
+	}
+}
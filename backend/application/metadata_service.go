@@ -0,0 +1,143 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+const (
+	// metadataTTL is how long cached AniList metadata is served before it is
+	// fetched again on demand.
+	metadataTTL = 7 * 24 * time.Hour
+
+	// refreshDelay spaces out requests during a refresh to stay well under
+	// the AniList rate limit of 90 requests per minute.
+	refreshDelay = time.Second
+)
+
+// MetadataService serves AniList metadata from the local cache and keeps it
+// fresh.
+type MetadataService struct {
+	anilistService *AnilistService
+	cacheRepo      *database.AnimeCacheRepository
+	watchlistRepo  domain.WatchlistStore
+	plexRepo       domain.PlexShowStore
+	seasonRepo     *database.SeasonMappingRepository
+}
+
+func NewMetadataService(anilistService *AnilistService, cacheRepo *database.AnimeCacheRepository, watchlistRepo domain.WatchlistStore, plexRepo domain.PlexShowStore, seasonRepo *database.SeasonMappingRepository) *MetadataService {
+	return &MetadataService{
+		anilistService: anilistService,
+		cacheRepo:      cacheRepo,
+		watchlistRepo:  watchlistRepo,
+		plexRepo:       plexRepo,
+		seasonRepo:     seasonRepo,
+	}
+}
+
+// GetAnime returns cached metadata when it is fresh, otherwise fetches it from
+// AniList. A stale entry is still served if AniList cannot be reached.
+func (s *MetadataService) GetAnime(anilistID int) (*domain.Anime, error) {
+	cached, fetchedAt, err := s.cacheRepo.GetCachedAnime(anilistID)
+	if err != nil {
+		log.Printf("Failed to read cached anime %d: %v", anilistID, err)
+	}
+	if cached != nil && time.Since(fetchedAt) < metadataTTL {
+		return cached, nil
+	}
+
+	anime, err := s.fetch(anilistID)
+	if err != nil {
+		if cached != nil && !errors.Is(err, domain.ErrAnimeNotFound) {
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	return anime, nil
+}
+
+// Refresh re-fetches metadata for everything on the watchlist or mapped on
+// the server. It stops early if AniList rate limits us or ctx is cancelled.
+func (s *MetadataService) Refresh(ctx context.Context) (int, error) {
+	ids, err := s.trackedIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for i, id := range ids {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return refreshed, ctx.Err()
+			case <-time.After(refreshDelay):
+			}
+		}
+
+		if _, err := s.fetch(id); err != nil {
+			if errors.Is(err, domain.ErrRateLimited) {
+				return refreshed, err
+			}
+			log.Printf("Failed to refresh metadata for anime %d: %v", id, err)
+			continue
+		}
+		refreshed++
+	}
+
+	return refreshed, nil
+}
+
+func (s *MetadataService) fetch(anilistID int) (*domain.Anime, error) {
+	anime, err := s.anilistService.GetAnimeByID(anilistID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cacheRepo.SaveAnime(anime); err != nil {
+		log.Printf("Failed to cache anime %d: %v", anilistID, err)
+	}
+
+	return anime, nil
+}
+
+func (s *MetadataService) trackedIDs() ([]int, error) {
+	items, err := s.watchlistRepo.GetWatchlist()
+	if err != nil {
+		return nil, err
+	}
+
+	mapped, err := s.plexRepo.GetMappedAnilistIDs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mapped anime: %w", err)
+	}
+
+	seasons, err := s.seasonRepo.GetMappedAnilistIDs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get season mapped anime: %w", err)
+	}
+	mapped = append(mapped, seasons...)
+
+	seen := make(map[int]bool)
+	var ids []int
+	for _, item := range items {
+		if !seen[item.AnilistID] {
+			seen[item.AnilistID] = true
+			ids = append(ids, item.AnilistID)
+		}
+	}
+	for _, id := range mapped {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
@@ -3,130 +3,265 @@ package application
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/mediaserver"
 )
 
 type PlexService struct {
 	config domain.PlexConfig
+	server mediaserver.MediaServer
+	anilistURL  string
+	events      *EventBus
+	mu          sync.Mutex
 	lastRequest time.Time
 }
 
-type PlexShowResponse struct {
-	MediaContainer struct {
-		Metadata []PlexShowMetadata `json:"Metadata"`
-	} `json:"MediaContainer"`
-}
-
-type PlexShowMetadata struct {
-	RatingKey string `json:"ratingKey"`
-	Title     string `json:"title"`
-	Year      int    `json:"year"`
-	ChildCount int   `json:"childCount"`
-}
-
-func NewPlexService(config domain.PlexConfig) *PlexService {
+func NewPlexService(config domain.PlexConfig, server mediaserver.MediaServer, anilistURL string, events *EventBus) *PlexService {
 	return &PlexService{
 		config: config,
+		server: server,
+		anilistURL: anilistURL,
+		events: events,
 		lastRequest: time.Now().Add(-time.Second), // Allow immediate first request
 	}
 }
 
+// ServerType names the media server backend, such as plex or jellyfin.
+func (s *PlexService) ServerType() string {
+	return s.server.Type()
+}
+
 func (s *PlexService) FetchShowsFromPlex() ([]domain.PlexShow, error) {
 	if !s.config.SyncEnabled {
 		return nil, fmt.Errorf("plex sync is disabled")
 	}
 
-	plexURL := fmt.Sprintf("%s/library/sections/%d/all", s.config.ServerURL, s.config.LibraryID)
-	
-	req, err := http.NewRequest("GET", plexURL, nil)
+	s.events.Publish(domain.EventSyncProgress, domain.SyncProgress{Phase: "fetching"})
+
+	shows, err := s.server.ListShows(s.config.LibraryID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to fetch shows from %s: %w", s.server.Type(), err)
 	}
 
-	req.Header.Set("X-Plex-Token", s.config.Token)
-	req.Header.Set("Accept", "application/json")
+	s.events.Publish(domain.EventSyncProgress, domain.SyncProgress{Phase: "fetched", Total: len(shows)})
+	return shows, nil
+}
+
+// SupportsIncrementalSync reports whether the server can list only changed
+// shows.
+func (s *PlexService) SupportsIncrementalSync() bool {
+	_, ok := s.server.(mediaserver.IncrementalLister)
+	return ok
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+// LibraryID is the library that syncs read from.
+func (s *PlexService) LibraryID() string {
+	return s.config.LibraryID
+}
+
+// FetchShowsChangedSince lists the shows added or updated since the given
+// time. Callers check SupportsIncrementalSync first.
+func (s *PlexService) FetchShowsChangedSince(since time.Time) ([]domain.PlexShow, error) {
+	if !s.config.SyncEnabled {
+		return nil, fmt.Errorf("plex sync is disabled")
+	}
+
+	lister, ok := s.server.(mediaserver.IncrementalLister)
+	if !ok {
+		return nil, fmt.Errorf("%s does not support incremental sync", s.server.Type())
+	}
+
+	s.events.Publish(domain.EventSyncProgress, domain.SyncProgress{Phase: "fetching"})
+
+	shows, err := lister.ListShowsChangedSince(s.config.LibraryID, since)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch from plex: %w", err)
+		return nil, fmt.Errorf("failed to fetch changed shows from %s: %w", s.server.Type(), err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("plex API returned status: %d", resp.StatusCode)
+	s.events.Publish(domain.EventSyncProgress, domain.SyncProgress{Phase: "fetched", Total: len(shows)})
+	return shows, nil
+}
+
+// FetchSeasons lists the seasons of a show with their episode counts.
+func (s *PlexService) FetchSeasons(show *domain.PlexShow) ([]domain.PlexSeason, error) {
+	seasons, err := s.server.ListSeasons(serverItemID(show))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch seasons from %s: %w", s.server.Type(), err)
 	}
 
-	var plexResp PlexShowResponse
-	if err := json.NewDecoder(resp.Body).Decode(&plexResp); err != nil {
-		return nil, fmt.Errorf("failed to decode plex response: %w", err)
+	for i := range seasons {
+		seasons[i].ShowPlexID = show.PlexID
 	}
+	return seasons, nil
+}
 
-	var shows []domain.PlexShow
-	for _, metadata := range plexResp.MediaContainer.Metadata {
-		plexID, _ := strconv.Atoi(metadata.RatingKey)
-		shows = append(shows, domain.PlexShow{
-			PlexID:       plexID,
-			Title:        metadata.Title,
-			Year:         metadata.Year,
-			EpisodeCount: metadata.ChildCount,
-			LastUpdated:  time.Now(),
-		})
+func (s *PlexService) FetchLibraries() ([]domain.MediaLibrary, error) {
+	return s.server.ListLibraries()
+}
+
+func (s *PlexService) FetchEpisodes(show *domain.PlexShow) ([]domain.MediaEpisode, error) {
+	return s.server.ListEpisodes(serverItemID(show))
+}
+
+func (s *PlexService) FetchWatchState(show *domain.PlexShow) (*domain.WatchState, error) {
+	return s.server.GetWatchState(serverItemID(show))
+}
+
+// SupportsQuality reports whether the server can describe episode files.
+func (s *PlexService) SupportsQuality() bool {
+	_, ok := s.server.(mediaserver.QualityReader)
+	return ok
+}
+
+// FetchShowQuality summarizes the resolution, codecs and languages of a
+// show's episode files.
+func (s *PlexService) FetchShowQuality(show *domain.PlexShow) (*domain.ShowQuality, error) {
+	reader, ok := s.server.(mediaserver.QualityReader)
+	if !ok {
+		return nil, fmt.Errorf("%s does not report media quality", s.server.Type())
 	}
 
-	return shows, nil
+	quality, err := reader.GetShowQuality(serverItemID(show))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch quality of %s from %s: %w", show.Title, s.server.Type(), err)
+	}
+
+	quality.PlexID = show.PlexID
+	return quality, nil
+}
+
+// serverItemID returns the media server's ID for a show. Shows synced from
+// Plex before external IDs were stored use their rating key as plex_id.
+func serverItemID(show *domain.PlexShow) string {
+	if show.ExternalID != "" {
+		return show.ExternalID
+	}
+	return strconv.Itoa(show.PlexID)
+}
+
+type searchStrategy struct {
+	name       string
+	searchTerm string
+	useYear    bool
+	weight     float64
 }
 
+// minConfidence is the lowest score at which a candidate is considered a match.
+const minConfidence = 0.5
+
 func (s *PlexService) SearchAnilistForShow(title string, year int) (*domain.Anime, error) {
-	searchStrategies := []struct {
-		searchTerm string
-		useYear    bool
-		weight     float64
-	}{
-		{title, true, 1.0},                    // Exact title + year (highest confidence)
-		{title, false, 0.8},                   // Exact title without year
-		{s.cleanTitle(title), true, 0.9},      // Cleaned title + year
-		{s.cleanTitle(title), false, 0.7},     // Cleaned title without year
-		{s.extractMainTitle(title), true, 0.6}, // Main title + year
-		{s.extractMainTitle(title), false, 0.5}, // Main title without year
+	candidates, err := s.RankAnilistCandidates(title, year, 0)
+	if err != nil {
+		return nil, err
 	}
 
-	var bestMatch *domain.Anime
-	var bestConfidence float64
-	const minConfidence = 0.5
+	if len(candidates) == 0 || candidates[0].Score < minConfidence {
+		return nil, nil
+	}
+
+	return &candidates[0].Anime, nil
+}
 
-	for _, strategy := range searchStrategies {
-		anime, err := s.searchAnilistWithStrategy(strategy.searchTerm, strategy.useYear, year)
+// RankAnilistCandidates runs every search strategy against AniList, scores all
+// returned media against the Plex title and returns them best first. Identical
+// search terms are only queried once.
+func (s *PlexService) RankAnilistCandidates(title string, year int, episodeCount int) ([]domain.AnilistMatch, error) {
+	strategies := []searchStrategy{
+		{"exact_year", title, true, 1.0},                    // Exact title + year (highest confidence)
+		{"exact", title, false, 0.8},                        // Exact title without year
+		{"clean_year", s.cleanTitle(title), true, 0.9},      // Cleaned title + year
+		{"clean", s.cleanTitle(title), false, 0.7},          // Cleaned title without year
+		{"main_year", s.extractMainTitle(title), true, 0.6}, // Main title + year
+		{"main", s.extractMainTitle(title), false, 0.5},     // Main title without year
+	}
+
+	queried := make(map[string]bool)
+	matches := make(map[int]domain.AnilistMatch)
+	var lastErr error
+	succeeded := 0
+
+	for _, strategy := range strategies {
+		searchTerm := strings.TrimSpace(strategy.searchTerm)
+		if searchTerm == "" || (strategy.useYear && year == 0) {
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%t", strings.ToLower(searchTerm), strategy.useYear)
+		if queried[key] {
+			continue
+		}
+		queried[key] = true
+
+		results, err := s.searchAnilistWithStrategy(searchTerm, strategy.useYear, year)
 		if err != nil {
+			if errors.Is(err, domain.ErrRateLimited) {
+				return nil, err
+			}
+			lastErr = err
 			continue
 		}
+		succeeded++
 
-		if anime != nil {
-			confidence := s.calculateConfidence(title, year, anime, strategy.weight)
-			if confidence > bestConfidence && confidence >= minConfidence {
-				bestMatch = anime
-				bestConfidence = confidence
+		for i := range results {
+			score := s.calculateConfidence(title, year, episodeCount, &results[i], strategy.weight)
+			if existing, ok := matches[results[i].AnilistID]; ok && existing.Score >= score {
+				continue
+			}
+			matches[results[i].AnilistID] = domain.AnilistMatch{
+				Anime:    results[i],
+				Score:    score,
+				Strategy: strategy.name,
 			}
 		}
 	}
 
-	return bestMatch, nil
+	if succeeded == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	ranked := make([]domain.AnilistMatch, 0, len(matches))
+	for _, match := range matches {
+		ranked = append(ranked, match)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Anime.Popularity > ranked[j].Anime.Popularity
+	})
+
+	return ranked, nil
 }
 
-func (s *PlexService) searchAnilistWithStrategy(searchTerm string, useYear bool, year int) (*domain.Anime, error) {
-	// Rate limiting: wait at least 1 second between requests
+// throttle waits until at least a second has passed since the last request
+// made through the rate limiter. Mapping follow-ups share it with searches so
+// bulk auto-mapping stays under the AniList and server rate limits.
+func (s *PlexService) throttle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	timeSinceLast := time.Since(s.lastRequest)
 	if timeSinceLast < time.Second {
 		time.Sleep(time.Second - timeSinceLast)
 	}
 	s.lastRequest = time.Now()
+}
+
+func (s *PlexService) searchAnilistWithStrategy(searchTerm string, useYear bool, year int) ([]domain.Anime, error) {
+	// Rate limiting: wait at least 1 second between requests
+	s.throttle()
 
 	var query string
 	var variables map[string]interface{}
@@ -139,7 +274,9 @@ func (s *PlexService) searchAnilistWithStrategy(searchTerm string, useYear bool,
 					title {
 						romaji
 						english
+						native
 					}
+					synonyms
 					description
 					coverImage {
 						large
@@ -169,7 +306,9 @@ func (s *PlexService) searchAnilistWithStrategy(searchTerm string, useYear bool,
 					title {
 						romaji
 						english
+						native
 					}
+					synonyms
 					description
 					coverImage {
 						large
@@ -205,7 +344,7 @@ func (s *PlexService) searchAnilistWithStrategy(searchTerm string, useYear bool,
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", "https://graphql.anilist.co", bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequest("POST", s.anilistURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create anilist request: %w", err)
 	}
@@ -222,7 +361,7 @@ func (s *PlexService) searchAnilistWithStrategy(searchTerm string, useYear bool,
 
 	// Check for rate limiting
 	if resp.StatusCode == 429 {
-		return nil, fmt.Errorf("rate limited by anilist API")
+		return nil, domain.ErrRateLimited
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -235,12 +374,12 @@ func (s *PlexService) searchAnilistWithStrategy(searchTerm string, useYear bool,
 		return nil, fmt.Errorf("failed to decode anilist response: %w", err)
 	}
 
-	if len(anilistResp.Data.Page.Media) == 0 {
-		return nil, nil
+	results := make([]domain.Anime, 0, len(anilistResp.Data.Page.Media))
+	for _, media := range anilistResp.Data.Page.Media {
+		results = append(results, media.ToDomain())
 	}
 
-	anime := anilistResp.Data.Page.Media[0].ToDomain()
-	return &anime, nil
+	return results, nil
 }
 
 func (s *PlexService) cleanTitle(title string) string {
@@ -287,52 +426,59 @@ func (s *PlexService) extractMainTitle(title string) string {
 	return title
 }
 
-func (s *PlexService) calculateConfidence(plexTitle string, plexYear int, anime *domain.Anime, baseWeight float64) float64 {
-	confidence := baseWeight
-	
-	// Title similarity
-	plexClean := strings.ToLower(s.cleanTitle(plexTitle))
-	animeEnglish := strings.ToLower(anime.TitleEnglish)
-	animeRomaji := strings.ToLower(anime.TitleRomaji)
-	
-	// Check exact matches
-	if plexClean == animeEnglish || plexClean == animeRomaji {
-		confidence += 0.3
-	}
-	
-	// Check if one contains the other
-	if strings.Contains(animeEnglish, plexClean) || strings.Contains(animeRomaji, plexClean) ||
-	   strings.Contains(plexClean, animeEnglish) || strings.Contains(plexClean, animeRomaji) {
-		confidence += 0.2
+func (s *PlexService) calculateConfidence(plexTitle string, plexYear int, plexEpisodes int, anime *domain.Anime, baseWeight float64) float64 {
+	// Title similarity against every known title of the candidate
+	plexTitles := []string{plexTitle, s.cleanTitle(plexTitle)}
+	animeTitles := append([]string{anime.TitleRomaji, anime.TitleEnglish, anime.TitleNative}, anime.Synonyms...)
+
+	titleScore := 0.0
+	for _, pt := range plexTitles {
+		for _, at := range animeTitles {
+			if sim := titleSimilarity(pt, at); sim > titleScore {
+				titleScore = sim
+			}
+		}
 	}
-	
+
 	// Year matching
-	if anime.SeasonYear == plexYear {
-		confidence += 0.2
-	} else if anime.SeasonYear == plexYear+1 || anime.SeasonYear == plexYear-1 {
-		confidence += 0.1
+	yearScore := 0.0
+	switch {
+	case plexYear == 0 || anime.SeasonYear == 0:
+		yearScore = 0.5
+	case anime.SeasonYear == plexYear:
+		yearScore = 1.0
+	case anime.SeasonYear == plexYear+1 || anime.SeasonYear == plexYear-1:
+		yearScore = 0.5
 	}
-	
-	// Format matching (TV series vs Movie)
-	if strings.Contains(strings.ToLower(plexTitle), "movie") && anime.Format == "MOVIE" {
-		confidence += 0.1
-	} else if !strings.Contains(strings.ToLower(plexTitle), "movie") && anime.Format == "TV" {
-		confidence += 0.1
+
+	confidence := titleScore*0.65 + yearScore*0.2 + baseWeight*0.15
+
+	// Format mismatch (TV series vs Movie)
+	isMovie := strings.Contains(strings.ToLower(plexTitle), "movie")
+	if isMovie && anime.Format != "" && anime.Format != "MOVIE" {
+		confidence -= 0.15
+	} else if !isMovie && anime.Format == "MOVIE" {
+		confidence -= 0.15
+	}
+
+	// Episode count mismatch. Plex having more episodes is expected when one
+	// show holds several AniList seasons, so only penalize having fewer.
+	if plexEpisodes > 0 && anime.Episodes > 0 && plexEpisodes < anime.Episodes {
+		ratio := float64(plexEpisodes) / float64(anime.Episodes)
+		confidence -= 0.15 * (1 - ratio)
+	}
+
+	if confidence < 0 {
+		confidence = 0
 	}
-	
-	// Cap confidence at 1.0
 	if confidence > 1.0 {
 		confidence = 1.0
 	}
-	
+
 	return confidence
 }
 
-func (s *PlexService) GetServerStatus(repo interface {
-	GetShowsOnServer() (int, error)
-	GetMappedShowsCount() (int, error)
-	GetUnmappedShowsCount() (int, error)
-}) (*domain.ServerStatus, error) {
+func (s *PlexService) GetServerStatus(repo domain.PlexShowStore) (*domain.ServerStatus, error) {
 	totalShows, err := repo.GetShowsOnServer()
 	if err != nil {
 		return nil, err
@@ -348,10 +494,17 @@ func (s *PlexService) GetServerStatus(repo interface {
 		return nil, err
 	}
 
+	ignoredShows, err := repo.GetIgnoredShowsCount()
+	if err != nil {
+		return nil, err
+	}
+
 	status := &domain.ServerStatus{
+		ServerType:       s.server.Type(),
 		ShowsOnServer:    totalShows,
 		MappedToAnilist:  mappedShows,
 		UnmappedShows:    unmappedShows,
+		IgnoredShows:     ignoredShows,
 		WatchlistShows:   0, // This would need to be calculated separately
 		MissingFromServer: 0, // This would need to be calculated separately
 	}
@@ -359,45 +512,138 @@ func (s *PlexService) GetServerStatus(repo interface {
 	return status, nil
 }
 
-func (s *PlexService) MapShowToAnilist(plexShow *domain.PlexShow) error {
-	anime, err := s.SearchAnilistForShow(plexShow.Title, plexShow.Year)
+// anilistLabelPattern matches labels written back for AniList mappings.
+var anilistLabelPattern = regexp.MustCompile(`(?i)^anilist-\d+$`)
+
+func (s *PlexService) WritebackEnabled() bool {
+	return s.config.WritebackEnabled
+}
+
+// WritebackNeedsAnime reports whether writeback uses AniList metadata beyond
+// the ID, so callers only fetch it when needed.
+func (s *PlexService) WritebackNeedsAnime() bool {
+	return s.config.WritebackGenres || s.config.WritebackRating
+}
+
+// WriteBackShow labels a mapped show with its AniList ID, replacing labels
+// from an earlier mapping, and sets genres and rating from anime when those
+// are enabled. A dry run reports the changes without making them.
+func (s *PlexService) WriteBackShow(show *domain.PlexShow, anime *domain.Anime, dryRun bool) (*domain.WritebackResult, error) {
+	writer, ok := s.server.(mediaserver.MetadataWriter)
+	if !ok {
+		return nil, domain.ErrWritebackUnsupported
+	}
+	if !dryRun && !s.config.WritebackEnabled {
+		return nil, domain.ErrWritebackDisabled
+	}
+	if show.AnilistID == nil {
+		return nil, domain.ErrShowNotMapped
+	}
+
+	current, err := writer.GetItemMetadata(serverItemID(show))
 	if err != nil {
-		return fmt.Errorf("failed to search anilist for %s: %w", plexShow.Title, err)
+		return nil, fmt.Errorf("failed to read metadata for %s: %w", show.Title, err)
 	}
 
-	if anime != nil {
-		plexShow.AnilistID = &anime.AnilistID
-		plexShow.Anime = anime
+	result := &domain.WritebackResult{
+		PlexID:    show.PlexID,
+		Title:     show.Title,
+		AnilistID: *show.AnilistID,
 	}
 
-	return nil
-}
+	label := fmt.Sprintf("anilist-%d", *show.AnilistID)
+	hasLabel := false
+	for _, existing := range current.Labels {
+		switch {
+		case strings.EqualFold(existing, label):
+			hasLabel = true
+		case anilistLabelPattern.MatchString(existing):
+			result.Changes.RemoveLabels = append(result.Changes.RemoveLabels, existing)
+		}
+	}
+	if !hasLabel {
+		result.Changes.AddLabels = []string{label}
+	}
 
-func (s *PlexService) BulkAutoMapShows(shows []*domain.PlexShow) (int, error) {
-	mappedCount := 0
-	
-	for i, show := range shows {
-		if show.AnilistID != nil {
-			continue // Already mapped
+	if s.config.WritebackGenres && anime != nil && anime.Genres != "" {
+		wanted := strings.Split(anime.Genres, ", ")
+		result.Changes.AddGenres, result.Changes.RemoveGenres = diffTags(current.Genres, wanted)
+	}
+
+	if s.config.WritebackRating && anime != nil && anime.Score > 0 {
+		rating := anime.Score / 10
+		if math.Abs(current.Rating-rating) >= 0.05 {
+			result.Changes.Rating = &rating
 		}
-		
-		if err := s.MapShowToAnilist(show); err != nil {
-			// If we hit rate limiting, stop processing
-			if strings.Contains(err.Error(), "rate limited") {
-				break
-			}
-			continue // Skip on other errors, continue with next show
+	}
+
+	if dryRun || result.Changes.IsEmpty() {
+		return result, nil
+	}
+
+	if err := writer.UpdateItemMetadata(s.config.LibraryID, serverItemID(show), result.Changes); err != nil {
+		return nil, fmt.Errorf("failed to write metadata for %s: %w", show.Title, err)
+	}
+	result.Applied = true
+
+	return result, nil
+}
+
+// ClearWriteback removes the AniList labels written back to a show, so an
+// unmapped show is not mapped again from its label on the next sync.
+func (s *PlexService) ClearWriteback(show *domain.PlexShow) error {
+	writer, ok := s.server.(mediaserver.MetadataWriter)
+	if !ok {
+		return domain.ErrWritebackUnsupported
+	}
+	if !s.config.WritebackEnabled {
+		return domain.ErrWritebackDisabled
+	}
+
+	current, err := writer.GetItemMetadata(serverItemID(show))
+	if err != nil {
+		return fmt.Errorf("failed to read metadata for %s: %w", show.Title, err)
+	}
+
+	var update domain.MetadataUpdate
+	for _, existing := range current.Labels {
+		if anilistLabelPattern.MatchString(existing) {
+			update.RemoveLabels = append(update.RemoveLabels, existing)
 		}
-		
-		if show.AnilistID != nil {
-			mappedCount++
+	}
+	if update.IsEmpty() {
+		return nil
+	}
+
+	if err := writer.UpdateItemMetadata(s.config.LibraryID, serverItemID(show), update); err != nil {
+		return fmt.Errorf("failed to write metadata for %s: %w", show.Title, err)
+	}
+	return nil
+}
+
+// diffTags returns the tags to add and remove to turn current into wanted,
+// ignoring case.
+func diffTags(current []string, wanted []string) ([]string, []string) {
+	have := make(map[string]bool, len(current))
+	for _, tag := range current {
+		have[strings.ToLower(tag)] = true
+	}
+	want := make(map[string]bool, len(wanted))
+	for _, tag := range wanted {
+		want[strings.ToLower(tag)] = true
+	}
+
+	var add, remove []string
+	for _, tag := range wanted {
+		if !have[strings.ToLower(tag)] {
+			add = append(add, tag)
 		}
-		
-		// Add a small delay every 5 shows to be respectful to the API
-		if (i+1)%5 == 0 {
-			time.Sleep(2 * time.Second)
+	}
+	for _, tag := range current {
+		if !want[strings.ToLower(tag)] {
+			remove = append(remove, tag)
 		}
 	}
-	
-	return mappedCount, nil
-} 
\ No newline at end of file
+
+	return add, remove
+}
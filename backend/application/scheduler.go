@@ -0,0 +1,199 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+// TaskFunc is the work done on each scheduled run.
+type TaskFunc func(ctx context.Context) error
+
+type scheduledTask struct {
+	name     string
+	spec     string
+	schedule Schedule
+	run      TaskFunc
+
+	mu      sync.Mutex
+	running bool
+}
+
+// Scheduler runs tasks on intervals or cron expressions. Each task runs in its
+// own goroutine, one run at a time, so a slow run delays the next rather than
+// overlapping it. Run times are persisted, so a run missed while the server
+// was down happens shortly after it starts again.
+type Scheduler struct {
+	repo   *database.ScheduleRepository
+	jitter time.Duration
+	tasks  []*scheduledTask
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(repo *database.ScheduleRepository, jitter time.Duration) *Scheduler {
+	return &Scheduler{
+		repo:   repo,
+		jitter: jitter,
+	}
+}
+
+// Add registers a task. It must be called before Start.
+func (s *Scheduler) Add(name string, spec string, run TaskFunc) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return fmt.Errorf("schedule %s: %w", name, err)
+	}
+
+	s.tasks = append(s.tasks, &scheduledTask{
+		name:     name,
+		spec:     spec,
+		schedule: schedule,
+		run:      run,
+	})
+	return nil
+}
+
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, task := range s.tasks {
+		state := s.loadState(task)
+		log.Printf("Scheduling %s (%s), next run at %s", task.name, task.spec, state.NextRunAt.Format(time.RFC3339))
+
+		s.wg.Add(1)
+		go func(task *scheduledTask) {
+			defer s.wg.Done()
+			s.loop(ctx, task, state)
+		}(task)
+	}
+}
+
+// Stop cancels any running task and waits for it to return.
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// GetSchedules returns the persisted state of every registered task.
+func (s *Scheduler) GetSchedules() ([]domain.ScheduleState, error) {
+	states := make([]domain.ScheduleState, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		state, err := s.repo.GetSchedule(task.name)
+		if err != nil {
+			return nil, err
+		}
+		if state == nil {
+			state = &domain.ScheduleState{Name: task.name, Spec: task.spec}
+		}
+
+		task.mu.Lock()
+		state.Running = task.running
+		task.mu.Unlock()
+
+		states = append(states, *state)
+	}
+
+	return states, nil
+}
+
+// loadState picks up the persisted next run, which may be in the past if the
+// server was down, unless the schedule has changed since.
+func (s *Scheduler) loadState(task *scheduledTask) *domain.ScheduleState {
+	state, err := s.repo.GetSchedule(task.name)
+	if err != nil {
+		log.Printf("Failed to load schedule %s: %v", task.name, err)
+	}
+	if state != nil && state.Spec == task.spec && state.NextRunAt != nil {
+		return state
+	}
+
+	if state == nil {
+		state = &domain.ScheduleState{Name: task.name}
+	}
+	state.Spec = task.spec
+	next := s.next(task, time.Now())
+	state.NextRunAt = &next
+	s.saveState(state)
+
+	return state
+}
+
+func (s *Scheduler) loop(ctx context.Context, task *scheduledTask, state *domain.ScheduleState) {
+	for {
+		timer := time.NewTimer(time.Until(*state.NextRunAt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.execute(ctx, task, state)
+		if ctx.Err() != nil {
+			return
+		}
+
+		next := s.next(task, time.Now())
+		state.NextRunAt = &next
+		s.saveState(state)
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, task *scheduledTask, state *domain.ScheduleState) {
+	task.mu.Lock()
+	task.running = true
+	task.mu.Unlock()
+
+	started := time.Now()
+	err := task.run(ctx)
+
+	task.mu.Lock()
+	task.running = false
+	task.mu.Unlock()
+
+	state.LastRunAt = &started
+	state.DurationMS = time.Since(started).Milliseconds()
+	state.LastError = ""
+
+	switch {
+	case err == nil:
+		state.LastStatus = domain.ScheduleStatusSuccess
+	case errors.Is(err, domain.ErrSyncInProgress), errors.Is(err, domain.ErrJobAlreadyRunning):
+		state.LastStatus = domain.ScheduleStatusSkipped
+		state.LastError = err.Error()
+	default:
+		state.LastStatus = domain.ScheduleStatusFailed
+		state.LastError = err.Error()
+		log.Printf("Scheduled %s failed: %v", task.name, err)
+	}
+
+	s.saveState(state)
+}
+
+// next adds up to the configured jitter so instances sharing a schedule do
+// not all hit Plex and AniList at the same moment.
+func (s *Scheduler) next(task *scheduledTask, after time.Time) time.Time {
+	next := task.schedule.Next(after)
+	if s.jitter > 0 {
+		next = next.Add(time.Duration(rand.Int63n(int64(s.jitter))))
+	}
+	return next
+}
+
+func (s *Scheduler) saveState(state *domain.ScheduleState) {
+	if err := s.repo.SaveSchedule(state); err != nil {
+		log.Printf("Failed to save schedule %s: %v", state.Name, err)
+	}
+}
@@ -0,0 +1,160 @@
+package application
+
+import (
+	"sort"
+	"strings"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// rankedShowSearcher is implemented by show stores with a full-text index.
+// Other stores fall back to their substring search.
+type rankedShowSearcher interface {
+	SearchShowTitles(text string, limit int) ([]domain.SearchResult, error)
+}
+
+// SearchService searches shows on the server and cached AniList metadata
+// without contacting AniList, so it also works offline.
+type SearchService struct {
+	cacheRepo *database.AnimeCacheRepository
+	plexRepo  domain.PlexShowStore
+}
+
+func NewSearchService(cacheRepo *database.AnimeCacheRepository, plexRepo domain.PlexShowStore) *SearchService {
+	return &SearchService{
+		cacheRepo: cacheRepo,
+		plexRepo:  plexRepo,
+	}
+}
+
+// Search matches text against show titles and the titles, synonyms and
+// descriptions of cached anime. A show also matches through the anime it is
+// mapped to, which finds "Attack on Titan" when searching "Shingeki". Anime
+// on the server are returned as their show rather than twice.
+func (s *SearchService) Search(text string, limit int) ([]domain.SearchResult, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, &domain.ValidationError{Field: "q", Message: "search query is required"}
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	shows, err := s.searchShows(text, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	anime, err := s.cacheRepo.SearchAnime(text, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]domain.SearchResult, 0, len(shows)+len(anime))
+	byPlexID := make(map[int]int, len(shows))
+	for _, show := range shows {
+		byPlexID[show.PlexID] = len(results)
+		results = append(results, show)
+	}
+
+	mapped, err := s.showsByAnilistID(anime)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hit := range anime {
+		onServer := mapped[*hit.AnilistID]
+		if len(onServer) == 0 {
+			results = append(results, hit)
+			continue
+		}
+
+		for _, show := range onServer {
+			if i, ok := byPlexID[show.PlexID]; ok {
+				if hit.Score > results[i].Score {
+					results[i].Score = hit.Score
+				}
+				continue
+			}
+
+			byPlexID[show.PlexID] = len(results)
+			results = append(results, domain.SearchResult{
+				Kind:      domain.SearchResultShow,
+				PlexID:    show.PlexID,
+				AnilistID: show.AnilistID,
+				Title:     show.Title,
+				Highlight: hit.Highlight,
+				Score:     hit.Score,
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Title < results[j].Title
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (s *SearchService) searchShows(text string, limit int) ([]domain.SearchResult, error) {
+	if searcher, ok := s.plexRepo.(rankedShowSearcher); ok {
+		return searcher.SearchShowTitles(text, limit)
+	}
+
+	shows, err := s.plexRepo.SearchShowsOnServer(text)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]domain.SearchResult, 0, len(shows))
+	for _, show := range shows {
+		results = append(results, domain.SearchResult{
+			Kind:      domain.SearchResultShow,
+			PlexID:    show.PlexID,
+			AnilistID: show.AnilistID,
+			Title:     show.Title,
+			Highlight: show.Title,
+		})
+	}
+	return results, nil
+}
+
+// showsByAnilistID finds the shows mapped to any of the matched anime.
+func (s *SearchService) showsByAnilistID(anime []domain.SearchResult) (map[int][]domain.PlexShow, error) {
+	if len(anime) == 0 {
+		return nil, nil
+	}
+
+	wanted := make(map[int]bool, len(anime))
+	for _, hit := range anime {
+		wanted[*hit.AnilistID] = true
+	}
+
+	shows, err := s.plexRepo.GetAllPlexShows()
+	if err != nil {
+		return nil, err
+	}
+
+	mapped := make(map[int][]domain.PlexShow)
+	for _, show := range shows {
+		if show.AnilistID != nil && wanted[*show.AnilistID] {
+			mapped[*show.AnilistID] = append(mapped[*show.AnilistID], show)
+		}
+	}
+	return mapped, nil
+}
@@ -2,20 +2,24 @@ package application
 
 import (
 	"fmt"
+	"time"
 
 	"anime-watchlist/backend/domain"
-	"anime-watchlist/backend/infrastructure/database"
 )
 
 type AnimeService struct {
-	watchlistRepo *database.WatchlistRepository
+	watchlistRepo domain.WatchlistStore
 	anilistService *AnilistService
+	metadataService *MetadataService
+	events *EventBus
 }
 
-func NewAnimeService(watchlistRepo *database.WatchlistRepository, anilistService *AnilistService) *AnimeService {
+func NewAnimeService(watchlistRepo domain.WatchlistStore, anilistService *AnilistService, metadataService *MetadataService, events *EventBus) *AnimeService {
 	return &AnimeService{
 		watchlistRepo: watchlistRepo,
 		anilistService: anilistService,
+		metadataService: metadataService,
+		events: events,
 	}
 }
 
@@ -48,7 +52,7 @@ func (s *AnimeService) GetWatchlist() ([]domain.Anime, error) {
 
 	animes := make([]domain.Anime, len(watchlistItems))
 	for i, item := range watchlistItems {
-		anime, err := s.anilistService.GetAnimeByID(item.AnilistID)
+		anime, err := s.metadataService.GetAnime(item.AnilistID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get anime data for ID %d: %w", item.AnilistID, err)
 		}
@@ -74,7 +78,12 @@ func (s *AnimeService) AddToWatchlist(anilistID int) error {
 		return fmt.Errorf("anime already in watchlist")
 	}
 
-	return s.watchlistRepo.AddToWatchlist(anilistID)
+	if err := s.watchlistRepo.AddToWatchlist(anilistID); err != nil {
+		return err
+	}
+
+	s.events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: "added", AnilistID: anilistID})
+	return nil
 }
 
 func (s *AnimeService) RemoveFromWatchlist(anilistID int) error {
@@ -82,7 +91,79 @@ func (s *AnimeService) RemoveFromWatchlist(anilistID int) error {
 		return fmt.Errorf("invalid anilist ID: %d", anilistID)
 	}
 
-	return s.watchlistRepo.RemoveFromWatchlist(anilistID)
+	if err := s.watchlistRepo.RemoveFromWatchlist(anilistID); err != nil {
+		return err
+	}
+
+	s.events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: "removed", AnilistID: anilistID})
+	return nil
+}
+
+// UpdateWatchlistItem changes the watch state of an anime on the watchlist.
+func (s *AnimeService) UpdateWatchlistItem(anilistID int, update domain.WatchlistUpdate) (*domain.WatchlistItem, error) {
+	items, err := s.watchlistRepo.GetWatchlist()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get watchlist: %w", err)
+	}
+
+	var item *domain.WatchlistItem
+	for i := range items {
+		if items[i].AnilistID == anilistID {
+			item = &items[i]
+			break
+		}
+	}
+	if item == nil {
+		return nil, fmt.Errorf("anime not in watchlist")
+	}
+
+	if update.Status != nil {
+		if !domain.ValidWatchStatus(*update.Status) {
+			return nil, &domain.ValidationError{Field: "status", Message: fmt.Sprintf("unknown status %q", *update.Status)}
+		}
+		item.Status = *update.Status
+	}
+	if update.Score != nil {
+		if *update.Score < 0 || *update.Score > 10 {
+			return nil, &domain.ValidationError{Field: "score", Message: "score must be between 0 and 10"}
+		}
+		item.Score = *update.Score
+	}
+	if update.Progress != nil {
+		if *update.Progress < 0 {
+			return nil, &domain.ValidationError{Field: "progress", Message: "progress cannot be negative"}
+		}
+		item.Progress = *update.Progress
+	}
+	if update.StartedAt != nil {
+		if item.StartedAt, err = parseWatchDate("started_at", *update.StartedAt); err != nil {
+			return nil, err
+		}
+	}
+	if update.CompletedAt != nil {
+		if item.CompletedAt, err = parseWatchDate("completed_at", *update.CompletedAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.watchlistRepo.SaveWatchlistItem(item); err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: "updated", AnilistID: anilistID})
+	return item, nil
+}
+
+// parseWatchDate reads a YYYY-MM-DD date, where an empty value clears it.
+func parseWatchDate(field, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, &domain.ValidationError{Field: field, Message: field + " must be a YYYY-MM-DD date"}
+	}
+	return &date, nil
 }
 
 func (s *AnimeService) GetWatchlistCount() (int, error) {
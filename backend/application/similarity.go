@@ -0,0 +1,202 @@
+package application
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// normalizeTitle lowercases a title and collapses punctuation and whitespace
+// so that "Attack on Titan: Final Season" and "attack on titan final season"
+// compare as equal.
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	lastSpace := true
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == '&':
+			if !lastSpace {
+				b.WriteRune(' ')
+			}
+			b.WriteString("and ")
+			lastSpace = true
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			lastSpace = false
+		default:
+			if !lastSpace {
+				b.WriteRune(' ')
+				lastSpace = true
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// titleSimilarity returns a score between 0 and 1 for two titles, taking the
+// better of Jaro-Winkler (good for typos and shared prefixes) and token-set
+// ratio (good for reordered or extra words such as season suffixes).
+func titleSimilarity(a, b string) float64 {
+	a = normalizeTitle(a)
+	b = normalizeTitle(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+
+	score := jaroWinkler(a, b)
+	if ts := tokenSetRatio(a, b); ts > score {
+		score = ts
+	}
+
+	// Token-set ratio scores a title and its sequel's title as identical; a
+	// small penalty on the overall edit ratio keeps the exact title on top.
+	return score - 0.05*(1-indelRatio(a, b))
+}
+
+func jaroWinkler(a, b string) float64 {
+	s1 := []rune(a)
+	s2 := []rune(b)
+	if len(s1) == 0 || len(s2) == 0 {
+		return 0
+	}
+
+	matchDistance := max(len(s1), len(s2))/2 - 1
+	if matchDistance < 0 {
+		matchDistance = 0
+	}
+
+	s1Matches := make([]bool, len(s1))
+	s2Matches := make([]bool, len(s2))
+
+	matches := 0
+	for i := range s1 {
+		start := max(0, i-matchDistance)
+		end := min(len(s2), i+matchDistance+1)
+		for j := start; j < end; j++ {
+			if s2Matches[j] || s1[i] != s2[j] {
+				continue
+			}
+			s1Matches[i] = true
+			s2Matches[j] = true
+			matches++
+			break
+		}
+	}
+
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	k := 0
+	for i := range s1 {
+		if !s1Matches[i] {
+			continue
+		}
+		for !s2Matches[k] {
+			k++
+		}
+		if s1[i] != s2[k] {
+			transpositions++
+		}
+		k++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(s1)) + m/float64(len(s2)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for i := 0; i < min(4, min(len(s1), len(s2))); i++ {
+		if s1[i] != s2[i] {
+			break
+		}
+		prefix++
+	}
+
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// tokenSetRatio compares the shared and differing word sets of two titles,
+// so "Kimetsu no Yaiba" scores highly against "Kimetsu no Yaiba Season 2".
+func tokenSetRatio(a, b string) float64 {
+	tokensA := tokenSet(a)
+	tokensB := tokenSet(b)
+
+	var common, onlyA, onlyB []string
+	for token := range tokensA {
+		if tokensB[token] {
+			common = append(common, token)
+		} else {
+			onlyA = append(onlyA, token)
+		}
+	}
+	for token := range tokensB {
+		if !tokensA[token] {
+			onlyB = append(onlyB, token)
+		}
+	}
+
+	sort.Strings(common)
+	sort.Strings(onlyA)
+	sort.Strings(onlyB)
+
+	base := strings.Join(common, " ")
+	combinedA := strings.TrimSpace(base + " " + strings.Join(onlyA, " "))
+	combinedB := strings.TrimSpace(base + " " + strings.Join(onlyB, " "))
+
+	best := indelRatio(combinedA, combinedB)
+	if base != "" {
+		if r := indelRatio(base, combinedA); r > best {
+			best = r
+		}
+		if r := indelRatio(base, combinedB); r > best {
+			best = r
+		}
+	}
+	return best
+}
+
+func tokenSet(s string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, token := range strings.Fields(s) {
+		tokens[token] = true
+	}
+	return tokens
+}
+
+func indelRatio(a, b string) float64 {
+	s1 := []rune(a)
+	s2 := []rune(b)
+	total := len(s1) + len(s2)
+	if total == 0 {
+		return 1
+	}
+	return float64(total-indelDistance(s1, s2)) / float64(total)
+}
+
+// indelDistance is the edit distance where a substitution counts as a delete
+// plus an insert, which keeps indelRatio at 0 for unrelated strings.
+func indelDistance(s1, s2 []rune) int {
+	prev := make([]int, len(s2)+1)
+	curr := make([]int, len(s2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(s1); i++ {
+		curr[0] = i
+		for j := 1; j <= len(s2); j++ {
+			cost := 2
+			if s1[i-1] == s2[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(s2)]
+}
@@ -0,0 +1,119 @@
+package application
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Attack on Titan: Final Season", "attack on titan final season"},
+		{"  Re:Zero -Starting Life in Another World-  ", "re zero starting life in another world"},
+		{"K-On!!", "k on"},
+		{"Fate/stay night [Unlimited Blade Works]", "fate stay night unlimited blade works"},
+		{"Kimetsu no Yaiba Season 2", "kimetsu no yaiba season 2"},
+		{"Mob Psycho 100 II", "mob psycho 100 ii"},
+		{"Tom & Jerry", "tom and jerry"},
+		{"R&D", "r and d"},
+		{"Pokémon", "pokémon"},
+		{"ＳＰＹ×ＦＡＭＩＬＹ", "ｓｐｙ ｆａｍｉｌｙ"},
+		{"進撃の巨人 Season 3", "進撃の巨人 season 3"},
+		{"!!!", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeTitle(tt.title); got != tt.want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func assertScore(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 0.001 {
+		t.Errorf("%s = %.4f, want %.4f", name, got, want)
+	}
+}
+
+func TestJaroWinkler(t *testing.T) {
+	// The reference pairs from Winkler's paper
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"martha", "marhta", 0.9611},
+		{"dwayne", "duane", 0.84},
+		{"dixon", "dicksonx", 0.8133},
+		{"crate", "trace", 0.7333},
+		{"abc", "abc", 1},
+		{"abc", "xyz", 0},
+		{"", "abc", 0},
+		{"ab", "ba", 0},
+	}
+
+	for _, tt := range tests {
+		assertScore(t, "jaroWinkler("+tt.a+", "+tt.b+")", jaroWinkler(tt.a, tt.b), tt.want)
+		assertScore(t, "jaroWinkler("+tt.b+", "+tt.a+")", jaroWinkler(tt.b, tt.a), tt.want)
+	}
+}
+
+func TestTokenSetRatio(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"kimetsu no yaiba", "kimetsu no yaiba season 2", 1},
+		{"fullmetal alchemist brotherhood", "brotherhood fullmetal alchemist", 1},
+		{"fuzzy was a bear", "fuzzy fuzzy was a bear", 1},
+		// No shared words: the sorted strings differ in one letter of three
+		{"abc", "abd", 4.0 / 6},
+		{"naruto", "bleach", 2.0 / 12}, // only the a is shared
+	}
+
+	for _, tt := range tests {
+		assertScore(t, "tokenSetRatio("+tt.a+", "+tt.b+")", tokenSetRatio(tt.a, tt.b), tt.want)
+	}
+}
+
+func TestIndelRatio(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"abc", "", 0},
+		{"abc", "abc", 1},
+		{"abc", "xyz", 0},
+		// One substitution is a delete and an insert
+		{"abc", "abd", 4.0 / 6},
+		{"kitten", "sitting", 8.0 / 13},
+	}
+
+	for _, tt := range tests {
+		assertScore(t, "indelRatio("+tt.a+", "+tt.b+")", indelRatio(tt.a, tt.b), tt.want)
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	if got := titleSimilarity("Attack on Titan: Final Season", "attack on titan final season"); got != 1 {
+		t.Errorf("titles equal once normalized scored %.4f", got)
+	}
+	if got := titleSimilarity("!!!", "Attack on Titan"); got != 0 {
+		t.Errorf("empty normalized title scored %.4f", got)
+	}
+
+	// The sequel scores high, but below the exact title
+	exact := titleSimilarity("Kimetsu no Yaiba", "Kimetsu no Yaiba")
+	sequel := titleSimilarity("Kimetsu no Yaiba", "Kimetsu no Yaiba Season 2")
+	if sequel >= exact || sequel < 0.95 {
+		t.Errorf("sequel scored %.4f against exact %.4f", sequel, exact)
+	}
+
+	unrelated := titleSimilarity("Kimetsu no Yaiba", "Cowboy Bebop")
+	if unrelated > 0.6 {
+		t.Errorf("unrelated title scored %.4f", unrelated)
+	}
+}
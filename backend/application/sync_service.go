@@ -0,0 +1,295 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+// SyncService pulls the show list from Plex into the database. Only one sync
+// runs at a time, whether it was started by a request or the scheduler.
+type SyncService struct {
+	plexService   *PlexService
+	plexRepo      domain.PlexShowStore
+	syncStateRepo *database.SyncStateRepository
+	qualityRepo   *database.ShowQualityRepository
+	events        *EventBus
+	// collections, when set, updates the watchlist collection after a sync
+	collections *CollectionService
+	// fullSyncInterval is how long incremental syncs may run before the next
+	// sync lists the whole library again. Zero disables incremental syncs.
+	fullSyncInterval time.Duration
+	mu               sync.Mutex
+}
+
+const (
+	// syncProgressEvery is how many compared shows go between progress
+	// events.
+	syncProgressEvery = 25
+	// syncOverlap widens incremental syncs to cover clock differences
+	// between us and the server.
+	syncOverlap = 5 * time.Minute
+)
+
+func NewSyncService(plexService *PlexService, plexRepo domain.PlexShowStore, syncStateRepo *database.SyncStateRepository, qualityRepo *database.ShowQualityRepository, events *EventBus, collections *CollectionService, fullSyncInterval time.Duration) *SyncService {
+	return &SyncService{
+		plexService:      plexService,
+		plexRepo:         plexRepo,
+		syncStateRepo:    syncStateRepo,
+		qualityRepo:      qualityRepo,
+		events:           events,
+		collections:      collections,
+		fullSyncInterval: fullSyncInterval,
+	}
+}
+
+// Sync runs a sync in the given mode. An empty mode syncs incrementally when
+// the server supports it and the last full sync is recent enough, and fully
+// otherwise.
+func (s *SyncService) Sync(mode string) (*domain.SyncResult, error) {
+	if mode != "" && mode != domain.SyncModeFull && mode != domain.SyncModeIncremental {
+		return nil, &domain.ValidationError{Field: "mode", Message: "mode must be full or incremental"}
+	}
+
+	if !s.mu.TryLock() {
+		return nil, domain.ErrSyncInProgress
+	}
+	defer s.mu.Unlock()
+
+	started := time.Now()
+	s.events.Publish(domain.EventSyncStarted, nil)
+
+	result, err := s.sync(mode, started)
+	if err != nil {
+		s.events.Publish(domain.EventSyncFailed, map[string]string{"error": err.Error()})
+		return nil, err
+	}
+
+	if s.collections != nil {
+		s.collections.SyncAfterLibrarySync()
+	}
+
+	s.events.Publish(domain.EventSyncFinished, result)
+	return result, nil
+}
+
+func (s *SyncService) sync(mode string, started time.Time) (*domain.SyncResult, error) {
+	state, err := s.syncStateRepo.GetSyncState(s.plexService.ServerType(), s.plexService.LibraryID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync state: %w", err)
+	}
+	if state == nil {
+		state = &domain.SyncState{
+			Source:    s.plexService.ServerType(),
+			LibraryID: s.plexService.LibraryID(),
+		}
+	}
+
+	mode, err = s.resolveMode(mode, state, started)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.SyncResult{
+		Mode:      mode,
+		Changed:   []domain.SyncChange{},
+		StartedAt: started,
+	}
+
+	var shows []domain.PlexShow
+	if mode == domain.SyncModeIncremental {
+		since := state.LastSyncAt.Add(-syncOverlap)
+		result.Since = &since
+		shows, err = s.plexService.FetchShowsChangedSince(since)
+	} else {
+		shows, err = s.plexService.FetchShowsFromPlex()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch plex shows: %w", err)
+	}
+
+	if err := s.assignShowIDs(shows); err != nil {
+		return nil, err
+	}
+
+	for i := range shows {
+		change, err := s.detectChange(&shows[i])
+		if err != nil {
+			return nil, err
+		}
+		if change != "" {
+			result.Changed = append(result.Changed, domain.SyncChange{
+				PlexID: shows[i].PlexID,
+				Title:  shows[i].Title,
+				Change: change,
+			})
+		}
+
+		if (i+1)%syncProgressEvery == 0 || i == len(shows)-1 {
+			s.events.Publish(domain.EventSyncProgress, domain.SyncProgress{
+				Phase:     "comparing",
+				Processed: i + 1,
+				Total:     len(shows),
+			})
+		}
+	}
+
+	// Shows are saved in one batch so a failed sync leaves the library as it
+	// was instead of half updated
+	if err := s.plexRepo.UpsertPlexShows(shows); err != nil {
+		return nil, fmt.Errorf("failed to save plex shows: %w", err)
+	}
+	s.events.Publish(domain.EventSyncProgress, domain.SyncProgress{
+		Phase:     "saving",
+		Processed: len(shows),
+		Total:     len(shows),
+	})
+
+	if s.plexService.SupportsQuality() {
+		s.analyzeQuality(shows, result.Changed)
+	}
+
+	state.LastSyncAt = &started
+	if mode == domain.SyncModeFull {
+		state.LastFullSyncAt = &started
+	}
+	if err := s.syncStateRepo.SaveSyncState(state); err != nil {
+		return nil, fmt.Errorf("failed to save sync state: %w", err)
+	}
+
+	result.Count = len(shows)
+	result.Duration = time.Since(started).Round(time.Millisecond).String()
+	return result, nil
+}
+
+// resolveMode picks the mode for an automatic sync and checks that an
+// incremental sync is possible.
+func (s *SyncService) resolveMode(mode string, state *domain.SyncState, now time.Time) (string, error) {
+	canIncrement := s.plexService.SupportsIncrementalSync() && state.LastSyncAt != nil && state.LastFullSyncAt != nil
+
+	switch mode {
+	case domain.SyncModeFull:
+		return mode, nil
+	case domain.SyncModeIncremental:
+		if !canIncrement {
+			return "", &domain.ValidationError{Field: "mode", Message: "incremental sync needs a server that supports it and a previous full sync"}
+		}
+		return mode, nil
+	}
+
+	if canIncrement && s.fullSyncInterval > 0 && now.Sub(*state.LastFullSyncAt) < s.fullSyncInterval {
+		return domain.SyncModeIncremental, nil
+	}
+	return domain.SyncModeFull, nil
+}
+
+// analyzeQuality refreshes the quality summary of changed shows and of shows
+// that were never analyzed. Failures are logged so one unreadable show does
+// not fail the sync.
+func (s *SyncService) analyzeQuality(shows []domain.PlexShow, changed []domain.SyncChange) {
+	known, err := s.qualityRepo.GetShowQualities()
+	if err != nil {
+		log.Printf("Failed to get show quality: %v", err)
+		return
+	}
+
+	changedIDs := make(map[int]bool, len(changed))
+	for _, change := range changed {
+		changedIDs[change.PlexID] = true
+	}
+
+	var pending []*domain.PlexShow
+	for i := range shows {
+		if _, ok := known[shows[i].PlexID]; !ok || changedIDs[shows[i].PlexID] {
+			pending = append(pending, &shows[i])
+		}
+	}
+
+	for i, show := range pending {
+		quality, err := s.plexService.FetchShowQuality(show)
+		if err == nil {
+			err = s.qualityRepo.SaveShowQuality(quality)
+		}
+		if err != nil {
+			log.Printf("Failed to update quality of %s: %v", show.Title, err)
+		}
+
+		if (i+1)%syncProgressEvery == 0 || i == len(pending)-1 {
+			s.events.Publish(domain.EventSyncProgress, domain.SyncProgress{
+				Phase:     "quality",
+				Processed: i + 1,
+				Total:     len(pending),
+			})
+		}
+	}
+}
+
+// assignShowIDs gives each fetched show the ID its item is already stored
+// under. IDs of servers other than Plex are hashes of the server's item ID,
+// so a new item whose hash belongs to another item moves to the next free
+// ID instead of overwriting it.
+func (s *SyncService) assignShowIDs(shows []domain.PlexShow) error {
+	owners, err := s.plexRepo.GetShowRefs()
+	if err != nil {
+		return fmt.Errorf("failed to get stored shows: %w", err)
+	}
+
+	stored := make(map[domain.ShowRef]int, len(owners))
+	for plexID, ref := range owners {
+		if ref.ExternalID != "" {
+			stored[ref] = plexID
+		}
+	}
+
+	for i := range shows {
+		ref := domain.RefOf(&shows[i])
+		if plexID, ok := stored[ref]; ok {
+			shows[i].PlexID = plexID
+			continue
+		}
+
+		plexID := shows[i].PlexID
+		for {
+			owner, taken := owners[plexID]
+			if !taken || owner.SameItem(ref) {
+				break
+			}
+			plexID = plexID%math.MaxInt32 + 1
+		}
+		if plexID != shows[i].PlexID {
+			log.Printf("Show ID %d of %s already belongs to %s item %s; storing it as %d", shows[i].PlexID, shows[i].Title, owners[shows[i].PlexID].Source, owners[shows[i].PlexID].ExternalID, plexID)
+			shows[i].PlexID = plexID
+		}
+
+		owners[plexID] = ref
+		if ref.ExternalID != "" {
+			stored[ref] = plexID
+		}
+	}
+
+	return nil
+}
+
+// detectChange compares a fetched show with the stored one. It returns an
+// empty string when nothing we track has changed.
+func (s *SyncService) detectChange(show *domain.PlexShow) (string, error) {
+	existing, err := s.plexRepo.GetPlexShowByPlexID(show.PlexID)
+	if errors.Is(err, domain.ErrShowNotFound) {
+		return domain.SyncChangeAdded, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get plex show %s: %w", show.Title, err)
+	}
+
+	if existing.Title != show.Title || existing.Year != show.Year ||
+		existing.EpisodeCount != show.EpisodeCount || existing.GUID != show.GUID {
+		return domain.SyncChangeUpdated, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,72 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/memory"
+)
+
+func TestAssignShowIDs(t *testing.T) {
+	store := memory.NewPlexShowStore()
+	err := store.UpsertPlexShows([]domain.PlexShow{
+		{PlexID: 5, Source: "jellyfin", ExternalID: "a", Title: "A"},
+		// Synced from Plex before external IDs were stored
+		{PlexID: 7, Source: "plex", Title: "Legacy"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := &SyncService{plexRepo: store}
+	shows := []domain.PlexShow{
+		{PlexID: 5, Source: "jellyfin", ExternalID: "b", Title: "B collides with A"},
+		{PlexID: 5, Source: "jellyfin", ExternalID: "a", Title: "A"},
+		{PlexID: 5, Source: "jellyfin", ExternalID: "c", Title: "C collides with A and B"},
+		{PlexID: 7, Source: "plex", ExternalID: "7", Title: "Legacy"},
+	}
+	if err := service.assignShowIDs(shows); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{6, 5, 8, 7}
+	for i, show := range shows {
+		if show.PlexID != want[i] {
+			t.Errorf("%s stored as %d, want %d", show.Title, show.PlexID, want[i])
+		}
+	}
+	if err := store.UpsertPlexShows(shows); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fetched again, every item keeps its ID
+	again := []domain.PlexShow{
+		{PlexID: 5, Source: "jellyfin", ExternalID: "c", Title: "C"},
+		{PlexID: 5, Source: "jellyfin", ExternalID: "b", Title: "B"},
+	}
+	if err := service.assignShowIDs(again); err != nil {
+		t.Fatal(err)
+	}
+	if again[0].PlexID != 8 || again[1].PlexID != 6 {
+		t.Errorf("IDs changed between syncs: %d and %d", again[0].PlexID, again[1].PlexID)
+	}
+}
+
+func TestUpsertRefusesShowIDCollision(t *testing.T) {
+	store := memory.NewPlexShowStore()
+	if err := store.UpsertPlexShow(&domain.PlexShow{PlexID: 5, Source: "emby", ExternalID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.UpsertPlexShows([]domain.PlexShow{
+		{PlexID: 9, Source: "emby", ExternalID: "z"},
+		{PlexID: 5, Source: "emby", ExternalID: "b"},
+	})
+	if !errors.Is(err, domain.ErrShowIDCollision) {
+		t.Fatalf("err = %v, want ErrShowIDCollision", err)
+	}
+	if _, err := store.GetPlexShowByPlexID(9); !errors.Is(err, domain.ErrShowNotFound) {
+		t.Error("failed batch saved a show")
+	}
+}
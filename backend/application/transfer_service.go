@@ -0,0 +1,622 @@
+package application
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+// importActor is recorded in mapping history for changes made by imports.
+const importActor = "import"
+
+// TransferService exports all user data to a portable document and imports
+// it again, on this instance or another one.
+type TransferService struct {
+	watchlistRepo domain.WatchlistStore
+	plexRepo      domain.PlexShowStore
+	seasonRepo    *database.SeasonMappingRepository
+	settingsRepo  *database.SettingsRepository
+	events        *EventBus
+}
+
+func NewTransferService(watchlistRepo domain.WatchlistStore, plexRepo domain.PlexShowStore, seasonRepo *database.SeasonMappingRepository, settingsRepo *database.SettingsRepository, events *EventBus) *TransferService {
+	return &TransferService{
+		watchlistRepo: watchlistRepo,
+		plexRepo:      plexRepo,
+		seasonRepo:    seasonRepo,
+		settingsRepo:  settingsRepo,
+		events:        events,
+	}
+}
+
+// Export collects the watchlist, every mapped or ignored show with its season
+// mappings, and the settings.
+func (s *TransferService) Export() (*domain.ExportDocument, error) {
+	items, err := s.watchlistRepo.GetWatchlist()
+	if err != nil {
+		return nil, err
+	}
+
+	shows, err := s.plexRepo.GetAllPlexShows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shows: %w", err)
+	}
+
+	seasons, err := s.seasonsByShow()
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := s.settingsRepo.GetSettings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+
+	doc := &domain.ExportDocument{
+		Version:    domain.ExportVersion,
+		ExportedAt: time.Now(),
+		Watchlist:  make([]domain.ExportedWatchlistItem, 0, len(items)),
+		Mappings:   []domain.ExportedMapping{},
+		Settings:   settings,
+	}
+
+	for _, item := range items {
+		doc.Watchlist = append(doc.Watchlist, domain.ExportedWatchlistItem{
+			AnilistID:   item.AnilistID,
+			Status:      item.Status,
+			Score:       item.Score,
+			Progress:    item.Progress,
+			StartedAt:   item.StartedAt,
+			CompletedAt: item.CompletedAt,
+			AddedAt:     item.AddedAt,
+		})
+	}
+
+	for _, show := range shows {
+		showSeasons := seasons[show.PlexID]
+		if show.AnilistID == nil && !show.Ignored && len(showSeasons) == 0 {
+			continue
+		}
+
+		mapping := domain.ExportedMapping{
+			Title:     show.Title,
+			Year:      show.Year,
+			GUID:      show.GUID,
+			AnilistID: show.AnilistID,
+			Locked:    show.MappingLocked,
+			Ignored:   show.Ignored,
+		}
+		if show.AnilistID != nil {
+			mapping.MappingSource = show.MappingSource
+		}
+		for _, season := range showSeasons {
+			mapping.Seasons = append(mapping.Seasons, exportSeason(season))
+		}
+		doc.Mappings = append(doc.Mappings, mapping)
+	}
+
+	return doc, nil
+}
+
+// Import applies a document. Merge only fills in what is missing here:
+// watchlist entries, mappings of unmapped shows, ignored flags and season
+// mappings. Replace makes this instance match the document, removing
+// watchlist entries, mappings and settings it does not contain. With dryRun
+// nothing is written and the result describes what would change.
+//
+// Writes are not made in one transaction. When one fails, the result is
+// returned along with the error, with Applied counting what was written
+// before it. Every part diffs against the current state, so running the same
+// import again applies only what is left.
+func (s *TransferService) Import(doc *domain.ExportDocument, mode string, dryRun bool) (*domain.ImportResult, error) {
+	if mode == "" {
+		mode = domain.ImportModeMerge
+	}
+	if err := validateImport(doc, mode); err != nil {
+		return nil, err
+	}
+
+	result := &domain.ImportResult{Mode: mode, DryRun: dryRun}
+
+	var err error
+	if err = s.importWatchlist(doc, mode, dryRun, result); err != nil {
+		err = fmt.Errorf("failed to import watchlist: %w", err)
+	} else if err = s.importMappings(doc, mode, dryRun, result); err != nil {
+		err = fmt.Errorf("failed to import mappings: %w", err)
+	} else if err = s.importSettings(doc, mode, dryRun, result); err != nil {
+		err = fmt.Errorf("failed to import settings: %w", err)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	return result, nil
+}
+
+func validateImport(doc *domain.ExportDocument, mode string) error {
+	if mode != domain.ImportModeMerge && mode != domain.ImportModeReplace {
+		return &domain.ValidationError{Field: "mode", Message: "mode must be merge or replace"}
+	}
+	if doc.Version < 1 || doc.Version > domain.ExportVersion {
+		return &domain.ValidationError{Field: "version", Message: fmt.Sprintf("unsupported export version %d (this server reads up to %d)", doc.Version, domain.ExportVersion)}
+	}
+
+	for _, item := range doc.Watchlist {
+		if item.AnilistID <= 0 {
+			return domain.ErrInvalidAnilistID
+		}
+		if item.Status != "" && !domain.ValidWatchStatus(item.Status) {
+			return &domain.ValidationError{Field: "watchlist", Message: fmt.Sprintf("unknown status %q for anime %d", item.Status, item.AnilistID)}
+		}
+		if item.Score < 0 || item.Score > 10 || item.Progress < 0 {
+			return &domain.ValidationError{Field: "watchlist", Message: fmt.Sprintf("anime %d needs a score from 0 to 10 and a progress of at least 0", item.AnilistID)}
+		}
+	}
+	for _, mapping := range doc.Mappings {
+		if mapping.Title == "" && mapping.GUID == "" {
+			return &domain.ValidationError{Field: "mappings", Message: "every mapping needs a title or guid"}
+		}
+		if mapping.AnilistID != nil && *mapping.AnilistID <= 0 {
+			return domain.ErrInvalidAnilistID
+		}
+		for _, season := range mapping.Seasons {
+			if season.AnilistID <= 0 {
+				return domain.ErrInvalidAnilistID
+			}
+		}
+	}
+	return nil
+}
+
+func (s *TransferService) importWatchlist(doc *domain.ExportDocument, mode string, dryRun bool, result *domain.ImportResult) error {
+	items, err := s.watchlistRepo.GetWatchlist()
+	if err != nil {
+		return err
+	}
+
+	current := make(map[int]domain.WatchlistItem, len(items))
+	for _, item := range items {
+		current[item.AnilistID] = item
+	}
+
+	diff := domain.ImportWatchlistDiff{Added: []int{}, Updated: []int{}, Removed: []int{}}
+	imported := make(map[int]bool, len(doc.Watchlist))
+	var toSave []domain.WatchlistItem
+	for _, exported := range doc.Watchlist {
+		if imported[exported.AnilistID] {
+			continue
+		}
+		imported[exported.AnilistID] = true
+
+		item := importWatchlistItem(exported)
+		existing, ok := current[item.AnilistID]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, item.AnilistID)
+		case mode == domain.ImportModeReplace && !sameWatchState(existing, item):
+			diff.Updated = append(diff.Updated, item.AnilistID)
+		default:
+			diff.Unchanged++
+			continue
+		}
+		toSave = append(toSave, item)
+	}
+
+	if mode == domain.ImportModeReplace {
+		for _, item := range items {
+			if !imported[item.AnilistID] {
+				diff.Removed = append(diff.Removed, item.AnilistID)
+			}
+		}
+	}
+
+	result.Watchlist = diff
+	if dryRun {
+		return nil
+	}
+
+	added := make(map[int]bool, len(diff.Added))
+	for _, anilistID := range diff.Added {
+		added[anilistID] = true
+	}
+	for i := range toSave {
+		item := &toSave[i]
+		if err := s.watchlistRepo.SaveWatchlistItem(item); err != nil {
+			return err
+		}
+		result.Applied++
+		action := "updated"
+		if added[item.AnilistID] {
+			action = "added"
+		}
+		s.events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: action, AnilistID: item.AnilistID})
+	}
+	for _, anilistID := range diff.Removed {
+		if err := s.watchlistRepo.RemoveFromWatchlist(anilistID); err != nil {
+			return err
+		}
+		result.Applied++
+		s.events.Publish(domain.EventWatchlistChanged, domain.WatchlistChange{Action: "removed", AnilistID: anilistID})
+	}
+
+	return nil
+}
+
+// mappingPlan is the state one show should end up in.
+type mappingPlan struct {
+	show      domain.PlexShow
+	anilistID *int
+	source    string
+	ignored   bool
+	// seasons, when not nil, replaces the show's season mappings in replace
+	// mode and is added to them in merge mode.
+	seasons []domain.SeasonMapping
+}
+
+func (s *TransferService) importMappings(doc *domain.ExportDocument, mode string, dryRun bool, result *domain.ImportResult) error {
+	shows, err := s.plexRepo.GetAllPlexShows()
+	if err != nil {
+		return err
+	}
+
+	seasons, err := s.seasonsByShow()
+	if err != nil {
+		return err
+	}
+
+	diff := domain.ImportMappingDiff{
+		Changed:   []domain.ImportMappingChange{},
+		Conflicts: []domain.ImportMappingChange{},
+		Unmatched: []string{},
+	}
+
+	matcher := newShowMatcher(shows)
+	var plans []mappingPlan
+	for _, mapping := range doc.Mappings {
+		show := matcher.match(mapping)
+		if show == nil {
+			diff.Unmatched = append(diff.Unmatched, describeExportedShow(mapping))
+			continue
+		}
+
+		plan := mappingPlan{show: *show, anilistID: show.AnilistID, source: show.MappingSource, ignored: show.Ignored}
+		local := seasons[show.PlexID]
+
+		if mode == domain.ImportModeReplace {
+			plan.anilistID = mapping.AnilistID
+			plan.source = importedSource(mapping)
+			plan.ignored = mapping.Ignored
+			if !sameSeasons(local, mapping.Seasons) {
+				plan.seasons = importSeasons(show.PlexID, mapping.Seasons)
+			}
+		} else {
+			if show.AnilistID == nil && mapping.AnilistID != nil {
+				plan.anilistID = mapping.AnilistID
+				plan.source = importedSource(mapping)
+			} else if show.AnilistID != nil && mapping.AnilistID != nil && *show.AnilistID != *mapping.AnilistID {
+				diff.Conflicts = append(diff.Conflicts, mappingChange(show, mapping.AnilistID, mapping.Ignored, 0))
+			}
+			plan.ignored = show.Ignored || mapping.Ignored
+			plan.seasons = missingSeasons(show.PlexID, local, mapping.Seasons)
+		}
+
+		plans = append(plans, plan)
+	}
+
+	// Replace clears everything the document does not mention
+	if mode == domain.ImportModeReplace {
+		for i := range shows {
+			show := shows[i]
+			if matcher.claimed[show.PlexID] {
+				continue
+			}
+			if show.AnilistID == nil && !show.Ignored && len(seasons[show.PlexID]) == 0 {
+				continue
+			}
+			plan := mappingPlan{show: show}
+			if len(seasons[show.PlexID]) > 0 {
+				plan.seasons = []domain.SeasonMapping{}
+			}
+			plans = append(plans, plan)
+		}
+	}
+
+	var changes []mappingPlan
+	for _, plan := range plans {
+		mappingChanged := !sameAnilistID(plan.show.AnilistID, plan.anilistID) ||
+			(plan.anilistID != nil && plan.source != plan.show.MappingSource)
+		if !mappingChanged && plan.ignored == plan.show.Ignored && plan.seasons == nil {
+			diff.Unchanged++
+			continue
+		}
+
+		diff.Changed = append(diff.Changed, mappingChange(&plan.show, plan.anilistID, plan.ignored, len(plan.seasons)))
+		changes = append(changes, plan)
+	}
+
+	result.Mappings = diff
+	if dryRun {
+		return nil
+	}
+
+	for _, plan := range changes {
+		if err := s.applyMapping(plan, mode); err != nil {
+			return fmt.Errorf("failed to update %s: %w", plan.show.Title, err)
+		}
+		result.Applied++
+	}
+	return nil
+}
+
+func (s *TransferService) applyMapping(plan mappingPlan, mode string) error {
+	show := plan.show
+
+	if !sameAnilistID(show.AnilistID, plan.anilistID) || (plan.anilistID != nil && plan.source != show.MappingSource) {
+		// A locked mapping only accepts manual changes, so unlock it first
+		if plan.anilistID == nil || (show.MappingLocked && plan.source != domain.MappingSourceManual) {
+			if err := s.plexRepo.ClearShowMapping(show.PlexID, importActor); err != nil {
+				return err
+			}
+		}
+		if plan.anilistID != nil {
+			if err := s.plexRepo.UpdateShowMapping(show.PlexID, *plan.anilistID, plan.source, importActor); err != nil {
+				return err
+			}
+		}
+	}
+
+	if plan.ignored != show.Ignored {
+		if err := s.plexRepo.SetShowIgnored(show.PlexID, plan.ignored); err != nil {
+			return err
+		}
+	}
+
+	if plan.seasons != nil {
+		if mode == domain.ImportModeReplace {
+			if err := s.seasonRepo.DeleteSeasonMappings(show.PlexID); err != nil {
+				return err
+			}
+		}
+		for i := range plan.seasons {
+			if err := s.seasonRepo.SetSeasonMapping(&plan.seasons[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *TransferService) importSettings(doc *domain.ExportDocument, mode string, dryRun bool, result *domain.ImportResult) error {
+	current, err := s.settingsRepo.GetSettings()
+	if err != nil {
+		return err
+	}
+
+	diff := domain.ImportSettingsDiff{Set: []string{}, Removed: []string{}}
+	changes := make(map[string]*string)
+	for key, value := range doc.Settings {
+		if existing, ok := current[key]; ok && (existing == value || mode == domain.ImportModeMerge) {
+			continue
+		}
+		value := value
+		changes[key] = &value
+		diff.Set = append(diff.Set, key)
+	}
+
+	if mode == domain.ImportModeReplace {
+		for key := range current {
+			if _, ok := doc.Settings[key]; !ok {
+				changes[key] = nil
+				diff.Removed = append(diff.Removed, key)
+			}
+		}
+	}
+
+	sort.Strings(diff.Set)
+	sort.Strings(diff.Removed)
+	result.Settings = diff
+
+	if dryRun || len(changes) == 0 {
+		return nil
+	}
+	if err := s.settingsRepo.UpdateSettings(changes); err != nil {
+		return err
+	}
+	result.Applied += len(changes)
+	return nil
+}
+
+func (s *TransferService) seasonsByShow() (map[int][]domain.SeasonMapping, error) {
+	mappings, err := s.seasonRepo.GetAllSeasonMappings()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get season mappings: %w", err)
+	}
+
+	byShow := make(map[int][]domain.SeasonMapping)
+	for _, mapping := range mappings {
+		byShow[mapping.PlexID] = append(byShow[mapping.PlexID], mapping)
+	}
+	return byShow, nil
+}
+
+// showMatcher finds the local show an exported mapping belongs to. Each show
+// is matched at most once.
+type showMatcher struct {
+	byGUID      map[string]*domain.PlexShow
+	byTitleYear map[string][]*domain.PlexShow
+	claimed     map[int]bool
+}
+
+func newShowMatcher(shows []domain.PlexShow) *showMatcher {
+	m := &showMatcher{
+		byGUID:      make(map[string]*domain.PlexShow),
+		byTitleYear: make(map[string][]*domain.PlexShow),
+		claimed:     make(map[int]bool),
+	}
+	for i := range shows {
+		show := &shows[i]
+		if show.GUID != "" {
+			m.byGUID[show.GUID] = show
+		}
+		key := titleYearKey(show.Title, show.Year)
+		m.byTitleYear[key] = append(m.byTitleYear[key], show)
+	}
+	return m
+}
+
+func (m *showMatcher) match(mapping domain.ExportedMapping) *domain.PlexShow {
+	if show, ok := m.byGUID[mapping.GUID]; ok && mapping.GUID != "" && !m.claimed[show.PlexID] {
+		m.claimed[show.PlexID] = true
+		return show
+	}
+
+	for _, show := range m.byTitleYear[titleYearKey(mapping.Title, mapping.Year)] {
+		if !m.claimed[show.PlexID] {
+			m.claimed[show.PlexID] = true
+			return show
+		}
+	}
+	return nil
+}
+
+func titleYearKey(title string, year int) string {
+	return normalizeTitle(title) + "|" + strconv.Itoa(year)
+}
+
+func describeExportedShow(mapping domain.ExportedMapping) string {
+	if mapping.Year > 0 {
+		return fmt.Sprintf("%s (%d)", mapping.Title, mapping.Year)
+	}
+	return mapping.Title
+}
+
+// importedSource is the mapping source an imported mapping is saved with.
+// Locked mappings stay locked by being saved as manual.
+func importedSource(mapping domain.ExportedMapping) string {
+	if mapping.AnilistID == nil {
+		return ""
+	}
+	if mapping.Locked {
+		return domain.MappingSourceManual
+	}
+	if mapping.MappingSource == "" {
+		return domain.MappingSourceAuto
+	}
+	return mapping.MappingSource
+}
+
+func mappingChange(show *domain.PlexShow, anilistID *int, ignored bool, seasons int) domain.ImportMappingChange {
+	return domain.ImportMappingChange{
+		PlexID:       show.PlexID,
+		Title:        show.Title,
+		OldAnilistID: show.AnilistID,
+		NewAnilistID: anilistID,
+		OldIgnored:   show.Ignored,
+		NewIgnored:   ignored,
+		Seasons:      seasons,
+	}
+}
+
+func exportSeason(mapping domain.SeasonMapping) domain.ExportedSeasonMapping {
+	return domain.ExportedSeasonMapping{
+		SeasonNumber:    mapping.SeasonNumber,
+		AnilistID:       mapping.AnilistID,
+		EpisodeOffset:   mapping.EpisodeOffset,
+		EpisodeCount:    mapping.EpisodeCount,
+		AnilistEpisodes: mapping.AnilistEpisodes,
+		Source:          mapping.Source,
+	}
+}
+
+func importSeasons(plexID int, seasons []domain.ExportedSeasonMapping) []domain.SeasonMapping {
+	mappings := make([]domain.SeasonMapping, 0, len(seasons))
+	for _, season := range seasons {
+		source := season.Source
+		if source == "" {
+			source = domain.MappingSourceManual
+		}
+		mappings = append(mappings, domain.SeasonMapping{
+			PlexID:          plexID,
+			SeasonNumber:    season.SeasonNumber,
+			AnilistID:       season.AnilistID,
+			EpisodeOffset:   season.EpisodeOffset,
+			EpisodeCount:    season.EpisodeCount,
+			AnilistEpisodes: season.AnilistEpisodes,
+			Source:          source,
+		})
+	}
+	return mappings
+}
+
+// missingSeasons returns the imported season mappings for seasons and
+// AniList entries the show has no season mapping for yet, or nil when there
+// are none. A season mapped here keeps its local mappings, even when the
+// document splits it differently.
+func missingSeasons(plexID int, local []domain.SeasonMapping, imported []domain.ExportedSeasonMapping) []domain.SeasonMapping {
+	have := make(map[int]bool, len(local))
+	mappedSeasons := make(map[int]bool, len(local))
+	for _, mapping := range local {
+		have[mapping.AnilistID] = true
+		mappedSeasons[mapping.SeasonNumber] = true
+	}
+
+	var missing []domain.ExportedSeasonMapping
+	for _, season := range imported {
+		if !have[season.AnilistID] && !mappedSeasons[season.SeasonNumber] {
+			have[season.AnilistID] = true
+			missing = append(missing, season)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return importSeasons(plexID, missing)
+}
+
+func sameSeasons(local []domain.SeasonMapping, imported []domain.ExportedSeasonMapping) bool {
+	if len(local) != len(imported) {
+		return false
+	}
+
+	want := make(map[domain.ExportedSeasonMapping]bool, len(imported))
+	for _, season := range imported {
+		want[season] = true
+	}
+	for _, mapping := range local {
+		if !want[exportSeason(mapping)] {
+			return false
+		}
+	}
+	return true
+}
+
+// importWatchlistItem converts an exported entry, treating a missing status
+// as being watched like entries from before statuses existed.
+func importWatchlistItem(exported domain.ExportedWatchlistItem) domain.WatchlistItem {
+	item := domain.WatchlistItem{
+		AnilistID:   exported.AnilistID,
+		Status:      exported.Status,
+		Score:       exported.Score,
+		Progress:    exported.Progress,
+		StartedAt:   exported.StartedAt,
+		CompletedAt: exported.CompletedAt,
+		AddedAt:     exported.AddedAt,
+	}
+	if item.Status == "" {
+		item.Status = domain.WatchStatusCurrent
+	}
+	return item
+}
+
+func sameAnilistID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
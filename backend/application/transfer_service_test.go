@@ -0,0 +1,94 @@
+package application
+
+import (
+	"errors"
+	"testing"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+	"anime-watchlist/backend/infrastructure/memory"
+)
+
+func TestMissingSeasons(t *testing.T) {
+	local := []domain.SeasonMapping{
+		{PlexID: 1, SeasonNumber: 1, AnilistID: 100, Source: domain.MappingSourceManual},
+	}
+	imported := []domain.ExportedSeasonMapping{
+		// Already mapped here to the same entry
+		{SeasonNumber: 1, AnilistID: 100},
+		// Season 1 is mapped here, split differently in the document
+		{SeasonNumber: 1, AnilistID: 101, EpisodeOffset: 12},
+		{SeasonNumber: 2, AnilistID: 200},
+		{SeasonNumber: 2, AnilistID: 201, EpisodeOffset: 12},
+		{SeasonNumber: 3, AnilistID: 200},
+	}
+
+	missing := missingSeasons(1, local, imported)
+	if len(missing) != 2 || missing[0].AnilistID != 200 || missing[1].AnilistID != 201 {
+		t.Fatalf("missing = %+v, want both parts of season 2", missing)
+	}
+	for _, season := range missing {
+		if season.PlexID != 1 || season.SeasonNumber != 2 || season.Source != domain.MappingSourceManual {
+			t.Errorf("season = %+v", season)
+		}
+	}
+
+	if missing := missingSeasons(1, local, imported[:2]); missing != nil {
+		t.Errorf("nothing missing, got %+v", missing)
+	}
+}
+
+// failingWatchlistStore fails every save after the first saves.
+type failingWatchlistStore struct {
+	*memory.WatchlistStore
+	saves int
+}
+
+func (s *failingWatchlistStore) SaveWatchlistItem(item *domain.WatchlistItem) error {
+	if s.saves <= 0 {
+		return errors.New("disk full")
+	}
+	s.saves--
+	return s.WatchlistStore.SaveWatchlistItem(item)
+}
+
+func TestImportReportsPartialWrites(t *testing.T) {
+	db, err := database.New(database.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	watchlist := &failingWatchlistStore{WatchlistStore: memory.NewWatchlistStore(), saves: 1}
+	service := NewTransferService(watchlist, memory.NewPlexShowStore(), database.NewSeasonMappingRepository(db.DB), database.NewSettingsRepository(db.DB), nil)
+
+	doc := &domain.ExportDocument{
+		Version:   domain.ExportVersion,
+		Watchlist: []domain.ExportedWatchlistItem{{AnilistID: 1}, {AnilistID: 2}, {AnilistID: 3}},
+		Settings:  map[string]string{"theme": "dark"},
+	}
+
+	result, err := service.Import(doc, domain.ImportModeMerge, false)
+	if err == nil {
+		t.Fatal("expected the failed save to be returned")
+	}
+	if result == nil || result.Applied != 1 || result.Error == "" || len(result.Watchlist.Added) != 3 {
+		t.Fatalf("partial result = %+v", result)
+	}
+	if settings, _ := database.NewSettingsRepository(db.DB).GetSettings(); settings["theme"] != "" {
+		t.Error("settings were imported after the watchlist failed")
+	}
+
+	// Importing again finishes the job
+	watchlist.saves = 10
+	result, err = service.Import(doc, domain.ImportModeMerge, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Watchlist.Added) != 2 || result.Watchlist.Unchanged != 1 || result.Applied != 3 || result.Error != "" {
+		t.Errorf("second import = %+v", result)
+	}
+	if count, _ := watchlist.GetWatchlistCount(); count != 3 {
+		t.Errorf("watchlist has %d entries, want 3", count)
+	}
+}
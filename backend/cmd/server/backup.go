@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"anime-watchlist/backend/application"
+	"anime-watchlist/backend/infrastructure/config"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+// runBackup handles "server backup", which writes a snapshot to BACKUP_DIR.
+// It works while the server is running.
+func runBackup(cfg *config.Config) {
+	db, err := database.Open(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	backup, err := application.NewBackupService(db, cfg.Backup.Dir, cfg.Backup.KeepDaily, cfg.Backup.KeepWeekly, cfg.Database.URL != "").Backup()
+	if err != nil {
+		log.Fatalf("Failed to back up database: %v", err)
+	}
+
+	fmt.Printf("Wrote %s (%d bytes)\n", backup.Name, backup.Size)
+}
+
+// runRestore handles "server restore <file>". The server must be stopped, as
+// the database file is replaced underneath it.
+func runRestore(cfg *config.Config, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: server restore <backup file>")
+		os.Exit(2)
+	}
+
+	version, err := database.Restore(cfg.Database.Path, args[0])
+	if err != nil {
+		log.Fatalf("Failed to restore database: %v", err)
+	}
+
+	fmt.Printf("Restored %s (schema version %d); the previous database was kept as %s.pre-restore\n", args[0], version, cfg.Database.Path)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"anime-watchlist/backend/infrastructure/config"
+	"anime-watchlist/backend/infrastructure/database"
+	"anime-watchlist/backend/infrastructure/postgres"
+)
+
+// runCopyToPostgres handles "server copy-to-postgres", which moves the
+// watchlist and shows from the SQLite database at DATABASE_PATH into the
+// empty PostgreSQL database at DATABASE_URL.
+func runCopyToPostgres(cfg *config.Config) {
+	if cfg.Database.URL == "" {
+		log.Fatal("DATABASE_URL must be set to copy to postgres")
+	}
+	if cfg.Database.Path == database.MemoryPath {
+		log.Fatal("DATABASE_PATH must point at an existing SQLite database")
+	}
+
+	// Migrating brings an older SQLite database up to the schema the copy reads
+	src, err := database.New(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer src.Close()
+
+	dst, err := postgres.New(cfg.Database.URL)
+	if err != nil {
+		log.Fatalf("Failed to initialize postgres: %v", err)
+	}
+	defer dst.Close()
+
+	result, err := postgres.CopyFromSQLite(src.DB, dst)
+	if err != nil {
+		log.Fatalf("Failed to copy to postgres: %v", err)
+	}
+
+	fmt.Printf("Copied %d watchlist entries, %d shows and %d mapping history entries\n",
+		result.Watchlist, result.Shows, result.MappingHistory)
+}
@@ -1,60 +1,233 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"anime-watchlist/backend/api"
 	"anime-watchlist/backend/application"
 	"anime-watchlist/backend/domain"
 	"anime-watchlist/backend/infrastructure/config"
 	"anime-watchlist/backend/infrastructure/database"
+	"anime-watchlist/backend/infrastructure/mediaserver"
+	"anime-watchlist/backend/infrastructure/memory"
+	"anime-watchlist/backend/infrastructure/postgres"
 )
 
 func main() {
 	cfg := config.Load()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "copy-to-postgres" {
+		runCopyToPostgres(cfg)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backup" {
+		runBackup(cfg)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		runRestore(cfg, os.Args[2:])
+		return
+	}
+
 	db, err := database.New(cfg.Database.Path)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
-	watchlistRepo := database.NewWatchlistRepository(db)
-	plexRepo := database.NewPlexRepository(db.DB)
-	anilistService := application.NewAnilistService()
+	var watchlistRepo domain.WatchlistStore = database.NewWatchlistRepository(db)
+	var plexRepo domain.PlexShowStore = database.NewPlexRepository(db.DB)
+	switch {
+	case cfg.Database.Path == database.MemoryPath:
+		log.Println("Running with in-memory storage; nothing is kept after the server stops")
+		watchlistRepo = memory.NewWatchlistStore()
+		plexRepo = memory.NewPlexShowStore()
+	case cfg.Database.URL != "":
+		pg, err := postgres.New(cfg.Database.URL)
+		if err != nil {
+			log.Fatalf("Failed to initialize postgres: %v", err)
+		}
+		defer pg.Close()
+
+		log.Println("Storing the watchlist and shows in postgres")
+		log.Printf("Candidates, season mappings, jobs, schedules, sync state, quality, settings, AniList sync and caches stay in the SQLite file %s; instances sharing postgres each keep their own and run their own jobs and schedules", cfg.Database.Path)
+		watchlistRepo = postgres.NewWatchlistStore(pg)
+		plexRepo = postgres.NewPlexShowStore(pg.DB)
+	}
+	candidateRepo := database.NewMappingCandidateRepository(db.DB)
+	seasonRepo := database.NewSeasonMappingRepository(db.DB)
+	jobRepo := database.NewJobRepository(db.DB)
+	scheduleRepo := database.NewScheduleRepository(db.DB)
+	cacheRepo := database.NewAnimeCacheRepository(db.DB)
+	syncStateRepo := database.NewSyncStateRepository(db.DB)
+	qualityRepo := database.NewShowQualityRepository(db.DB)
+	settingsRepo := database.NewSettingsRepository(db.DB)
+	anilistService := application.NewAnilistService(cfg.Anilist.APIURL)
+	events := application.NewEventBus()
+
+	// Changes made from here on are queued for the connected AniList account
+	anilistSync := application.NewAnilistSyncService(anilistService, watchlistRepo, database.NewAnilistSyncRepository(db.DB), domain.AnilistOAuthConfig{
+		URL:          cfg.Anilist.OAuthURL,
+		ClientID:     cfg.Anilist.ClientID,
+		ClientSecret: cfg.Anilist.ClientSecret,
+		RedirectURL:  cfg.Anilist.RedirectURL,
+	}, events)
+	watchlistRepo = anilistSync.Track(watchlistRepo)
 	
 	plexConfig := domain.PlexConfig{
+		ServerType:  cfg.Plex.ServerType,
 		ServerURL:   cfg.Plex.ServerURL,
 		Token:       cfg.Plex.Token,
 		LibraryID:   cfg.Plex.LibraryID,
+		UserName:    cfg.Plex.UserName,
 		SyncEnabled: cfg.Plex.SyncEnabled,
+
+		WritebackEnabled: cfg.Plex.WritebackEnabled,
+		WritebackGenres:  cfg.Plex.WritebackGenres,
+		WritebackRating:  cfg.Plex.WritebackRating,
+	}
+	mediaServer, err := mediaserver.New(mediaserver.Config{
+		Type:         plexConfig.ServerType,
+		ServerURL:    plexConfig.ServerURL,
+		Token:        plexConfig.Token,
+		UserName:     plexConfig.UserName,
+		LibraryPaths: cfg.Plex.LibraryPaths,
+		Index:        database.NewLibraryFileRepository(db.DB),
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure media server: %v", err)
 	}
-	plexService := application.NewPlexService(plexConfig)
+	plexService := application.NewPlexService(plexConfig, mediaServer, cfg.Anilist.APIURL, events)
+	metadataService := application.NewMetadataService(anilistService, cacheRepo, watchlistRepo, plexRepo, seasonRepo)
+	mappingService := application.NewMappingService(plexService, anilistService, plexRepo, candidateRepo, seasonRepo, metadataService, cfg.Plex.AutoApplyThreshold)
 	
-	service := application.NewAnimeService(watchlistRepo, anilistService)
+	jobRunner := application.NewJobRunner(jobRepo, plexRepo, mappingService, events)
+	if err := jobRunner.Resume(); err != nil {
+		log.Printf("Failed to resume jobs: %v", err)
+	}
+
+	collectionService := application.NewCollectionService(mediaServer, plexRepo, seasonRepo, watchlistRepo, plexConfig.LibraryID, cfg.Plex.WatchlistCollection)
+	var afterSync *application.CollectionService
+	if cfg.Plex.SyncCollection {
+		afterSync = collectionService
+	}
+	syncService := application.NewSyncService(plexService, plexRepo, syncStateRepo, qualityRepo, events, afterSync, cfg.Schedule.PlexFullSync)
+
+	scheduler := application.NewScheduler(scheduleRepo, cfg.Schedule.Jitter)
+	addSchedule(scheduler, "plex_sync", cfg.Schedule.PlexSync, func(ctx context.Context) error {
+		result, err := syncService.Sync("")
+		if err == nil {
+			log.Printf("Scheduled %s plex sync saved %d shows (%d changed) in %s", result.Mode, result.Count, len(result.Changed), result.Duration)
+		}
+		return err
+	})
+	addSchedule(scheduler, "auto_map", cfg.Schedule.AutoMap, func(ctx context.Context) error {
+		_, err := jobRunner.StartAutoMapNew()
+		return err
+	})
+	addSchedule(scheduler, "metadata_refresh", cfg.Schedule.MetadataRefresh, func(ctx context.Context) error {
+		count, err := metadataService.Refresh(ctx)
+		log.Printf("Scheduled metadata refresh updated %d anime", count)
+		return err
+	})
+	backupService := application.NewBackupService(db, cfg.Backup.Dir, cfg.Backup.KeepDaily, cfg.Backup.KeepWeekly, cfg.Database.URL != "")
+	backupSchedule := cfg.Schedule.Backup
+	if cfg.Database.URL != "" {
+		// Snapshots would miss the watchlist and shows kept in Postgres
+		backupSchedule = ""
+	}
+	addSchedule(scheduler, "backup", backupSchedule, func(ctx context.Context) error {
+		backup, removed, err := backupService.Snapshot()
+		if backup != nil {
+			log.Printf("Scheduled backup wrote %s and removed %d old backups", backup.Name, removed)
+		}
+		return err
+	})
+	addSchedule(scheduler, "anilist_sync", cfg.Schedule.AnilistSync, func(ctx context.Context) error {
+		result, err := anilistSync.Sync()
+		if errors.Is(err, domain.ErrAnilistNotConnected) {
+			return nil
+		}
+		if err == nil {
+			log.Printf("Scheduled AniList sync pulled %d changes and pushed %d, %d still pending", len(result.Added)+len(result.Updated)+len(result.Removed), result.Pushed+result.Deleted, result.Pending)
+		}
+		return err
+	})
+	scheduler.Start()
+
+	service := application.NewAnimeService(watchlistRepo, anilistService, metadataService, events)
 	handlers := api.NewHandlers(service)
-	plexHandlers := api.NewPlexHandlers(plexService, plexRepo)
+	plexHandlers := api.NewPlexHandlers(plexService, mappingService, syncService, collectionService, plexRepo, qualityRepo, events)
+	jobHandlers := api.NewJobHandlers(jobRunner)
+	scheduleHandlers := api.NewScheduleHandlers(scheduler)
+	searchHandlers := api.NewSearchHandlers(application.NewSearchService(cacheRepo, plexRepo))
+	eventHandlers := api.NewEventHandlers(events)
+	adminHandlers := api.NewAdminHandlers(backupService)
+	transferService := application.NewTransferService(watchlistRepo, plexRepo, seasonRepo, settingsRepo, events)
+	transferHandlers := api.NewTransferHandlers(transferService, settingsRepo)
+	malHandlers := api.NewMALHandlers(application.NewMALService(anilistService, watchlistRepo, events))
+	anilistImportHandlers := api.NewAnilistImportHandlers(application.NewAnilistImportService(anilistService, watchlistRepo, events))
+	anilistSyncHandlers := api.NewAnilistSyncHandlers(anilistSync)
 
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/api/anime/watching", handlers.GetWatchlist)
 	mux.HandleFunc("/api/anime/search", handlers.SearchAnime)
+	mux.HandleFunc("/api/search", searchHandlers.Search)
 	mux.HandleFunc("/api/anime/", handlers.HandleWatchlist)
 	mux.HandleFunc("/api/watchlist/count", handlers.GetWatchlistCount)
 
 	mux.HandleFunc("/api/plex/status", plexHandlers.GetServerStatus)
 	mux.HandleFunc("/api/plex/sync", plexHandlers.SyncPlexShows)
 	mux.HandleFunc("/api/plex/shows", plexHandlers.GetShowsOnServer)
+	mux.HandleFunc("/api/plex/libraries", plexHandlers.GetLibraries)
+	mux.HandleFunc("/api/plex/episodes", plexHandlers.GetEpisodes)
+	mux.HandleFunc("/api/plex/collections/watchlist", plexHandlers.SyncWatchlistCollection)
+	mux.HandleFunc("/api/plex/writeback", plexHandlers.WriteBackMappings)
 	mux.HandleFunc("/api/plex/unmapped", plexHandlers.GetUnmappedShows)
+	mux.HandleFunc("/api/plex/ignored", plexHandlers.GetIgnoredShows)
+	mux.HandleFunc("/api/plex/ignore", plexHandlers.IgnoreShow)
 	mux.HandleFunc("/api/plex/search", plexHandlers.SearchShowsOnServer)
 	mux.HandleFunc("/api/plex/map", plexHandlers.MapShowToAnilist)
+	mux.HandleFunc("/api/plex/map/", plexHandlers.HandleShowMapping)
 	mux.HandleFunc("/api/plex/auto-map", plexHandlers.AutoMapShow)
-	mux.HandleFunc("/api/plex/bulk-auto-map", plexHandlers.BulkAutoMapShows)
+	mux.HandleFunc("/api/plex/bulk-auto-map", jobHandlers.StartBulkAutoMap)
 	mux.HandleFunc("/api/plex/check", plexHandlers.CheckShowOnServer)
+	mux.HandleFunc("/api/plex/seasons", plexHandlers.HandleSeasonMappings)
+	mux.HandleFunc("/api/plex/seasons/propose", plexHandlers.ProposeSeasonMappings)
+
+	mux.HandleFunc("/api/jobs", jobHandlers.GetJobs)
+	mux.HandleFunc("/api/jobs/", jobHandlers.HandleJob)
+	mux.HandleFunc("/api/schedules", scheduleHandlers.GetSchedules)
+	mux.HandleFunc("/api/events", eventHandlers.StreamEvents)
+	mux.HandleFunc("/api/plex/review", plexHandlers.GetReviewQueue)
+	mux.HandleFunc("/api/plex/review/", plexHandlers.ResolveReview)
+
+	mux.HandleFunc("/api/admin/backup", adminHandlers.Backup)
+	mux.HandleFunc("/api/admin/backups", adminHandlers.GetBackups)
+
+	mux.HandleFunc("/api/export", transferHandlers.Export)
+	mux.HandleFunc("/api/import", transferHandlers.Import)
+	mux.HandleFunc("/api/export/mal", malHandlers.Export)
+	mux.HandleFunc("/api/import/mal", malHandlers.Import)
+	mux.HandleFunc("/api/import/anilist", anilistImportHandlers.Import)
+	mux.HandleFunc("/api/settings", transferHandlers.HandleSettings)
+
+	mux.HandleFunc("/api/anilist/authorize", anilistSyncHandlers.Authorize)
+	mux.HandleFunc("/api/anilist/callback", anilistSyncHandlers.Callback)
+	mux.HandleFunc("/api/anilist/account", anilistSyncHandlers.HandleAccount)
+	mux.HandleFunc("/api/anilist/sync", anilistSyncHandlers.Sync)
 
 	handler := api.LoggingMiddleware()(
 		api.RequestIDMiddleware()(
@@ -70,6 +243,8 @@ func main() {
 		Addr:    cfg.Server.Host + ":" + cfg.Server.Port,
 		Handler: handler,
 	}
+	// Event streams never finish on their own, so end them when shutting down
+	server.RegisterOnShutdown(events.Close)
 
 	go func() {
 		log.Printf("Server starting on %s:%s", cfg.Server.Host, cfg.Server.Port)
@@ -83,4 +258,25 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Server shutdown failed: %v", err)
+	}
+
+	scheduler.Stop()
+	jobRunner.Shutdown()
+}
+
+// addSchedule registers a task unless its schedule is disabled. An invalid
+// schedule is logged rather than stopping the server.
+func addSchedule(scheduler *application.Scheduler, name string, spec string, run application.TaskFunc) {
+	if spec == "" {
+		log.Printf("Schedule %s is disabled", name)
+		return
+	}
+	if err := scheduler.Add(name, spec, run); err != nil {
+		log.Printf("Failed to add schedule: %v", err)
+	}
 } 
\ No newline at end of file
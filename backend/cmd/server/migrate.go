@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"anime-watchlist/backend/infrastructure/config"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  status          list migrations and whether they are applied
+  up              apply pending migrations
+  down <version>  revert migrations newer than version`
+
+// runMigrate handles "server migrate ...". The server itself applies pending
+// migrations on start, so this is mostly for status and rollbacks.
+func runMigrate(cfg *config.Config, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	db, err := database.Open(cfg.Database.Path)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := database.NewMigrator(db.DB)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("Failed to get migration status: %v", err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			down := ""
+			if !status.HasDown {
+				down = " (irreversible)"
+			}
+			fmt.Printf("%04d %-30s %s%s\n", status.Version, status.Name, applied, down)
+		}
+	case "up":
+		count, err := migrator.Up()
+		if err != nil {
+			log.Fatalf("Failed to migrate: %v", err)
+		}
+		fmt.Printf("Applied %d migrations\n", count)
+	case "down":
+		if len(args) != 2 {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			os.Exit(2)
+		}
+		target, err := strconv.Atoi(args[1])
+		if err != nil || target < 0 {
+			log.Fatalf("Invalid target version %q", args[1])
+		}
+		count, err := migrator.Down(target)
+		if err != nil {
+			log.Fatalf("Failed to revert migrations: %v", err)
+		}
+		fmt.Printf("Reverted %d migrations\n", count)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "errors"
+
 type ValidationError struct {
 	Field   string
 	Message string
@@ -12,4 +14,30 @@ func (e *ValidationError) Error() string {
 var (
 	ErrInvalidTitle     = &ValidationError{Field: "title", Message: "title is required"}
 	ErrInvalidAnilistID = &ValidationError{Field: "anilist_id", Message: "anilist_id must be positive"}
-) 
\ No newline at end of file
+)
+
+var (
+	ErrCandidateNotFound      = errors.New("mapping candidate not found")
+	ErrCandidateResolved      = errors.New("mapping candidate was already reviewed")
+	ErrShowNotFound           = errors.New("plex show not found")
+	ErrShowIDCollision        = errors.New("show ID already belongs to another media server item")
+	ErrMappingLocked          = errors.New("show mapping is locked")
+	ErrShowIgnored            = errors.New("show is ignored")
+	ErrAnimeNotFound          = errors.New("anime not found on anilist")
+	ErrNoMappingHistory       = errors.New("no mapping change to undo")
+	ErrShowNotMapped          = errors.New("show is not mapped to anilist")
+	ErrRateLimited            = errors.New("rate limited by anilist API")
+	ErrAnilistUserNotFound    = errors.New("anilist user not found or their list is private")
+	ErrAnilistUnauthorized    = errors.New("anilist rejected the access token; connect the account again")
+	ErrAnilistNotConnected    = errors.New("no anilist account is connected")
+	ErrAnilistOAuthDisabled   = errors.New("anilist login is not configured; set ANILIST_CLIENT_ID and ANILIST_CLIENT_SECRET")
+	ErrInvalidOAuthState      = errors.New("anilist login expired or did not start here; try again")
+	ErrJobNotFound            = errors.New("job not found")
+	ErrJobAlreadyRunning      = errors.New("a job of this type is already running")
+	ErrSyncInProgress         = errors.New("a plex sync is already in progress")
+	ErrCollectionsUnsupported = errors.New("media server does not support collections")
+	ErrLibraryNotSet          = errors.New("no media server library is configured; set PLEX_LIBRARY_ID")
+	ErrWritebackUnsupported   = errors.New("media server does not support metadata writeback")
+	ErrWritebackDisabled      = errors.New("metadata writeback is disabled")
+	ErrBackupPostgres         = errors.New("backups cover the SQLite database only; back up PostgreSQL with pg_dump")
+)
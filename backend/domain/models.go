@@ -10,6 +10,8 @@ type Anime struct {
 	Title         string    `json:"title" db:"title"`
 	TitleEnglish  string    `json:"title_english" db:"title_english"`
 	TitleRomaji   string    `json:"title_romaji" db:"title_romaji"`
+	TitleNative   string    `json:"title_native" db:"title_native"`
+	Synonyms      []string  `json:"synonyms,omitempty"`
 	Description   string    `json:"description" db:"description"`
 	CoverImage    string    `json:"cover_image" db:"cover_image"`
 	BannerImage   string    `json:"banner_image" db:"banner_image"`
@@ -28,10 +30,35 @@ type Anime struct {
 }
 
 type WatchlistItem struct {
-	ID        int       `json:"id" db:"id"`
-	AnilistID int       `json:"anilist_id" db:"anilist_id"`
-	AddedAt   time.Time `json:"added_at" db:"added_at"`
-	Anime     *Anime    `json:"anime,omitempty"`
+	ID          int        `json:"id" db:"id"`
+	AnilistID   int        `json:"anilist_id" db:"anilist_id"`
+	Status      string     `json:"status" db:"status"`
+	Score       float64    `json:"score" db:"score"`
+	Progress    int        `json:"progress" db:"progress"`
+	StartedAt   *time.Time `json:"started_at,omitempty" db:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	AddedAt     time.Time  `json:"added_at" db:"added_at"`
+	Anime       *Anime     `json:"anime,omitempty"`
+}
+
+// Watchlist statuses, named like AniList's list statuses. Score is out of
+// 10 with 0 meaning unscored, and Progress counts watched episodes.
+const (
+	WatchStatusCurrent   = "CURRENT"
+	WatchStatusPlanning  = "PLANNING"
+	WatchStatusCompleted = "COMPLETED"
+	WatchStatusDropped   = "DROPPED"
+	WatchStatusPaused    = "PAUSED"
+	WatchStatusRepeating = "REPEATING"
+)
+
+// ValidWatchStatus reports whether status is one of the watchlist statuses.
+func ValidWatchStatus(status string) bool {
+	switch status {
+	case WatchStatusCurrent, WatchStatusPlanning, WatchStatusCompleted, WatchStatusDropped, WatchStatusPaused, WatchStatusRepeating:
+		return true
+	}
+	return false
 }
 
 type AnimeSearchFilter struct {
@@ -54,31 +81,327 @@ func (f *AnimeSearchFilter) Validate() error {
 }
 
 type PlexShow struct {
-	ID          int       `json:"id" db:"id"`
-	PlexID      int       `json:"plex_id" db:"plex_id"`
-	Title       string    `json:"title" db:"title"`
-	AnilistID   *int      `json:"anilist_id" db:"anilist_id"`
-	Year        int       `json:"year" db:"year"`
-	EpisodeCount int      `json:"episode_count" db:"episode_count"`
-	LastUpdated time.Time `json:"last_updated" db:"last_updated"`
-	Anime       *Anime    `json:"anime,omitempty"`
+	ID            int        `json:"id" db:"id"`
+	PlexID        int        `json:"plex_id" db:"plex_id"`
+	Source        string     `json:"source" db:"source"`
+	ExternalID    string     `json:"external_id" db:"external_id"`
+	Title         string     `json:"title" db:"title"`
+	GUID          string     `json:"guid" db:"guid"`
+	AnilistID     *int       `json:"anilist_id" db:"anilist_id"`
+	MappingSource string     `json:"mapping_source" db:"mapping_source"`
+	MappingLocked bool       `json:"mapping_locked" db:"mapping_locked"`
+	Ignored       bool       `json:"ignored" db:"ignored"`
+	Year          int        `json:"year" db:"year"`
+	EpisodeCount  int        `json:"episode_count" db:"episode_count"`
+	AutoMappedAt  *time.Time `json:"auto_mapped_at,omitempty" db:"auto_mapped_at"`
+	LastUpdated   time.Time  `json:"last_updated" db:"last_updated"`
+	Anime         *Anime     `json:"anime,omitempty"`
+	Quality       *ShowQuality `json:"quality,omitempty"`
+}
+
+// ShowRef identifies a synced show by the media server it came from and the
+// server's own ID for it.
+type ShowRef struct {
+	Source     string
+	ExternalID string
+}
+
+// RefOf returns the ref of a show, which counts as from Plex when the source
+// is missing.
+func RefOf(show *PlexShow) ShowRef {
+	source := show.Source
+	if source == "" {
+		source = "plex"
+	}
+	return ShowRef{Source: source, ExternalID: show.ExternalID}
+}
+
+// SameItem reports whether a show stored with ref r is the item other refers
+// to. Shows synced from Plex before external IDs were stored have none and
+// are the Plex item their ID was taken from.
+func (r ShowRef) SameItem(other ShowRef) bool {
+	return r.Source == other.Source && (r.ExternalID == "" || r.ExternalID == other.ExternalID)
+}
+
+// PlexSeason is a season of a Plex show. SeasonNumber 0 holds specials.
+type PlexSeason struct {
+	PlexID       int    `json:"plex_id"`
+	ShowPlexID   int    `json:"show_plex_id"`
+	SeasonNumber int    `json:"season_number"`
+	Title        string `json:"title"`
+	EpisodeCount int    `json:"episode_count"`
+}
+
+// SeasonMapping links a run of episodes in a Plex season to one AniList
+// entry. A season split into several cours has one row per cour, each
+// starting at EpisodeOffset within the season.
+type SeasonMapping struct {
+	PlexID          int       `json:"plex_id" db:"plex_id"`
+	SeasonNumber    int       `json:"season_number" db:"season_number"`
+	AnilistID       int       `json:"anilist_id" db:"anilist_id"`
+	EpisodeOffset   int       `json:"episode_offset" db:"episode_offset"`
+	EpisodeCount    int       `json:"episode_count" db:"episode_count"`
+	AnilistEpisodes int       `json:"anilist_episodes" db:"anilist_episodes"`
+	Source          string    `json:"source" db:"source"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	Anime           *Anime    `json:"anime,omitempty"`
+}
+
+// ServerAvailability answers whether an AniList entry is on the server, and
+// which seasons and episodes of the Plex show cover it.
+type ServerAvailability struct {
+	OnServer   bool            `json:"on_server"`
+	Show       *PlexShow       `json:"show"`
+	Seasons    []SeasonMapping `json:"seasons"`
+	WatchState *WatchState     `json:"watch_state,omitempty"`
+}
+
+// Mapping sources record how a Plex show got its AniList ID. Manual
+// mappings are locked and never replaced by auto-map or sync.
+const (
+	MappingSourceManual = "manual"
+	MappingSourceAuto   = "auto"
+	MappingSourceGUID   = "guid"
+)
+
+type SyncResult struct {
+	Count     int          `json:"count"`
+	Mode      string       `json:"mode"`
+	Since     *time.Time   `json:"since,omitempty"`
+	Changed   []SyncChange `json:"changed"`
+	StartedAt time.Time    `json:"started_at"`
+	Duration  string       `json:"duration"`
+}
+
+// Sync modes. A full sync lists the whole library; an incremental sync only
+// asks the server for shows added or updated since the last sync.
+const (
+	SyncModeFull        = "full"
+	SyncModeIncremental = "incremental"
+)
+
+const (
+	SyncChangeAdded   = "added"
+	SyncChangeUpdated = "updated"
+)
+
+// SyncChange is a show that a sync added or whose details changed.
+type SyncChange struct {
+	PlexID int    `json:"plex_id"`
+	Title  string `json:"title"`
+	Change string `json:"change"`
+}
+
+// ShowQuality summarizes the media files of a show's episodes. Languages are
+// ISO 639-2 codes such as "jpn" and "eng".
+type ShowQuality struct {
+	PlexID            int       `json:"plex_id" db:"plex_id"`
+	Resolutions       []string  `json:"resolutions" db:"resolutions"`
+	MaxHeight         int       `json:"max_height" db:"max_height"`
+	VideoCodecs       []string  `json:"video_codecs" db:"video_codecs"`
+	AudioLanguages    []string  `json:"audio_languages" db:"audio_languages"`
+	SubtitleLanguages []string  `json:"subtitle_languages" db:"subtitle_languages"`
+	EpisodesAnalyzed  int       `json:"episodes_analyzed" db:"episodes_analyzed"`
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// QualityFilter narrows a show listing by media quality. Zero values match
+// everything.
+type QualityFilter struct {
+	SubtitleLanguage string
+	AudioLanguage    string
+	MinHeight        int
+	VideoCodec       string
+}
+
+func (f QualityFilter) IsEmpty() bool {
+	return f == QualityFilter{}
+}
+
+// SyncState records when a library was last synced successfully.
+type SyncState struct {
+	Source         string     `json:"source" db:"source"`
+	LibraryID      string     `json:"library_id" db:"library_id"`
+	LastSyncAt     *time.Time `json:"last_sync_at" db:"last_sync_at"`
+	LastFullSyncAt *time.Time `json:"last_full_sync_at" db:"last_full_sync_at"`
+}
+
+// ScheduleState is the persisted record of a scheduled task.
+type ScheduleState struct {
+	Name       string     `json:"name" db:"name"`
+	Spec       string     `json:"spec" db:"spec"`
+	LastRunAt  *time.Time `json:"last_run_at" db:"last_run_at"`
+	NextRunAt  *time.Time `json:"next_run_at" db:"next_run_at"`
+	LastStatus string     `json:"last_status" db:"last_status"`
+	LastError  string     `json:"last_error,omitempty" db:"last_error"`
+	DurationMS int64      `json:"last_duration_ms" db:"last_duration_ms"`
+	Running    bool       `json:"running"`
 }
 
+const (
+	ScheduleStatusSuccess = "success"
+	ScheduleStatusFailed  = "failed"
+	ScheduleStatusSkipped = "skipped"
+)
+
 type PlexConfig struct {
+	ServerType  string `json:"server_type"`
 	ServerURL   string `json:"server_url"`
 	Token       string `json:"token"`
-	LibraryID   int    `json:"library_id"`
+	LibraryID   string `json:"library_id"`
+	UserName    string `json:"user_name"`
 	SyncEnabled bool   `json:"sync_enabled"`
+	// Writeback adds anilist-<id> labels to mapped shows, optionally with
+	// genres and rating from AniList.
+	WritebackEnabled bool `json:"writeback_enabled"`
+	WritebackGenres  bool `json:"writeback_genres"`
+	WritebackRating  bool `json:"writeback_rating"`
+}
+
+// MediaLibrary is a library on the media server, such as a TV section.
+type MediaLibrary struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Type  string `json:"type"`
+}
+
+// MediaEpisode is an episode of a show on the media server.
+type MediaEpisode struct {
+	ID            string `json:"id"`
+	SeasonNumber  int    `json:"season_number"`
+	EpisodeNumber int    `json:"episode_number"`
+	Title         string `json:"title"`
+	Watched       bool   `json:"watched"`
+}
+
+type WatchState struct {
+	WatchedEpisodes int `json:"watched_episodes"`
+	TotalEpisodes   int `json:"total_episodes"`
+}
+
+const (
+	MappingActionMap   = "map"
+	MappingActionUnmap = "unmap"
+	MappingActionUndo  = "undo"
+)
+
+// MappingHistoryEntry records one change to a show's AniList mapping so it
+// can be audited and undone.
+type MappingHistoryEntry struct {
+	ID           int       `json:"id" db:"id"`
+	PlexID       int       `json:"plex_id" db:"plex_id"`
+	Action       string    `json:"action" db:"action"`
+	OldAnilistID *int      `json:"old_anilist_id" db:"old_anilist_id"`
+	NewAnilistID *int      `json:"new_anilist_id" db:"new_anilist_id"`
+	OldSource    string    `json:"old_source" db:"old_source"`
+	NewSource    string    `json:"new_source" db:"new_source"`
+	Actor        string    `json:"actor" db:"actor"`
+	Undone       bool      `json:"undone" db:"undone"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+const (
+	CandidateStatusPending  = "pending"
+	CandidateStatusAccepted = "accepted"
+	CandidateStatusRejected = "rejected"
+)
+
+// MappingCandidate is an auto-map result waiting for (or past) review. The
+// top candidate is stored in AnilistID; Alternatives holds the runners-up.
+type MappingCandidate struct {
+	PlexID       int            `json:"plex_id" db:"plex_id"`
+	AnilistID    int            `json:"anilist_id" db:"anilist_id"`
+	Score        float64        `json:"score" db:"score"`
+	Strategy     string         `json:"strategy" db:"strategy"`
+	Alternatives []AnilistMatch `json:"alternatives" db:"alternatives"`
+	Status       string         `json:"status" db:"status"`
+	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at" db:"updated_at"`
+	Show         *PlexShow      `json:"show,omitempty"`
+}
+
+const (
+	AutoMapOutcomeMapped  = "mapped"
+	AutoMapOutcomeQueued  = "queued"
+	AutoMapOutcomeNoMatch = "no_match"
+	AutoMapOutcomeError   = "error"
+)
+
+const (
+	JobTypeBulkAutoMap = "bulk_auto_map"
+	JobTypeAutoMapNew  = "auto_map_new"
+)
+
+const (
+	JobStatusPending   = "pending"
+	JobStatusRunning   = "running"
+	JobStatusPaused    = "paused"
+	JobStatusCompleted = "completed"
+	JobStatusCancelled = "cancelled"
+	JobStatusFailed    = "failed"
+)
+
+// Job is a long-running background task. Checkpoint holds the last Plex ID
+// processed so an interrupted job can pick up where it left off.
+type Job struct {
+	ID          string     `json:"id" db:"id"`
+	Type        string     `json:"type" db:"type"`
+	Status      string     `json:"status" db:"status"`
+	Limit       int        `json:"limit" db:"max_items"`
+	Total       int        `json:"total" db:"total"`
+	Processed   int        `json:"processed" db:"processed"`
+	Mapped      int        `json:"mapped" db:"mapped"`
+	Queued      int        `json:"queued" db:"queued"`
+	Failed      int        `json:"failed" db:"failed"`
+	Checkpoint  int        `json:"checkpoint" db:"checkpoint"`
+	Error       string     `json:"error,omitempty" db:"error"`
+	ResumeAfter *time.Time `json:"resume_after,omitempty" db:"resume_after"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+	Items       []JobItem  `json:"items,omitempty"`
+}
+
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusCancelled || j.Status == JobStatusFailed
+}
+
+// JobItem is the outcome of one show within a job.
+type JobItem struct {
+	JobID     string    `json:"job_id" db:"job_id"`
+	PlexID    int       `json:"plex_id" db:"plex_id"`
+	Title     string    `json:"title" db:"title"`
+	Outcome   string    `json:"outcome" db:"outcome"`
+	AnilistID *int      `json:"anilist_id" db:"anilist_id"`
+	Score     float64   `json:"score" db:"score"`
+	Message   string    `json:"message,omitempty" db:"message"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type AutoMapResult struct {
+	Show       *PlexShow      `json:"show"`
+	Outcome    string         `json:"outcome"`
+	Candidates []AnilistMatch `json:"candidates"`
 }
 
 type ServerStatus struct {
+	ServerType       string `json:"server_type"`
 	ShowsOnServer    int `json:"shows_on_server"`
 	MappedToAnilist  int `json:"mapped_to_anilist"`
 	UnmappedShows    int `json:"unmapped_shows"`
+	IgnoredShows     int `json:"ignored_shows"`
 	WatchlistShows   int `json:"watchlist_shows"`
 	MissingFromServer int `json:"missing_from_server"`
 }
 
+// AnilistMatch is a scored AniList candidate for a Plex show. Strategy names
+// the search that produced the best score for this candidate.
+type AnilistMatch struct {
+	Anime    Anime   `json:"anime"`
+	Score    float64 `json:"score"`
+	Strategy string  `json:"strategy"`
+}
+
 type AnilistAnime struct {
 	ID          int     `json:"id"`
 	Title       Title   `json:"title"`
@@ -92,6 +415,7 @@ type AnilistAnime struct {
 	Season      string  `json:"season"`
 	SeasonYear  int     `json:"seasonYear"`
 	Genres      []string `json:"genres"`
+	Synonyms    []string `json:"synonyms"`
 	AverageScore float64 `json:"averageScore"`
 	Popularity   int     `json:"popularity"`
 }
@@ -99,6 +423,7 @@ type AnilistAnime struct {
 type Title struct {
 	Romaji  string `json:"romaji"`
 	English string `json:"english"`
+	Native  string `json:"native"`
 }
 
 type Cover struct {
@@ -124,6 +449,8 @@ func (a *AnilistAnime) ToDomain() Anime {
 		Title:        a.Title.Romaji,
 		TitleEnglish: a.Title.English,
 		TitleRomaji:  a.Title.Romaji,
+		TitleNative:  a.Title.Native,
+		Synonyms:     a.Synonyms,
 		Description:  a.Description,
 		CoverImage:   a.CoverImage.Large,
 		BannerImage:  a.BannerImage,
@@ -137,4 +464,383 @@ func (a *AnilistAnime) ToDomain() Anime {
 		Score:        a.AverageScore,
 		Popularity:   a.Popularity,
 	}
-} 
\ No newline at end of file
+}
+
+// Event is a notification streamed to clients over /api/events.
+type Event struct {
+	ID   uint64      `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+const (
+	EventSyncStarted      = "sync.started"
+	EventSyncProgress     = "sync.progress"
+	EventSyncFinished     = "sync.finished"
+	EventSyncFailed       = "sync.failed"
+	EventShowMapped       = "show.mapped"
+	EventShowUnmapped     = "show.unmapped"
+	EventJobProgress      = "job.progress"
+	EventWatchlistChanged = "watchlist.changed"
+)
+
+// SyncProgress is the payload of sync progress events.
+type SyncProgress struct {
+	Phase     string `json:"phase"`
+	Processed int    `json:"processed"`
+	Total     int    `json:"total"`
+}
+
+// WatchlistChange is the payload of watchlist changed events.
+type WatchlistChange struct {
+	Action    string `json:"action"`
+	AnilistID int    `json:"anilist_id"`
+}
+
+// ReleaseInfo is what can be read from a release-style file name such as
+// "[Group] Title S2 - 05 [1080p][ABCD1234].mkv".
+type ReleaseInfo struct {
+	Group      string `json:"group,omitempty"`
+	Title      string `json:"title"`
+	Season     int    `json:"season"`
+	Episode    int    `json:"episode"`
+	Resolution string `json:"resolution,omitempty"`
+	CRC        string `json:"crc,omitempty"`
+	Year       int    `json:"year,omitempty"`
+}
+
+// LibraryFile is a video file found by the filesystem library scanner.
+type LibraryFile struct {
+	Path    string    `json:"path" db:"path"`
+	Root    string    `json:"root" db:"root"`
+	ModTime time.Time `json:"mod_time" db:"mod_time"`
+	Size    int64     `json:"size" db:"size"`
+	ShowKey string    `json:"show_key" db:"show_key"`
+	ReleaseInfo
+}
+
+// CollectionSyncResult describes a sync of the watchlist collection on the
+// media server. Running it again with nothing changed adds and removes none.
+type CollectionSyncResult struct {
+	Collection string `json:"collection"`
+	Total      int    `json:"total"`
+	Added      int    `json:"added"`
+	Removed    int    `json:"removed"`
+}
+
+// ItemMetadata is the writable metadata of a media server item.
+type ItemMetadata struct {
+	Labels []string `json:"labels"`
+	Genres []string `json:"genres"`
+	Rating float64  `json:"rating"`
+}
+
+// MetadataUpdate is a set of tag changes to apply to a media server item.
+type MetadataUpdate struct {
+	AddLabels    []string `json:"add_labels,omitempty"`
+	RemoveLabels []string `json:"remove_labels,omitempty"`
+	AddGenres    []string `json:"add_genres,omitempty"`
+	RemoveGenres []string `json:"remove_genres,omitempty"`
+	Rating       *float64 `json:"rating,omitempty"`
+}
+
+func (u MetadataUpdate) IsEmpty() bool {
+	return len(u.AddLabels) == 0 && len(u.RemoveLabels) == 0 &&
+		len(u.AddGenres) == 0 && len(u.RemoveGenres) == 0 && u.Rating == nil
+}
+
+// WritebackResult reports the metadata changes written, or in a dry run
+// that would be written, for one show.
+type WritebackResult struct {
+	PlexID    int            `json:"plex_id"`
+	Title     string         `json:"title"`
+	AnilistID int            `json:"anilist_id"`
+	Changes   MetadataUpdate `json:"changes"`
+	Applied   bool           `json:"applied"`
+	Error     string         `json:"error,omitempty"`
+}
+
+const (
+	SearchResultShow  = "show"
+	SearchResultAnime = "anime"
+)
+
+// SearchResult is one hit of the local search. Shows on the server and
+// cached AniList entries that are not on it come back in one list, best
+// match first. Highlight wraps matched words in <mark> tags.
+type SearchResult struct {
+	Kind      string  `json:"kind"`
+	PlexID    int     `json:"plex_id,omitempty"`
+	AnilistID *int    `json:"anilist_id,omitempty"`
+	Title     string  `json:"title"`
+	Highlight string  `json:"highlight"`
+	Score     float64 `json:"score"`
+}
+
+// BackupInfo describes a database backup file in the backup directory.
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportVersion is the version of the export document this release writes.
+// MediaLink pairs an AniList entry with its MyAnimeList ID, which is 0 when
+// AniList does not know it, along with the details list exports need.
+type MediaLink struct {
+	AnilistID int    `json:"anilist_id"`
+	MalID     int    `json:"mal_id"`
+	Title     string `json:"title"`
+	Format    string `json:"format"`
+	Episodes  int    `json:"episodes"`
+}
+
+// WatchlistUpdate changes some fields of a watchlist entry; nil fields are
+// left as they are. Dates are YYYY-MM-DD, or empty to clear them.
+type WatchlistUpdate struct {
+	Status      *string  `json:"status"`
+	Score       *float64 `json:"score"`
+	Progress    *int     `json:"progress"`
+	StartedAt   *string  `json:"started_at"`
+	CompletedAt *string  `json:"completed_at"`
+}
+
+// AnilistListEntry is one entry of an AniList user's anime list, with the
+// score out of 10. ListEntryID is AniList's ID of the entry itself.
+type AnilistListEntry struct {
+	ListEntryID int
+	MediaID     int
+	Title       string
+	Status      string
+	Score       float64
+	Progress    int
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	UpdatedAt   time.Time
+}
+
+// How list imports treat anime that are already on the watchlist.
+const (
+	// ListMergeKeep leaves the local entry as it is.
+	ListMergeKeep = "keep"
+	// ListMergeOverwrite replaces the local state with the imported one.
+	ListMergeOverwrite = "overwrite"
+	// ListMergeFill keeps the local status, fills in a score and dates that
+	// are not set locally, and keeps the higher progress.
+	ListMergeFill = "fill"
+)
+
+// ListImportResult reports an import of a list from another site. Updated
+// entries were already on the watchlist and changed by the merge strategy;
+// conflicts were left as they were although the import differs.
+type ListImportResult struct {
+	Source     string            `json:"source"`
+	Strategy   string            `json:"strategy"`
+	DryRun     bool              `json:"dry_run"`
+	Total      int               `json:"total"`
+	Added      []ListImportEntry `json:"added"`
+	Updated    []ListImportEntry `json:"updated"`
+	Unchanged  int               `json:"unchanged"`
+	Unresolved []ListImportEntry `json:"unresolved"`
+	Conflicts  []ListImportEntry `json:"conflicts"`
+}
+
+// ListImportEntry is one imported entry. SourceID is its ID on the other
+// site; AnilistID is 0 when it could not be resolved.
+type ListImportEntry struct {
+	SourceID  int    `json:"source_id"`
+	AnilistID int    `json:"anilist_id,omitempty"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Imports accept documents up to this version.
+const ExportVersion = 1
+
+const (
+	ImportModeMerge   = "merge"
+	ImportModeReplace = "replace"
+)
+
+// ExportDocument is a portable copy of all user data, for moving between
+// instances.
+type ExportDocument struct {
+	Version    int                     `json:"version"`
+	ExportedAt time.Time               `json:"exported_at"`
+	Watchlist  []ExportedWatchlistItem `json:"watchlist"`
+	Mappings   []ExportedMapping       `json:"mappings"`
+	Settings   map[string]string       `json:"settings"`
+}
+
+// ExportedWatchlistItem is one watchlist entry. Documents written before
+// entries had a status leave it out, and those entries import as being
+// watched.
+type ExportedWatchlistItem struct {
+	AnilistID   int        `json:"anilist_id"`
+	Status      string     `json:"status,omitempty"`
+	Score       float64    `json:"score,omitempty"`
+	Progress    int        `json:"progress,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	AddedAt     time.Time  `json:"added_at"`
+}
+
+// ExportedMapping is the mapping of one show. Shows are identified by GUID,
+// or by title and year when the GUID does not match, since plex_id only
+// means something on the server it came from.
+type ExportedMapping struct {
+	Title         string                  `json:"title"`
+	Year          int                     `json:"year,omitempty"`
+	GUID          string                  `json:"guid,omitempty"`
+	AnilistID     *int                    `json:"anilist_id,omitempty"`
+	MappingSource string                  `json:"mapping_source,omitempty"`
+	Locked        bool                    `json:"locked,omitempty"`
+	Ignored       bool                    `json:"ignored,omitempty"`
+	Seasons       []ExportedSeasonMapping `json:"seasons,omitempty"`
+}
+
+type ExportedSeasonMapping struct {
+	SeasonNumber    int    `json:"season_number"`
+	AnilistID       int    `json:"anilist_id"`
+	EpisodeOffset   int    `json:"episode_offset"`
+	EpisodeCount    int    `json:"episode_count"`
+	AnilistEpisodes int    `json:"anilist_episodes"`
+	Source          string `json:"source"`
+}
+
+// ImportResult describes what an import changed, or in a dry run would
+// change.
+type ImportResult struct {
+	Mode      string              `json:"mode"`
+	DryRun    bool                `json:"dry_run"`
+	Watchlist ImportWatchlistDiff `json:"watchlist"`
+	Mappings  ImportMappingDiff   `json:"mappings"`
+	Settings  ImportSettingsDiff  `json:"settings"`
+	// Applied counts the watchlist entries, shows and settings written. When
+	// a write fails, Error describes it and the import stopped partway.
+	Applied int    `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ImportWatchlistDiff lists AniList IDs. Updated entries had a different
+// status, score, progress or dates (replace only).
+type ImportWatchlistDiff struct {
+	Added     []int `json:"added"`
+	Updated   []int `json:"updated"`
+	Removed   []int `json:"removed"`
+	Unchanged int   `json:"unchanged"`
+}
+
+// ImportMappingDiff lists changed shows, shows whose local mapping was kept
+// over a different imported one (merge only), and imported shows that are
+// not on this server.
+type ImportMappingDiff struct {
+	Changed   []ImportMappingChange `json:"changed"`
+	Conflicts []ImportMappingChange `json:"conflicts"`
+	Unmatched []string              `json:"unmatched"`
+	Unchanged int                   `json:"unchanged"`
+}
+
+type ImportMappingChange struct {
+	PlexID       int    `json:"plex_id"`
+	Title        string `json:"title"`
+	OldAnilistID *int   `json:"old_anilist_id"`
+	NewAnilistID *int   `json:"new_anilist_id"`
+	OldIgnored   bool   `json:"old_ignored"`
+	NewIgnored   bool   `json:"new_ignored"`
+	Seasons      int    `json:"seasons"`
+}
+
+type ImportSettingsDiff struct {
+	Set     []string `json:"set"`
+	Removed []string `json:"removed"`
+}
+
+// AnilistAccount is the AniList account the watchlist syncs with.
+type AnilistAccount struct {
+	UserID      int        `json:"user_id"`
+	UserName    string     `json:"user_name"`
+	AccessToken string     `json:"-"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	ConnectedAt time.Time  `json:"connected_at"`
+	LastSyncAt  *time.Time `json:"last_sync_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// AnilistOAuthConfig is the AniList API client accounts are connected
+// through. URL is the OAuth base holding the authorize and token endpoints.
+type AnilistOAuthConfig struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// AnilistAccountStatus describes the AniList connection. Configured is false
+// until an OAuth client is set up.
+type AnilistAccountStatus struct {
+	Configured bool            `json:"configured"`
+	Connected  bool            `json:"connected"`
+	Account    *AnilistAccount `json:"account,omitempty"`
+	Pending    int             `json:"pending"`
+}
+
+// Watchlist fields that sync with AniList, as named in outbox entries.
+const (
+	SyncFieldStatus      = "status"
+	SyncFieldScore       = "score"
+	SyncFieldProgress    = "progress"
+	SyncFieldStartedAt   = "started_at"
+	SyncFieldCompletedAt = "completed_at"
+)
+
+// SyncFields lists every synced field.
+var SyncFields = []string{SyncFieldStatus, SyncFieldScore, SyncFieldProgress, SyncFieldStartedAt, SyncFieldCompletedAt}
+
+const (
+	OutboxActionSave   = "save"
+	OutboxActionDelete = "delete"
+)
+
+// OutboxEntry is a local watchlist change waiting to be pushed to AniList.
+// Fields lists what a save changed; its values are read from the watchlist
+// when it is pushed.
+type OutboxEntry struct {
+	ID        int64     `json:"id"`
+	AnilistID int       `json:"anilist_id"`
+	Action    string    `json:"action"`
+	Fields    []string  `json:"fields"`
+	ChangedAt time.Time `json:"changed_at"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Winners of a sync conflict.
+const (
+	SyncWinnerLocal   = "local"
+	SyncWinnerAnilist = "anilist"
+)
+
+// AnilistSyncResult reports a sync. Added, Updated and Removed are AniList
+// IDs changed locally by pulled changes; Pushed and Deleted count entries
+// sent to AniList. Pending outbox entries are left for the next sync.
+type AnilistSyncResult struct {
+	Added     []int                 `json:"added"`
+	Updated   []int                 `json:"updated"`
+	Removed   []int                 `json:"removed"`
+	Pushed    int                   `json:"pushed"`
+	Deleted   int                   `json:"deleted"`
+	Pending   int                   `json:"pending"`
+	Conflicts []AnilistSyncConflict `json:"conflicts"`
+}
+
+// AnilistSyncConflict is a field changed both here and on AniList since the
+// last sync. The newer change wins. Field "entry" means one side removed
+// the entry while the other changed it.
+type AnilistSyncConflict struct {
+	AnilistID int    `json:"anilist_id"`
+	Field     string `json:"field"`
+	Winner    string `json:"winner"`
+}
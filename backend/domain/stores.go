@@ -0,0 +1,61 @@
+package domain
+
+import "time"
+
+// WatchlistStore persists the AniList IDs on the watchlist.
+type WatchlistStore interface {
+	GetWatchlist() ([]WatchlistItem, error)
+	AddToWatchlist(anilistID int) error
+	// AddToWatchlistAt adds an entry with a given date, for imports.
+	AddToWatchlistAt(anilistID int, addedAt time.Time) error
+	// SaveWatchlistItem adds an entry with its status, score, progress and
+	// dates, or updates them when the anime is already on the watchlist. A
+	// zero AddedAt means now; an existing entry keeps its date.
+	SaveWatchlistItem(item *WatchlistItem) error
+	// RemoveFromWatchlist fails with "anime not in watchlist" when the ID is
+	// not on the watchlist.
+	RemoveFromWatchlist(anilistID int) error
+	IsInWatchlist(anilistID int) (bool, error)
+	GetWatchlistCount() (int, error)
+}
+
+// PlexShowStore persists shows synced from the media server along with their
+// AniList mappings and the history of mapping changes.
+type PlexShowStore interface {
+	// UpsertPlexShow inserts or refreshes a synced show. An incoming AniList
+	// ID never replaces a locked mapping, and a missing one never clears an
+	// existing mapping.
+	UpsertPlexShow(show *PlexShow) error
+	// UpsertPlexShows upserts a batch of shows atomically: either all of them
+	// are saved or none are.
+	UpsertPlexShows(shows []PlexShow) error
+	// GetShowRefs returns which media server item each stored show ID
+	// belongs to. Upserts fail with ErrShowIDCollision rather than save a show
+	// under the ID of a different item.
+	GetShowRefs() (map[int]ShowRef, error)
+	// GetPlexShowByPlexID fails with ErrShowNotFound for unknown shows.
+	GetPlexShowByPlexID(plexID int) (*PlexShow, error)
+	GetAllPlexShows() ([]PlexShow, error)
+	GetUnmappedShows() ([]PlexShow, error)
+	GetIgnoredShows() ([]PlexShow, error)
+	SearchShowsOnServer(searchTerm string) ([]PlexShow, error)
+	// GetMappedAnilistIDs returns the distinct AniList IDs of mapped shows
+	// that are not ignored. Season mappings are stored separately.
+	GetMappedAnilistIDs() ([]int, error)
+
+	GetShowsOnServer() (int, error)
+	GetMappedShowsCount() (int, error)
+	GetUnmappedShowsCount() (int, error)
+	GetIgnoredShowsCount() (int, error)
+
+	// UpdateShowMapping sets a show's AniList ID. Manual mappings lock the
+	// show; other sources fail with ErrMappingLocked once it is locked.
+	UpdateShowMapping(plexID int, anilistID int, source string, actor string) error
+	ClearShowMapping(plexID int, actor string) error
+	// UndoLastMapping reverts the newest change not undone yet, failing with
+	// ErrNoMappingHistory when there is none.
+	UndoLastMapping(plexID int, actor string) (*MappingHistoryEntry, error)
+	GetMappingHistory(plexID int) ([]MappingHistoryEntry, error)
+	MarkAutoMapped(plexID int) error
+	SetShowIgnored(plexID int, ignored bool) error
+}
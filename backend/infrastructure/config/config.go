@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,6 +12,9 @@ type Config struct {
 	Database DatabaseConfig
 	CORS     CORSConfig
 	Plex     PlexConfig
+	Schedule ScheduleConfig
+	Backup   BackupConfig
+	Anilist  AnilistConfig
 }
 
 type ServerConfig struct {
@@ -17,8 +22,12 @@ type ServerConfig struct {
 	Port string
 }
 
+// DatabaseConfig locates the SQLite database. When URL is set, the watchlist
+// and synced shows are kept in that PostgreSQL database instead; everything
+// else stays in SQLite at Path.
 type DatabaseConfig struct {
 	Path string
+	URL  string
 }
 
 type CORSConfig struct {
@@ -27,14 +36,72 @@ type CORSConfig struct {
 	AllowedHeaders []string
 }
 
+// PlexConfig configures the media server shows are synced from. Despite the
+// name it covers Jellyfin and Emby too; the PLEX_* variables still work as
+// fallbacks for the MEDIA_SERVER_* ones.
 type PlexConfig struct {
-	ServerURL   string
-	Token       string
-	LibraryID   int
-	SyncEnabled bool
+	ServerType         string
+	ServerURL          string
+	Token              string
+	LibraryID          string
+	UserName           string
+	LibraryPaths       []string
+	SyncEnabled        bool
+	AutoApplyThreshold float64
+	// WatchlistCollection names the collection the watchlist is pushed to;
+	// SyncCollection pushes it after every library sync.
+	WatchlistCollection string
+	SyncCollection      bool
+	// Writeback labels mapped shows with anilist-<id> on the server,
+	// optionally also setting genres and rating from AniList.
+	WritebackEnabled bool
+	WritebackGenres  bool
+	WritebackRating  bool
+}
+
+// BackupConfig sets where database snapshots go and how many are kept: the
+// newest of each of the last KeepDaily days and KeepWeekly weeks.
+type BackupConfig struct {
+	Dir        string
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// AnilistConfig locates AniList and the OAuth client used to connect an
+// account for two-way sync. Sync stays off until ClientID and ClientSecret
+// are set. The URLs only change to point at a stand-in for testing.
+type AnilistConfig struct {
+	APIURL       string
+	OAuthURL     string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// ScheduleConfig holds the interval or cron expression of each background
+// task. An empty spec disables the task.
+type ScheduleConfig struct {
+	PlexSync string
+	// PlexFullSync is how often a scheduled sync lists the whole library
+	// instead of only recently changed shows. Zero always syncs fully.
+	PlexFullSync    time.Duration
+	AutoMap         string
+	MetadataRefresh string
+	Backup          string
+	AnilistSync     string
+	Jitter          time.Duration
 }
 
 func Load() *Config {
+	syncEnabled := getEnvAsBool("PLEX_SYNC_ENABLED", false)
+
+	// Plex tasks default to off unless sync is enabled
+	plexSyncDefault, autoMapDefault := "", ""
+	if syncEnabled {
+		plexSyncDefault = "0 */6 * * *"
+		autoMapDefault = "30 */6 * * *"
+	}
+
 	return &Config{
 		Server: ServerConfig{
 			Host: getEnv("HOST", "0.0.0.0"),
@@ -42,6 +109,7 @@ func Load() *Config {
 		},
 		Database: DatabaseConfig{
 			Path: getEnv("DATABASE_PATH", "./data/anime.db"),
+			URL:  getEnv("DATABASE_URL", ""),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: []string{"*"},
@@ -49,10 +117,40 @@ func Load() *Config {
 			AllowedHeaders: []string{"Content-Type", "Authorization"},
 		},
 		Plex: PlexConfig{
-			ServerURL:   getEnv("PLEX_SERVER_URL", ""),
-			Token:       getEnv("PLEX_TOKEN", ""),
-			LibraryID:   getEnvAsInt("PLEX_LIBRARY_ID", 0),
-			SyncEnabled: getEnvAsBool("PLEX_SYNC_ENABLED", false),
+			ServerType:          strings.ToLower(getEnv("MEDIA_SERVER_TYPE", "plex")),
+			ServerURL:           getEnv("MEDIA_SERVER_URL", getEnv("PLEX_SERVER_URL", "")),
+			Token:               getEnv("MEDIA_SERVER_TOKEN", getEnv("PLEX_TOKEN", "")),
+			LibraryID:           getEnv("MEDIA_SERVER_LIBRARY_ID", getEnv("PLEX_LIBRARY_ID", "")),
+			UserName:            getEnv("MEDIA_SERVER_USER", ""),
+			LibraryPaths:        getEnvAsList("LIBRARY_PATHS"),
+			SyncEnabled:         syncEnabled,
+			AutoApplyThreshold:  getEnvAsFloat("PLEX_AUTO_APPLY_THRESHOLD", 0.85),
+			WatchlistCollection: getEnv("PLEX_WATCHLIST_COLLECTION", "Watchlist"),
+			SyncCollection:      getEnvAsBool("PLEX_SYNC_COLLECTION", false),
+			WritebackEnabled:    getEnvAsBool("PLEX_WRITEBACK_ENABLED", false),
+			WritebackGenres:     getEnvAsBool("PLEX_WRITEBACK_GENRES", false),
+			WritebackRating:     getEnvAsBool("PLEX_WRITEBACK_RATING", false),
+		},
+		Schedule: ScheduleConfig{
+			PlexSync:        getScheduleSpec("SCHEDULE_PLEX_SYNC", plexSyncDefault),
+			PlexFullSync:    getEnvAsDuration("SCHEDULE_PLEX_FULL_SYNC", 24*time.Hour),
+			AutoMap:         getScheduleSpec("SCHEDULE_AUTO_MAP", autoMapDefault),
+			MetadataRefresh: getScheduleSpec("SCHEDULE_METADATA_REFRESH", "24h"),
+			Backup:          getScheduleSpec("SCHEDULE_BACKUP", "0 3 * * *"),
+			AnilistSync:     getScheduleSpec("SCHEDULE_ANILIST_SYNC", "15m"),
+			Jitter:          getEnvAsDuration("SCHEDULE_JITTER", 5*time.Minute),
+		},
+		Backup: BackupConfig{
+			Dir:        getEnv("BACKUP_DIR", "./data/backups"),
+			KeepDaily:  getEnvAsInt("BACKUP_KEEP_DAILY", 7),
+			KeepWeekly: getEnvAsInt("BACKUP_KEEP_WEEKLY", 4),
+		},
+		Anilist: AnilistConfig{
+			APIURL:       getEnv("ANILIST_API_URL", "https://graphql.anilist.co"),
+			OAuthURL:     strings.TrimRight(getEnv("ANILIST_OAUTH_URL", "https://anilist.co/api/v2/oauth"), "/"),
+			ClientID:     getEnv("ANILIST_CLIENT_ID", ""),
+			ClientSecret: getEnv("ANILIST_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv("ANILIST_REDIRECT_URL", "http://localhost:8080/api/anilist/callback"),
 		},
 	}
 }
@@ -80,4 +178,50 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 		}
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+// getScheduleSpec returns the schedule for a task, or "" if it is set to off.
+func getScheduleSpec(key, defaultValue string) string {
+	spec := strings.TrimSpace(getEnv(key, defaultValue))
+	if strings.EqualFold(spec, "off") {
+		return ""
+	}
+	return spec
+}
+
+// getEnvAsList splits a list of paths on the OS path list separator, as in
+// PATH, or on commas.
+func getEnvAsList(key string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == os.PathListSeparator || r == ','
+	}) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
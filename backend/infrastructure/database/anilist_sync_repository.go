@@ -0,0 +1,254 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+// AnilistSyncRepository stores the connected AniList account, the AniList
+// state of synced entries and the outbox of changes to push.
+type AnilistSyncRepository struct {
+	db *sql.DB
+}
+
+func NewAnilistSyncRepository(db *sql.DB) *AnilistSyncRepository {
+	return &AnilistSyncRepository{db: db}
+}
+
+// GetAccount returns nil when no account is connected.
+func (r *AnilistSyncRepository) GetAccount() (*domain.AnilistAccount, error) {
+	query := `
+		SELECT user_id, user_name, access_token, expires_at, connected_at, last_sync_at, last_error
+		FROM anilist_account
+		WHERE id = 1
+	`
+
+	var account domain.AnilistAccount
+	err := r.db.QueryRow(query).Scan(&account.UserID, &account.UserName, &account.AccessToken,
+		&account.ExpiresAt, &account.ConnectedAt, &account.LastSyncAt, &account.LastError)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get anilist account: %w", err)
+	}
+
+	return &account, nil
+}
+
+// SaveAccount connects an account, replacing the connected one. Sync state
+// belongs to the previous account, so it is cleared when the user changes.
+func (r *AnilistSyncRepository) SaveAccount(account *domain.AnilistAccount) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previousUser int
+	err = tx.QueryRow(`SELECT user_id FROM anilist_account WHERE id = 1`).Scan(&previousUser)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get anilist account: %w", err)
+	}
+	if err == nil && previousUser != account.UserID {
+		if err := clearSyncState(tx); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO anilist_account (id, user_id, user_name, access_token, expires_at, connected_at, last_sync_at, last_error)
+		VALUES (1, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			user_id = excluded.user_id,
+			user_name = excluded.user_name,
+			access_token = excluded.access_token,
+			expires_at = excluded.expires_at,
+			connected_at = excluded.connected_at,
+			last_error = excluded.last_error
+	`, account.UserID, account.UserName, account.AccessToken, account.ExpiresAt, account.ConnectedAt, account.LastSyncAt, account.LastError)
+	if err != nil {
+		return fmt.Errorf("failed to save anilist account: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// RecordSync saves when a sync finished and its error, empty on success.
+func (r *AnilistSyncRepository) RecordSync(at time.Time, syncErr string) error {
+	_, err := r.db.Exec(`UPDATE anilist_account SET last_sync_at = ?, last_error = ? WHERE id = 1`, at, syncErr)
+	if err != nil {
+		return fmt.Errorf("failed to record anilist sync: %w", err)
+	}
+	return nil
+}
+
+// DeleteAccount disconnects the account and drops its sync state, including
+// changes that were never pushed.
+func (r *AnilistSyncRepository) DeleteAccount() error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM anilist_account`); err != nil {
+		return fmt.Errorf("failed to delete anilist account: %w", err)
+	}
+	if err := clearSyncState(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func clearSyncState(tx *sql.Tx) error {
+	if _, err := tx.Exec(`DELETE FROM anilist_sync_entries`); err != nil {
+		return fmt.Errorf("failed to clear anilist sync entries: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM anilist_outbox`); err != nil {
+		return fmt.Errorf("failed to clear anilist outbox: %w", err)
+	}
+	return nil
+}
+
+// GetSyncedEntries returns the AniList state of every synced entry by
+// AniList ID.
+func (r *AnilistSyncRepository) GetSyncedEntries() (map[int]domain.AnilistListEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT anilist_id, list_entry_id, status, score, progress, started_at, completed_at, updated_at
+		FROM anilist_sync_entries
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anilist sync entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make(map[int]domain.AnilistListEntry)
+	for rows.Next() {
+		var entry domain.AnilistListEntry
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&entry.MediaID, &entry.ListEntryID, &entry.Status, &entry.Score, &entry.Progress,
+			&entry.StartedAt, &entry.CompletedAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan anilist sync entry: %w", err)
+		}
+		entry.UpdatedAt = updatedAt.Time
+		entries[entry.MediaID] = entry
+	}
+
+	return entries, rows.Err()
+}
+
+func (r *AnilistSyncRepository) SaveSyncedEntry(entry *domain.AnilistListEntry) error {
+	var updatedAt sql.NullTime
+	if !entry.UpdatedAt.IsZero() {
+		updatedAt = sql.NullTime{Time: entry.UpdatedAt, Valid: true}
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO anilist_sync_entries (anilist_id, list_entry_id, status, score, progress, started_at, completed_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(anilist_id) DO UPDATE SET
+			list_entry_id = excluded.list_entry_id,
+			status = excluded.status,
+			score = excluded.score,
+			progress = excluded.progress,
+			started_at = excluded.started_at,
+			completed_at = excluded.completed_at,
+			updated_at = excluded.updated_at
+	`, entry.MediaID, entry.ListEntryID, entry.Status, entry.Score, entry.Progress, entry.StartedAt, entry.CompletedAt, updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save anilist sync entry: %w", err)
+	}
+	return nil
+}
+
+func (r *AnilistSyncRepository) DeleteSyncedEntry(anilistID int) error {
+	if _, err := r.db.Exec(`DELETE FROM anilist_sync_entries WHERE anilist_id = ?`, anilistID); err != nil {
+		return fmt.Errorf("failed to delete anilist sync entry: %w", err)
+	}
+	return nil
+}
+
+func (r *AnilistSyncRepository) AddOutboxEntry(entry *domain.OutboxEntry) error {
+	fields, err := json.Marshal(entry.Fields)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.Exec(`
+		INSERT INTO anilist_outbox (anilist_id, action, fields, changed_at)
+		VALUES (?, ?, ?, ?)
+	`, entry.AnilistID, entry.Action, string(fields), entry.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("failed to queue anilist change: %w", err)
+	}
+
+	entry.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetOutbox returns the queued changes, oldest first.
+func (r *AnilistSyncRepository) GetOutbox() ([]domain.OutboxEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT id, anilist_id, action, fields, changed_at, attempts, last_error
+		FROM anilist_outbox
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query anilist outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []domain.OutboxEntry
+	for rows.Next() {
+		var entry domain.OutboxEntry
+		var fields string
+		if err := rows.Scan(&entry.ID, &entry.AnilistID, &entry.Action, &fields, &entry.ChangedAt, &entry.Attempts, &entry.LastError); err != nil {
+			return nil, fmt.Errorf("failed to scan anilist outbox entry: %w", err)
+		}
+		if err := json.Unmarshal([]byte(fields), &entry.Fields); err != nil {
+			return nil, fmt.Errorf("failed to read fields of anilist outbox entry %d: %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (r *AnilistSyncRepository) CountOutbox() (int, error) {
+	var count int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM anilist_outbox`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count anilist outbox: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteOutboxEntries removes the changes to one anime up to and including
+// upToID, leaving any queued while they were being pushed.
+func (r *AnilistSyncRepository) DeleteOutboxEntries(anilistID int, upToID int64) error {
+	_, err := r.db.Exec(`DELETE FROM anilist_outbox WHERE anilist_id = ? AND id <= ?`, anilistID, upToID)
+	if err != nil {
+		return fmt.Errorf("failed to delete anilist outbox entries: %w", err)
+	}
+	return nil
+}
+
+// MarkOutboxFailed records a failed push of the changes to one anime up to
+// and including upToID.
+func (r *AnilistSyncRepository) MarkOutboxFailed(anilistID int, upToID int64, message string) error {
+	_, err := r.db.Exec(`
+		UPDATE anilist_outbox
+		SET attempts = attempts + 1, last_error = ?
+		WHERE anilist_id = ? AND id <= ?
+	`, message, anilistID, upToID)
+	if err != nil {
+		return fmt.Errorf("failed to update anilist outbox entries: %w", err)
+	}
+	return nil
+}
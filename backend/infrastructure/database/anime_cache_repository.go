@@ -0,0 +1,168 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+// AnimeCacheRepository stores AniList metadata locally so the watchlist does
+// not need a round trip to AniList per entry.
+type AnimeCacheRepository struct {
+	db *sql.DB
+}
+
+func NewAnimeCacheRepository(db *sql.DB) *AnimeCacheRepository {
+	return &AnimeCacheRepository{db: db}
+}
+
+// GetCachedAnime returns the cached anime and when it was fetched, or nil if
+// it is not cached.
+func (r *AnimeCacheRepository) GetCachedAnime(anilistID int) (*domain.Anime, time.Time, error) {
+	query := `
+		SELECT anilist_id, title, title_english, title_romaji, title_native, synonyms, description,
+			cover_image, banner_image, status, format, episodes, duration, season, season_year,
+			genres, score, popularity, fetched_at
+		FROM anime_cache
+		WHERE anilist_id = ?
+	`
+
+	var anime domain.Anime
+	var titleEnglish, titleRomaji, titleNative, synonyms, description sql.NullString
+	var coverImage, bannerImage, status, format, season, genres sql.NullString
+	var episodes, duration, seasonYear, popularity sql.NullInt64
+	var score sql.NullFloat64
+	var fetchedAt time.Time
+
+	err := r.db.QueryRow(query, anilistID).Scan(
+		&anime.AnilistID,
+		&anime.Title,
+		&titleEnglish,
+		&titleRomaji,
+		&titleNative,
+		&synonyms,
+		&description,
+		&coverImage,
+		&bannerImage,
+		&status,
+		&format,
+		&episodes,
+		&duration,
+		&season,
+		&seasonYear,
+		&genres,
+		&score,
+		&popularity,
+		&fetchedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	anime.TitleEnglish = titleEnglish.String
+	anime.TitleRomaji = titleRomaji.String
+	anime.TitleNative = titleNative.String
+	anime.Description = description.String
+	anime.CoverImage = coverImage.String
+	anime.BannerImage = bannerImage.String
+	anime.Status = status.String
+	anime.Format = format.String
+	anime.Episodes = int(episodes.Int64)
+	anime.Duration = int(duration.Int64)
+	anime.Season = season.String
+	anime.SeasonYear = int(seasonYear.Int64)
+	anime.Genres = genres.String
+	anime.Score = score.Float64
+	anime.Popularity = int(popularity.Int64)
+	if synonyms.Valid && synonyms.String != "" {
+		if err := json.Unmarshal([]byte(synonyms.String), &anime.Synonyms); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	return &anime, fetchedAt, nil
+}
+
+func (r *AnimeCacheRepository) SaveAnime(anime *domain.Anime) error {
+	query := `
+		INSERT INTO anime_cache (anilist_id, title, title_english, title_romaji, title_native, synonyms,
+			description, cover_image, banner_image, status, format, episodes, duration, season,
+			season_year, genres, score, popularity, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(anilist_id) DO UPDATE SET
+			title = excluded.title,
+			title_english = excluded.title_english,
+			title_romaji = excluded.title_romaji,
+			title_native = excluded.title_native,
+			synonyms = excluded.synonyms,
+			description = excluded.description,
+			cover_image = excluded.cover_image,
+			banner_image = excluded.banner_image,
+			status = excluded.status,
+			format = excluded.format,
+			episodes = excluded.episodes,
+			duration = excluded.duration,
+			season = excluded.season,
+			season_year = excluded.season_year,
+			genres = excluded.genres,
+			score = excluded.score,
+			popularity = excluded.popularity,
+			fetched_at = excluded.fetched_at
+	`
+
+	synonyms, err := json.Marshal(anime.Synonyms)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(query, anime.AnilistID, anime.Title, anime.TitleEnglish, anime.TitleRomaji,
+		anime.TitleNative, string(synonyms), anime.Description, anime.CoverImage, anime.BannerImage,
+		anime.Status, anime.Format, anime.Episodes, anime.Duration, anime.Season, anime.SeasonYear,
+		anime.Genres, anime.Score, anime.Popularity, time.Now())
+	return err
+}
+
+// SearchAnime runs a ranked full-text search over the titles, synonyms and
+// descriptions of cached anime. Title matches weigh more than synonyms, and
+// those more than the description.
+func (r *AnimeCacheRepository) SearchAnime(text string, limit int) ([]domain.SearchResult, error) {
+	match := ftsQuery(text)
+	if match == "" {
+		return nil, nil
+	}
+
+	query := `
+		SELECT c.anilist_id, c.title,
+			snippet(anime_cache_fts, -1, '<mark>', '</mark>', '…', 16),
+			-bm25(anime_cache_fts, 10.0, 10.0, 10.0, 10.0, 5.0, 1.0)
+		FROM anime_cache_fts
+		JOIN anime_cache c ON c.anilist_id = anime_cache_fts.rowid
+		WHERE anime_cache_fts MATCH ?
+		ORDER BY bm25(anime_cache_fts, 10.0, 10.0, 10.0, 10.0, 5.0, 1.0)
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.SearchResult
+	for rows.Next() {
+		var anilistID int
+		result := domain.SearchResult{Kind: domain.SearchResultAnime}
+		if err := rows.Scan(&anilistID, &result.Title, &result.Highlight, &result.Score); err != nil {
+			return nil, err
+		}
+		result.AnilistID = &anilistID
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
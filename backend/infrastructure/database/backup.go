@@ -0,0 +1,133 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Backup writes a consistent copy of the database to path while it stays in
+// use. VACUUM INTO also compacts the copy. It fails if path already exists.
+func (d *Database) Backup(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup %s already exists", path)
+	}
+
+	if _, err := d.DB.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// Restore replaces the database at dbPath with a backup and returns the
+// backup's schema version. The backup is checked first: it has to pass an
+// integrity check and carry migrations this binary knows, so an older backup
+// restores fine (it is migrated on the next start) but one from a newer
+// release is refused. The replaced database is kept as dbPath.pre-restore.
+//
+// Nothing may have the database open while it is restored, so stop the
+// server first.
+func Restore(dbPath, backupPath string) (int, error) {
+	if dbPath == MemoryPath {
+		return 0, errors.New("cannot restore into an in-memory database")
+	}
+
+	staged := dbPath + ".restore"
+	if err := copyFile(backupPath, staged); err != nil {
+		return 0, fmt.Errorf("failed to stage backup: %w", err)
+	}
+
+	version, err := validateBackup(staged)
+	if err != nil {
+		removeDatabaseFiles(staged)
+		return 0, err
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		// Fold the write-ahead log into the old database so the kept copy is
+		// complete on its own
+		if old, err := Open(dbPath); err == nil {
+			old.DB.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`)
+			old.Close()
+		}
+		if err := os.Rename(dbPath, dbPath+".pre-restore"); err != nil {
+			removeDatabaseFiles(staged)
+			return 0, fmt.Errorf("failed to move current database aside: %w", err)
+		}
+	}
+	removeDatabaseFiles(dbPath)
+
+	if err := os.Rename(staged, dbPath); err != nil {
+		return 0, fmt.Errorf("failed to restore database: %w", err)
+	}
+	removeDatabaseFiles(staged)
+
+	return version, nil
+}
+
+func validateBackup(path string) (int, error) {
+	db, err := Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.DB.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return 0, fmt.Errorf("backup is not a readable SQLite database: %w", err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("backup failed the integrity check: %s", result)
+	}
+
+	hasMigrations, err := db.tableExists("schema_migrations")
+	if err != nil {
+		return 0, err
+	}
+	if !hasMigrations {
+		return 0, errors.New("backup has no schema version; it is not a backup of this server's database")
+	}
+
+	migrator, err := NewMigrator(db.DB)
+	if err != nil {
+		return 0, err
+	}
+	version, err := migrator.CurrentVersion()
+	if err != nil {
+		return 0, fmt.Errorf("backup cannot be restored: %w", err)
+	}
+
+	// Leave a single self-contained file behind
+	if _, err := db.DB.Exec(`PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// removeDatabaseFiles deletes a database file and its WAL and shared memory
+// files, ignoring any that do not exist.
+func removeDatabaseFiles(path string) {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		os.Remove(path + suffix)
+	}
+}
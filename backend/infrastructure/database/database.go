@@ -9,31 +9,61 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// MemoryPath opens a throwaway database that lives only as long as the
+// process, for demos and tests.
+const MemoryPath = ":memory:"
+
+// fileDatabaseOptions are applied to every connection to a database file. WAL
+// lets requests keep reading while a sync writes, and the busy timeout makes
+// a second writer wait for the lock instead of failing straight away.
+const fileDatabaseOptions = "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+
 type Database struct {
 	DB *sql.DB
 }
 
 func New(dbPath string) (*Database, error) {
+	database, err := Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.migrate(); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return database, nil
+}
+
+// Open connects to the database without migrating it, for tools that manage
+// migrations themselves.
+func Open(dbPath string) (*Database, error) {
 	dir := filepath.Dir(dbPath)
 	if err := ensureDir(dir); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	dsn := dbPath
+	if dbPath != MemoryPath {
+		dsn += fileDatabaseOptions
+	}
+
+	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	// Every SQLite connection to :memory: gets its own empty database
+	if dbPath == MemoryPath {
+		db.SetMaxOpenConns(1)
 	}
 
-	database := &Database{DB: db}
-	if err := database.migrate(); err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return database, nil
+	return &Database{DB: db}, nil
 }
 
 func (d *Database) Close() error {
@@ -41,38 +71,96 @@ func (d *Database) Close() error {
 }
 
 func (d *Database) migrate() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS watchlist (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			anilist_id INTEGER UNIQUE NOT NULL,
-			added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_watchlist_anilist_id ON watchlist(anilist_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_watchlist_added_at ON watchlist(added_at)`,
-		`CREATE TABLE IF NOT EXISTS plex_shows (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			plex_id INTEGER UNIQUE NOT NULL,
-			title TEXT NOT NULL,
-			anilist_id INTEGER,
-			year INTEGER,
-			episode_count INTEGER,
-			last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-		`CREATE INDEX IF NOT EXISTS idx_plex_shows_plex_id ON plex_shows(plex_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_plex_shows_anilist_id ON plex_shows(anilist_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_plex_shows_title ON plex_shows(title)`,
-	}
-
-	for _, query := range queries {
-		if _, err := d.DB.Exec(query); err != nil {
-			return fmt.Errorf("failed to execute migration: %w", err)
+	migrator, err := NewMigrator(d.DB)
+	if err != nil {
+		return err
+	}
+
+	count, err := migrator.Up()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Database migration completed successfully (schema version %d, %d applied)", migrator.LatestVersion(), count)
+	return nil
+}
+
+// upgradeLegacySchema brings databases created before versioned migrations
+// up to the first migration. Migrator.Up runs it before anything else. Those grew columns at startup instead, so a
+// database from an older release may still lack some of them.
+func (d *Database) upgradeLegacySchema() error {
+	hasShows, err := d.tableExists("plex_shows")
+	if err != nil {
+		return err
+	}
+	hasMigrations, err := d.tableExists("schema_migrations")
+	if err != nil {
+		return err
+	}
+	if !hasShows || hasMigrations {
+		return nil
+	}
+
+	columns := []struct {
+		table      string
+		column     string
+		definition string
+	}{
+		{"plex_shows", "guid", "TEXT"},
+		{"plex_shows", "mapping_source", "TEXT"},
+		{"plex_shows", "mapping_locked", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"plex_shows", "ignored", "BOOLEAN NOT NULL DEFAULT 0"},
+		{"plex_shows", "auto_mapped_at", "TIMESTAMP"},
+		{"plex_shows", "source", "TEXT NOT NULL DEFAULT 'plex'"},
+		{"plex_shows", "external_id", "TEXT"},
+	}
+
+	for _, c := range columns {
+		if err := d.addColumnIfMissing(c.table, c.column, c.definition); err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", c.table, c.column, err)
 		}
 	}
 
-	log.Println("Database migration completed successfully")
 	return nil
 }
 
+func (d *Database) addColumnIfMissing(table, column, definition string) error {
+	rows, err := d.DB.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = d.DB.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+func (d *Database) tableExists(name string) (bool, error) {
+	var count int
+	err := d.DB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`, name).Scan(&count)
+	return count > 0, err
+}
+
 func ensureDir(dir string) error {
 	if dir == "." || dir == "/" {
 		return nil
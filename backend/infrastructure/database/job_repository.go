@@ -0,0 +1,186 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+type JobRepository struct {
+	db *sql.DB
+}
+
+func NewJobRepository(db *sql.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+const jobColumns = `id, type, status, max_items, total, processed, mapped, queued, failed, checkpoint, error, resume_after, created_at, updated_at, finished_at`
+
+func (r *JobRepository) CreateJob(job *domain.Job) error {
+	query := `
+		INSERT INTO jobs (id, type, status, max_items, total, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	_, err := r.db.Exec(query, job.ID, job.Type, job.Status, job.Limit, job.Total, now, now)
+	return err
+}
+
+// UpdateJob saves the progress, status and checkpoint of a job.
+func (r *JobRepository) UpdateJob(job *domain.Job) error {
+	query := `
+		UPDATE jobs
+		SET status = ?, total = ?, processed = ?, mapped = ?, queued = ?, failed = ?, checkpoint = ?,
+			error = ?, resume_after = ?, updated_at = ?, finished_at = ?
+		WHERE id = ?
+	`
+
+	job.UpdatedAt = time.Now()
+	_, err := r.db.Exec(query, job.Status, job.Total, job.Processed, job.Mapped, job.Queued, job.Failed, job.Checkpoint,
+		nullString(job.Error), job.ResumeAfter, job.UpdatedAt, job.FinishedAt, job.ID)
+	return err
+}
+
+func (r *JobRepository) GetJob(id string) (*domain.Job, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE id = ?
+	`
+
+	job, err := scanJob(r.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrJobNotFound
+	}
+	return job, err
+}
+
+func (r *JobRepository) GetRecentJobs(limit int) ([]domain.Job, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		ORDER BY created_at DESC
+		LIMIT ?
+	`
+
+	return r.queryJobs(query, limit)
+}
+
+// GetUnfinishedJobs returns jobs that were pending, running or paused, such as
+// those interrupted by a restart.
+func (r *JobRepository) GetUnfinishedJobs() ([]domain.Job, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM jobs
+		WHERE status IN (?, ?, ?)
+		ORDER BY created_at
+	`
+
+	return r.queryJobs(query, domain.JobStatusPending, domain.JobStatusRunning, domain.JobStatusPaused)
+}
+
+func (r *JobRepository) AddJobItem(item *domain.JobItem) error {
+	query := `
+		INSERT INTO job_items (job_id, plex_id, title, outcome, anilist_id, score, message, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(job_id, plex_id) DO UPDATE SET
+			outcome = excluded.outcome,
+			anilist_id = excluded.anilist_id,
+			score = excluded.score,
+			message = excluded.message,
+			created_at = excluded.created_at
+	`
+
+	item.CreatedAt = time.Now()
+	_, err := r.db.Exec(query, item.JobID, item.PlexID, item.Title, item.Outcome, item.AnilistID, item.Score,
+		nullString(item.Message), item.CreatedAt)
+	return err
+}
+
+func (r *JobRepository) GetJobItems(jobID string) ([]domain.JobItem, error) {
+	query := `
+		SELECT job_id, plex_id, title, outcome, anilist_id, score, message, created_at
+		FROM job_items
+		WHERE job_id = ?
+		ORDER BY created_at
+	`
+
+	rows, err := r.db.Query(query, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []domain.JobItem{}
+	for rows.Next() {
+		var item domain.JobItem
+		var message sql.NullString
+		err := rows.Scan(&item.JobID, &item.PlexID, &item.Title, &item.Outcome, &item.AnilistID, &item.Score, &message, &item.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		item.Message = message.String
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *JobRepository) queryJobs(query string, args ...interface{}) ([]domain.Job, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []domain.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func scanJob(row rowScanner) (*domain.Job, error) {
+	job := &domain.Job{}
+	var jobError sql.NullString
+	var resumeAfter, finishedAt sql.NullTime
+
+	err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&job.Status,
+		&job.Limit,
+		&job.Total,
+		&job.Processed,
+		&job.Mapped,
+		&job.Queued,
+		&job.Failed,
+		&job.Checkpoint,
+		&jobError,
+		&resumeAfter,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.Error = jobError.String
+	if resumeAfter.Valid {
+		job.ResumeAfter = &resumeAfter.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return job, nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+
+	"anime-watchlist/backend/domain"
+)
+
+// LibraryFileRepository is the file index of the filesystem library source.
+type LibraryFileRepository struct {
+	db *sql.DB
+}
+
+func NewLibraryFileRepository(db *sql.DB) *LibraryFileRepository {
+	return &LibraryFileRepository{db: db}
+}
+
+const libraryFileColumns = `path, root, mod_time, size, show_key, title, release_group, season, episode, resolution, crc, year`
+
+func (r *LibraryFileRepository) GetLibraryFiles(root string) ([]domain.LibraryFile, error) {
+	query := `
+		SELECT ` + libraryFileColumns + `
+		FROM library_files
+		WHERE root = ?
+	`
+
+	return r.queryLibraryFiles(query, root)
+}
+
+func (r *LibraryFileRepository) GetShowFiles(showKey string) ([]domain.LibraryFile, error) {
+	query := `
+		SELECT ` + libraryFileColumns + `
+		FROM library_files
+		WHERE show_key = ?
+		ORDER BY season, episode, path
+	`
+
+	return r.queryLibraryFiles(query, showKey)
+}
+
+func (r *LibraryFileRepository) SaveLibraryFiles(files []domain.LibraryFile) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	query := `
+		INSERT INTO library_files (` + libraryFileColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			root = excluded.root,
+			mod_time = excluded.mod_time,
+			size = excluded.size,
+			show_key = excluded.show_key,
+			title = excluded.title,
+			release_group = excluded.release_group,
+			season = excluded.season,
+			episode = excluded.episode,
+			resolution = excluded.resolution,
+			crc = excluded.crc,
+			year = excluded.year
+	`
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, file := range files {
+		_, err := stmt.Exec(file.Path, file.Root, file.ModTime, file.Size, file.ShowKey, file.Title,
+			nullString(file.Group), file.Season, file.Episode, nullString(file.Resolution), nullString(file.CRC), file.Year)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *LibraryFileRepository) DeleteLibraryFiles(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, path := range paths {
+		if _, err := tx.Exec(`DELETE FROM library_files WHERE path = ?`, path); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *LibraryFileRepository) queryLibraryFiles(query string, args ...interface{}) ([]domain.LibraryFile, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []domain.LibraryFile
+	for rows.Next() {
+		var file domain.LibraryFile
+		var group, resolution, crc sql.NullString
+
+		err := rows.Scan(&file.Path, &file.Root, &file.ModTime, &file.Size, &file.ShowKey, &file.Title,
+			&group, &file.Season, &file.Episode, &resolution, &crc, &file.Year)
+		if err != nil {
+			return nil, err
+		}
+
+		file.Group = group.String
+		file.Resolution = resolution.String
+		file.CRC = crc.String
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+type MappingCandidateRepository struct {
+	db *sql.DB
+}
+
+func NewMappingCandidateRepository(db *sql.DB) *MappingCandidateRepository {
+	return &MappingCandidateRepository{db: db}
+}
+
+// UpsertCandidate saves the candidate for a show. A rejected candidate stays
+// rejected when a new pending one replaces it, so re-running auto-map does not
+// bring it back into review.
+func (r *MappingCandidateRepository) UpsertCandidate(candidate *domain.MappingCandidate) error {
+	query := `
+		INSERT INTO mapping_candidates (plex_id, anilist_id, score, strategy, alternatives, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(plex_id) DO UPDATE SET
+			anilist_id = excluded.anilist_id,
+			score = excluded.score,
+			strategy = excluded.strategy,
+			alternatives = excluded.alternatives,
+			status = CASE
+				WHEN mapping_candidates.status = 'rejected' AND excluded.status = 'pending' THEN mapping_candidates.status
+				ELSE excluded.status
+			END,
+			updated_at = excluded.updated_at
+	`
+
+	alternatives, err := json.Marshal(candidate.Alternatives)
+	if err != nil {
+		return fmt.Errorf("failed to encode alternatives: %w", err)
+	}
+
+	now := time.Now()
+	_, err = r.db.Exec(query, candidate.PlexID, candidate.AnilistID, candidate.Score, candidate.Strategy,
+		string(alternatives), candidate.Status, now, now)
+	return err
+}
+
+func (r *MappingCandidateRepository) GetCandidate(plexID int) (*domain.MappingCandidate, error) {
+	query := `
+		SELECT plex_id, anilist_id, score, strategy, alternatives, status, created_at, updated_at
+		FROM mapping_candidates
+		WHERE plex_id = ?
+	`
+
+	candidate, err := scanCandidate(r.db.QueryRow(query, plexID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrCandidateNotFound
+	}
+	return candidate, err
+}
+
+func (r *MappingCandidateRepository) GetCandidatesByStatus(status string) ([]domain.MappingCandidate, error) {
+	query := `
+		SELECT plex_id, anilist_id, score, strategy, alternatives, status, created_at, updated_at
+		FROM mapping_candidates
+		WHERE status = ?
+		ORDER BY score DESC
+	`
+
+	rows, err := r.db.Query(query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []domain.MappingCandidate
+	for rows.Next() {
+		candidate, err := scanCandidate(rows)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, *candidate)
+	}
+
+	return candidates, rows.Err()
+}
+
+func (r *MappingCandidateRepository) UpdateCandidateStatus(plexID int, status string) error {
+	query := `
+		UPDATE mapping_candidates
+		SET status = ?, updated_at = ?
+		WHERE plex_id = ?
+	`
+
+	result, err := r.db.Exec(query, status, time.Now(), plexID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return domain.ErrCandidateNotFound
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCandidate(row rowScanner) (*domain.MappingCandidate, error) {
+	candidate := &domain.MappingCandidate{}
+	var alternatives string
+
+	err := row.Scan(
+		&candidate.PlexID,
+		&candidate.AnilistID,
+		&candidate.Score,
+		&candidate.Strategy,
+		&alternatives,
+		&candidate.Status,
+		&candidate.CreatedAt,
+		&candidate.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if alternatives != "" {
+		if err := json.Unmarshal([]byte(alternatives), &candidate.Alternatives); err != nil {
+			return nil, fmt.Errorf("failed to decode alternatives: %w", err)
+		}
+	}
+
+	return candidate, nil
+}
@@ -0,0 +1,358 @@
+package database
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationName matches files such as 0002_add_watchlist_notes.up.sql.
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one numbered schema change. Down is empty when the migration
+// cannot be reverted.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus describes a known or applied migration.
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+	HasDown   bool       `json:"has_down"`
+}
+
+// ParamStyle formats the nth bind parameter of a query, counting from 1.
+type ParamStyle func(n int) string
+
+// QuestionParams is SQLite's style; PostgresParams numbers them as $1, $2...
+func QuestionParams(int) string { return "?" }
+
+func PostgresParams(n int) string { return "$" + strconv.Itoa(n) }
+
+// Migrator applies the embedded migrations and records them in
+// schema_migrations. Every migration runs in its own transaction.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	param      ParamStyle
+
+	// tableQuery counts the tables with a name, so reading the version
+	// does not create schema_migrations
+	tableQuery string
+	// prepare runs before Up creates schema_migrations
+	prepare func() error
+}
+
+// NewMigrator migrates a SQLite database with the migrations in this package.
+// Up first brings a database from before versioned migrations up to the
+// first one, as schema_migrations existing marks it as already upgraded.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrator, err := NewMigratorFrom(db, migrationFiles, QuestionParams)
+	if err != nil {
+		return nil, err
+	}
+	migrator.tableQuery = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	migrator.prepare = (&Database{DB: db}).upgradeLegacySchema
+	return migrator, nil
+}
+
+// NewMigratorFrom migrates another database with the migrations directory of
+// files, for stores that need their own dialect. The database must have
+// information_schema, as Postgres does.
+func NewMigratorFrom(db *sql.DB, files fs.FS, param ParamStyle) (*Migrator, error) {
+	migrations, err := loadMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		param:      param,
+		tableQuery: `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ` + param(1),
+	}, nil
+}
+
+func loadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(files, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+
+		if match[3] == "up" {
+			sum := sha256.Sum256(content)
+			migration.Up = string(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// LatestVersion is the highest migration this binary knows.
+func (m *Migrator) LatestVersion() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// CurrentVersion returns the newest applied migration, after the same checks
+// Up makes before migrating. It is 0 for a database never migrated, and the
+// database is not changed.
+func (m *Migrator) CurrentVersion() (int, error) {
+	applied, err := m.check()
+	if err != nil {
+		return 0, err
+	}
+
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+	return current, nil
+}
+
+// Up applies every pending migration. It refuses to run when the database
+// was migrated by a newer binary or an applied migration has been edited.
+func (m *Migrator) Up() (int, error) {
+	if m.prepare != nil {
+		if err := m.prepare(); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			checksum TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := m.check()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.inTransaction(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migration.Up); err != nil {
+				return err
+			}
+			query := fmt.Sprintf(`
+				INSERT INTO schema_migrations (version, name, checksum, applied_at)
+				VALUES (%s, %s, %s, %s)
+			`, m.param(1), m.param(2), m.param(3), m.param(4))
+			_, err := tx.Exec(query, migration.Version, migration.Name, migration.Checksum, time.Now())
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Down reverts applied migrations newer than target, newest first. It stops
+// at the first migration without a down file.
+func (m *Migrator) Down(target int) (int, error) {
+	applied, err := m.check()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= target {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return count, fmt.Errorf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+		}
+
+		err := m.inTransaction(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migration.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = `+m.param(1), migration.Version)
+			return err
+		})
+		if err != nil {
+			return count, fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// Status lists every known migration and whether it has been applied,
+// without changing the database.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+			HasDown: migration.Down != "",
+		}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// check loads the applied migrations and compares them with the embedded
+// ones.
+func (m *Migrator) check() (map[int]appliedMigration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	latest := m.LatestVersion()
+	known := make(map[int]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, record := range applied {
+		if version > latest {
+			return nil, fmt.Errorf("database schema version %d is newer than this binary supports (%d); upgrade the binary or restore a backup", version, latest)
+		}
+		migration, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("database has migration %d applied, which this binary does not know", version)
+		}
+		if migration.Checksum != record.checksum {
+			return nil, fmt.Errorf("migration %d_%s was edited after it was applied; add a new migration instead", version, migration.Name)
+		}
+	}
+
+	return applied, nil
+}
+
+// applied reads schema_migrations, which a database never migrated does not
+// have yet.
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	var tables int
+	if err := m.db.QueryRow(m.tableQuery, "schema_migrations").Scan(&tables); err != nil {
+		return nil, err
+	}
+	if tables == 0 {
+		return map[int]appliedMigration{}, nil
+	}
+
+	rows, err := m.db.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.checksum, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = record
+	}
+
+	return applied, rows.Err()
+}
+
+func (m *Migrator) inTransaction(fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// legacySchema is the part of the schema releases before versioned
+// migrations created that the first migration does not create itself.
+const legacySchema = `
+	CREATE TABLE watchlist (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		anilist_id INTEGER UNIQUE NOT NULL,
+		added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE plex_shows (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		plex_id INTEGER UNIQUE NOT NULL,
+		title TEXT NOT NULL,
+		anilist_id INTEGER,
+		year INTEGER,
+		episode_count INTEGER,
+		last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	INSERT INTO watchlist (anilist_id) VALUES (1);
+	INSERT INTO plex_shows (plex_id, title, anilist_id, year, episode_count) VALUES (10, 'Frieren', 154587, 2023, 28);
+`
+
+func newLegacyDatabase(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "anime.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.DB.Exec(legacySchema); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkUpgraded opens the database the way the server does and reads the
+// columns the legacy upgrade adds.
+func checkUpgraded(t *testing.T, path string) {
+	t.Helper()
+
+	db, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	show, err := NewPlexRepository(db.DB).GetPlexShowByPlexID(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.Title != "Frieren" || show.Source != "plex" || show.AnilistID == nil || *show.AnilistID != 154587 {
+		t.Errorf("show = %+v", show)
+	}
+	if count, err := NewWatchlistRepository(db).GetWatchlistCount(); err != nil || count != 1 {
+		t.Errorf("watchlist count = %d, %v", count, err)
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	checkUpgraded(t, newLegacyDatabase(t))
+}
+
+func TestMigratorOnLegacyDatabase(t *testing.T) {
+	t.Run("status", func(t *testing.T) {
+		path := newLegacyDatabase(t)
+
+		db, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		migrator, err := NewMigrator(db.DB)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		statuses, err := migrator.Status()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, status := range statuses {
+			if status.AppliedAt != nil {
+				t.Errorf("migration %d reported applied", status.Version)
+			}
+		}
+		if version, err := migrator.CurrentVersion(); err != nil || version != 0 {
+			t.Errorf("current version = %d, %v", version, err)
+		}
+		if exists, _ := db.tableExists("schema_migrations"); exists {
+			t.Error("reading the status created schema_migrations")
+		}
+		if count, err := migrator.Down(0); err != nil || count != 0 {
+			t.Errorf("down = %d, %v", count, err)
+		}
+		db.Close()
+
+		checkUpgraded(t, path)
+	})
+
+	t.Run("up", func(t *testing.T) {
+		path := newLegacyDatabase(t)
+
+		db, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		migrator, err := NewMigrator(db.DB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count, err := migrator.Up()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != migrator.LatestVersion() {
+			t.Errorf("applied %d migrations, want %d", count, migrator.LatestVersion())
+		}
+		db.Close()
+
+		checkUpgraded(t, path)
+	})
+}
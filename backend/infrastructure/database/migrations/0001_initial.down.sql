@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS plex_show_quality;
+DROP TABLE IF EXISTS sync_state;
+DROP TABLE IF EXISTS library_files;
+DROP TABLE IF EXISTS anime_cache;
+DROP TABLE IF EXISTS schedules;
+DROP TABLE IF EXISTS job_items;
+DROP TABLE IF EXISTS jobs;
+DROP TABLE IF EXISTS plex_season_mappings;
+DROP TABLE IF EXISTS mapping_history;
+DROP TABLE IF EXISTS mapping_candidates;
+DROP TABLE IF EXISTS plex_shows;
+DROP TABLE IF EXISTS watchlist;
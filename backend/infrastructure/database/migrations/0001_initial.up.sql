@@ -0,0 +1,168 @@
+-- Schema as of the switch to versioned migrations. Existing databases
+-- already have these tables, so every statement is idempotent.
+
+CREATE TABLE IF NOT EXISTS watchlist (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	anilist_id INTEGER UNIQUE NOT NULL,
+	added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_watchlist_anilist_id ON watchlist(anilist_id);
+CREATE INDEX IF NOT EXISTS idx_watchlist_added_at ON watchlist(added_at);
+
+CREATE TABLE IF NOT EXISTS plex_shows (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	plex_id INTEGER UNIQUE NOT NULL,
+	title TEXT NOT NULL,
+	anilist_id INTEGER,
+	year INTEGER,
+	episode_count INTEGER,
+	last_updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	guid TEXT,
+	mapping_source TEXT,
+	mapping_locked BOOLEAN NOT NULL DEFAULT 0,
+	ignored BOOLEAN NOT NULL DEFAULT 0,
+	auto_mapped_at TIMESTAMP,
+	source TEXT NOT NULL DEFAULT 'plex',
+	external_id TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_plex_shows_plex_id ON plex_shows(plex_id);
+CREATE INDEX IF NOT EXISTS idx_plex_shows_anilist_id ON plex_shows(anilist_id);
+CREATE INDEX IF NOT EXISTS idx_plex_shows_title ON plex_shows(title);
+
+CREATE TABLE IF NOT EXISTS mapping_candidates (
+	plex_id INTEGER PRIMARY KEY,
+	anilist_id INTEGER NOT NULL,
+	score REAL NOT NULL,
+	strategy TEXT NOT NULL,
+	alternatives TEXT NOT NULL DEFAULT '[]',
+	status TEXT NOT NULL DEFAULT 'pending',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_mapping_candidates_status ON mapping_candidates(status);
+
+CREATE TABLE IF NOT EXISTS mapping_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	plex_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	old_anilist_id INTEGER,
+	new_anilist_id INTEGER,
+	old_source TEXT,
+	new_source TEXT,
+	actor TEXT NOT NULL,
+	undone BOOLEAN NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_mapping_history_plex_id ON mapping_history(plex_id);
+
+CREATE TABLE IF NOT EXISTS plex_season_mappings (
+	plex_id INTEGER NOT NULL,
+	season_number INTEGER NOT NULL,
+	anilist_id INTEGER NOT NULL,
+	episode_offset INTEGER NOT NULL DEFAULT 0,
+	episode_count INTEGER NOT NULL DEFAULT 0,
+	anilist_episodes INTEGER NOT NULL DEFAULT 0,
+	source TEXT NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (plex_id, anilist_id)
+);
+CREATE INDEX IF NOT EXISTS idx_plex_season_mappings_anilist_id ON plex_season_mappings(anilist_id);
+
+CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT PRIMARY KEY,
+	type TEXT NOT NULL,
+	status TEXT NOT NULL,
+	max_items INTEGER NOT NULL DEFAULT 0,
+	total INTEGER NOT NULL DEFAULT 0,
+	processed INTEGER NOT NULL DEFAULT 0,
+	mapped INTEGER NOT NULL DEFAULT 0,
+	queued INTEGER NOT NULL DEFAULT 0,
+	failed INTEGER NOT NULL DEFAULT 0,
+	checkpoint INTEGER NOT NULL DEFAULT 0,
+	error TEXT,
+	resume_after TIMESTAMP,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	finished_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+
+CREATE TABLE IF NOT EXISTS job_items (
+	job_id TEXT NOT NULL,
+	plex_id INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	outcome TEXT NOT NULL,
+	anilist_id INTEGER,
+	score REAL NOT NULL DEFAULT 0,
+	message TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (job_id, plex_id)
+);
+
+CREATE TABLE IF NOT EXISTS schedules (
+	name TEXT PRIMARY KEY,
+	spec TEXT NOT NULL,
+	last_run_at TIMESTAMP,
+	next_run_at TIMESTAMP,
+	last_status TEXT,
+	last_error TEXT,
+	last_duration_ms INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS anime_cache (
+	anilist_id INTEGER PRIMARY KEY,
+	title TEXT NOT NULL,
+	title_english TEXT,
+	title_romaji TEXT,
+	title_native TEXT,
+	synonyms TEXT,
+	description TEXT,
+	cover_image TEXT,
+	banner_image TEXT,
+	status TEXT,
+	format TEXT,
+	episodes INTEGER,
+	duration INTEGER,
+	season TEXT,
+	season_year INTEGER,
+	genres TEXT,
+	score REAL,
+	popularity INTEGER,
+	fetched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS library_files (
+	path TEXT PRIMARY KEY,
+	root TEXT NOT NULL,
+	mod_time TIMESTAMP NOT NULL,
+	size INTEGER NOT NULL,
+	show_key TEXT NOT NULL,
+	title TEXT NOT NULL,
+	release_group TEXT,
+	season INTEGER NOT NULL DEFAULT 1,
+	episode INTEGER NOT NULL DEFAULT 0,
+	resolution TEXT,
+	crc TEXT,
+	year INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_library_files_root ON library_files(root);
+CREATE INDEX IF NOT EXISTS idx_library_files_show_key ON library_files(show_key);
+
+CREATE TABLE IF NOT EXISTS sync_state (
+	source TEXT NOT NULL,
+	library_id TEXT NOT NULL,
+	last_sync_at TIMESTAMP,
+	last_full_sync_at TIMESTAMP,
+	PRIMARY KEY (source, library_id)
+);
+
+CREATE TABLE IF NOT EXISTS plex_show_quality (
+	plex_id INTEGER PRIMARY KEY,
+	resolutions TEXT NOT NULL DEFAULT '[]',
+	max_height INTEGER NOT NULL DEFAULT 0,
+	video_codecs TEXT NOT NULL DEFAULT '[]',
+	audio_languages TEXT NOT NULL DEFAULT '[]',
+	subtitle_languages TEXT NOT NULL DEFAULT '[]',
+	episodes_analyzed INTEGER NOT NULL DEFAULT 0,
+	updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS anime_cache_fts_update;
+DROP TRIGGER IF EXISTS anime_cache_fts_delete;
+DROP TRIGGER IF EXISTS anime_cache_fts_insert;
+DROP TABLE IF EXISTS anime_cache_fts;
+
+DROP TRIGGER IF EXISTS plex_shows_fts_update;
+DROP TRIGGER IF EXISTS plex_shows_fts_delete;
+DROP TRIGGER IF EXISTS plex_shows_fts_insert;
+DROP TABLE IF EXISTS plex_shows_fts;
@@ -0,0 +1,62 @@
+-- Full-text indexes for local search, kept in step with the tables they
+-- index by triggers.
+
+-- Show titles are read from plex_shows itself (an external content table).
+CREATE VIRTUAL TABLE plex_shows_fts USING fts5(
+	title,
+	content = 'plex_shows',
+	content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER plex_shows_fts_insert AFTER INSERT ON plex_shows BEGIN
+	INSERT INTO plex_shows_fts(rowid, title) VALUES (new.id, new.title);
+END;
+
+CREATE TRIGGER plex_shows_fts_delete AFTER DELETE ON plex_shows BEGIN
+	INSERT INTO plex_shows_fts(plex_shows_fts, rowid, title) VALUES ('delete', old.id, old.title);
+END;
+
+CREATE TRIGGER plex_shows_fts_update AFTER UPDATE OF title ON plex_shows BEGIN
+	INSERT INTO plex_shows_fts(plex_shows_fts, rowid, title) VALUES ('delete', old.id, old.title);
+	INSERT INTO plex_shows_fts(rowid, title) VALUES (new.id, new.title);
+END;
+
+INSERT INTO plex_shows_fts(plex_shows_fts) VALUES ('rebuild');
+
+-- The anime index keeps its own copy of the text so synonyms, stored as a
+-- JSON array, can be indexed as plain comma separated names.
+CREATE VIRTUAL TABLE anime_cache_fts USING fts5(
+	title,
+	title_english,
+	title_romaji,
+	title_native,
+	synonyms,
+	description,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER anime_cache_fts_insert AFTER INSERT ON anime_cache BEGIN
+	INSERT INTO anime_cache_fts(rowid, title, title_english, title_romaji, title_native, synonyms, description)
+	VALUES (new.anilist_id, new.title, new.title_english, new.title_romaji, new.title_native,
+		(SELECT group_concat(value, ', ') FROM json_each(CASE WHEN json_valid(new.synonyms) THEN new.synonyms ELSE '[]' END)),
+		new.description);
+END;
+
+CREATE TRIGGER anime_cache_fts_delete AFTER DELETE ON anime_cache BEGIN
+	DELETE FROM anime_cache_fts WHERE rowid = old.anilist_id;
+END;
+
+CREATE TRIGGER anime_cache_fts_update AFTER UPDATE ON anime_cache BEGIN
+	DELETE FROM anime_cache_fts WHERE rowid = old.anilist_id;
+	INSERT INTO anime_cache_fts(rowid, title, title_english, title_romaji, title_native, synonyms, description)
+	VALUES (new.anilist_id, new.title, new.title_english, new.title_romaji, new.title_native,
+		(SELECT group_concat(value, ', ') FROM json_each(CASE WHEN json_valid(new.synonyms) THEN new.synonyms ELSE '[]' END)),
+		new.description);
+END;
+
+INSERT INTO anime_cache_fts(rowid, title, title_english, title_romaji, title_native, synonyms, description)
+SELECT anilist_id, title, title_english, title_romaji, title_native,
+	(SELECT group_concat(value, ', ') FROM json_each(CASE WHEN json_valid(synonyms) THEN synonyms ELSE '[]' END)),
+	description
+FROM anime_cache;
@@ -0,0 +1,5 @@
+ALTER TABLE watchlist DROP COLUMN completed_at;
+ALTER TABLE watchlist DROP COLUMN started_at;
+ALTER TABLE watchlist DROP COLUMN progress;
+ALTER TABLE watchlist DROP COLUMN score;
+ALTER TABLE watchlist DROP COLUMN status;
@@ -0,0 +1,8 @@
+-- Per-entry list state, so watchlists can round-trip with AniList and
+-- MyAnimeList. Statuses use AniList's names; entries added before this were
+-- all being watched. Scores are out of 10, 0 meaning unscored.
+ALTER TABLE watchlist ADD COLUMN status TEXT NOT NULL DEFAULT 'CURRENT';
+ALTER TABLE watchlist ADD COLUMN score REAL NOT NULL DEFAULT 0;
+ALTER TABLE watchlist ADD COLUMN progress INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE watchlist ADD COLUMN started_at DATE;
+ALTER TABLE watchlist ADD COLUMN completed_at DATE;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS anilist_outbox;
+DROP TABLE IF EXISTS anilist_sync_entries;
+DROP TABLE IF EXISTS anilist_account;
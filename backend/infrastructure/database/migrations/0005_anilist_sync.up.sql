@@ -0,0 +1,36 @@
+-- The AniList account the watchlist syncs with. There is at most one.
+CREATE TABLE anilist_account (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	user_id INTEGER NOT NULL,
+	user_name TEXT NOT NULL,
+	access_token TEXT NOT NULL,
+	expires_at TIMESTAMP,
+	connected_at TIMESTAMP NOT NULL,
+	last_sync_at TIMESTAMP,
+	last_error TEXT NOT NULL DEFAULT ''
+);
+
+-- The AniList state of each entry as of the last sync, to tell which side
+-- changed a field since.
+CREATE TABLE anilist_sync_entries (
+	anilist_id INTEGER PRIMARY KEY,
+	list_entry_id INTEGER NOT NULL,
+	status TEXT NOT NULL,
+	score REAL NOT NULL DEFAULT 0,
+	progress INTEGER NOT NULL DEFAULT 0,
+	started_at DATE,
+	completed_at DATE,
+	updated_at TIMESTAMP
+);
+
+-- Local watchlist changes waiting to be pushed to AniList, in order.
+CREATE TABLE anilist_outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	anilist_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	fields TEXT NOT NULL DEFAULT '[]',
+	changed_at TIMESTAMP NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_anilist_outbox_anilist_id ON anilist_outbox(anilist_id);
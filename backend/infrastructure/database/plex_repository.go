@@ -2,11 +2,14 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"anime-watchlist/backend/domain"
 )
 
+var _ domain.PlexShowStore = (*PlexRepository)(nil)
+
 type PlexRepository struct {
 	db *sql.DB
 }
@@ -15,151 +18,269 @@ func NewPlexRepository(db *sql.DB) *PlexRepository {
 	return &PlexRepository{db: db}
 }
 
+const plexShowColumns = `id, plex_id, source, external_id, title, guid, anilist_id, mapping_source, mapping_locked, ignored, year, episode_count, auto_mapped_at, last_updated`
+
+// UpsertPlexShow inserts or refreshes a show from Plex. An incoming AniList ID
+// only replaces the stored one when the show is not locked, and a missing one
+// never clears an existing mapping. Mapping changes are recorded in history.
 func (r *PlexRepository) UpsertPlexShow(show *domain.PlexShow) error {
+	return r.UpsertPlexShows([]domain.PlexShow{*show})
+}
+
+// UpsertPlexShows upserts shows the way UpsertPlexShow does, all in one
+// transaction. Nothing is saved if any show fails.
+func (r *PlexRepository) UpsertPlexShows(shows []domain.PlexShow) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	upserter, err := prepareShowUpsert(tx)
+	if err != nil {
+		return err
+	}
+	defer upserter.Close()
+
+	for i := range shows {
+		if err := upserter.upsert(&shows[i]); err != nil {
+			return fmt.Errorf("failed to save show %s: %w", shows[i].Title, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// showUpsert holds the statements of a batch upsert, prepared once for the
+// whole batch.
+type showUpsert struct {
+	owner   *sql.Stmt
+	state   *sql.Stmt
+	save    *sql.Stmt
+	history *sql.Stmt
+}
+
+func prepareShowUpsert(tx *sql.Tx) (*showUpsert, error) {
 	query := `
-		INSERT INTO plex_shows (plex_id, title, anilist_id, year, episode_count, last_updated)
-		VALUES (?, ?, ?, ?, ?, ?)
+		INSERT INTO plex_shows (plex_id, source, external_id, title, guid, anilist_id, mapping_source, year, episode_count, last_updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(plex_id) DO UPDATE SET
+			source = excluded.source,
+			external_id = excluded.external_id,
 			title = excluded.title,
-			anilist_id = excluded.anilist_id,
+			guid = excluded.guid,
+			anilist_id = CASE
+				WHEN plex_shows.mapping_locked OR excluded.anilist_id IS NULL THEN plex_shows.anilist_id
+				ELSE excluded.anilist_id
+			END,
+			mapping_source = CASE
+				WHEN plex_shows.mapping_locked OR excluded.anilist_id IS NULL THEN plex_shows.mapping_source
+				ELSE excluded.mapping_source
+			END,
 			year = excluded.year,
 			episode_count = excluded.episode_count,
 			last_updated = excluded.last_updated
 	`
 
-	var anilistID *int
-	if show.AnilistID != nil {
-		anilistID = show.AnilistID
+	u := &showUpsert{}
+	var err error
+	if u.owner, err = tx.Prepare(`SELECT source, external_id FROM plex_shows WHERE plex_id = ?`); err != nil {
+		return nil, err
 	}
-
-	_, err := r.db.Exec(query, show.PlexID, show.Title, anilistID, show.Year, show.EpisodeCount, show.LastUpdated)
-	return err
+	if u.state, err = tx.Prepare(mappingStateQuery); err != nil {
+		u.Close()
+		return nil, err
+	}
+	if u.save, err = tx.Prepare(query); err != nil {
+		u.Close()
+		return nil, err
+	}
+	if u.history, err = tx.Prepare(insertMappingHistoryQuery); err != nil {
+		u.Close()
+		return nil, err
+	}
+	return u, nil
 }
 
-func (r *PlexRepository) GetPlexShowByPlexID(plexID int) (*domain.PlexShow, error) {
-	query := `
-		SELECT id, plex_id, title, anilist_id, year, episode_count, last_updated
-		FROM plex_shows
-		WHERE plex_id = ?
-	`
+func (u *showUpsert) upsert(show *domain.PlexShow) error {
+	var owner domain.ShowRef
+	var externalID sql.NullString
+	err := u.owner.QueryRow(show.PlexID).Scan(&owner.Source, &externalID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	owner.ExternalID = externalID.String
+	if err == nil && !owner.SameItem(domain.RefOf(show)) {
+		return fmt.Errorf("%w: %d is %s item %s", domain.ErrShowIDCollision, show.PlexID, owner.Source, owner.ExternalID)
+	}
 
-	show := &domain.PlexShow{}
-	var anilistID *int
+	var previous *mappingState
+	if show.AnilistID != nil {
+		var err error
+		previous, err = scanMappingState(u.state.QueryRow(show.PlexID))
+		if err != nil && err != domain.ErrShowNotFound {
+			return err
+		}
+	}
 
-	err := r.db.QueryRow(query, plexID).Scan(
-		&show.ID,
-		&show.PlexID,
-		&show.Title,
-		&anilistID,
-		&show.Year,
-		&show.EpisodeCount,
-		&show.LastUpdated,
-	)
+	source := show.Source
+	if source == "" {
+		source = "plex"
+	}
 
-	if err != nil {
-		return nil, err
+	if _, err := u.save.Exec(show.PlexID, source, nullString(show.ExternalID), show.Title, show.GUID, show.AnilistID, nullString(show.MappingSource), show.Year, show.EpisodeCount, show.LastUpdated); err != nil {
+		return err
 	}
 
-	show.AnilistID = anilistID
-	return show, nil
+	if show.AnilistID != nil && (previous == nil || (!previous.locked && !sameAnilistID(previous.anilistID, show.AnilistID))) {
+		entry := &domain.MappingHistoryEntry{
+			PlexID:       show.PlexID,
+			Action:       domain.MappingActionMap,
+			NewAnilistID: show.AnilistID,
+			NewSource:    show.MappingSource,
+			Actor:        "sync",
+		}
+		if previous != nil {
+			entry.OldAnilistID = previous.anilistID
+			entry.OldSource = previous.source
+		}
+		entry.CreatedAt = time.Now()
+		if _, err := u.history.Exec(mappingHistoryArgs(entry)...); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (r *PlexRepository) GetAllPlexShows() ([]domain.PlexShow, error) {
-	query := `
-		SELECT id, plex_id, title, anilist_id, year, episode_count, last_updated
-		FROM plex_shows
-		ORDER BY title
-	`
+func (u *showUpsert) Close() {
+	for _, stmt := range []*sql.Stmt{u.owner, u.state, u.save, u.history} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
 
-	rows, err := r.db.Query(query)
+func (r *PlexRepository) GetShowRefs() (map[int]domain.ShowRef, error) {
+	rows, err := r.db.Query(`SELECT plex_id, source, external_id FROM plex_shows`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var shows []domain.PlexShow
+	refs := make(map[int]domain.ShowRef)
 	for rows.Next() {
-		show := domain.PlexShow{}
-		var anilistID *int
-
-		err := rows.Scan(
-			&show.ID,
-			&show.PlexID,
-			&show.Title,
-			&anilistID,
-			&show.Year,
-			&show.EpisodeCount,
-			&show.LastUpdated,
-		)
-		if err != nil {
+		var plexID int
+		var ref domain.ShowRef
+		var externalID sql.NullString
+		if err := rows.Scan(&plexID, &ref.Source, &externalID); err != nil {
 			return nil, err
 		}
+		ref.ExternalID = externalID.String
+		refs[plexID] = ref
+	}
 
-		show.AnilistID = anilistID
-		shows = append(shows, show)
+	return refs, rows.Err()
+}
+
+func (r *PlexRepository) GetPlexShowByPlexID(plexID int) (*domain.PlexShow, error) {
+	query := `
+		SELECT ` + plexShowColumns + `
+		FROM plex_shows
+		WHERE plex_id = ?
+	`
+
+	show, err := scanPlexShow(r.db.QueryRow(query, plexID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrShowNotFound
 	}
+	return show, err
+}
 
-	return shows, nil
+func (r *PlexRepository) GetAllPlexShows() ([]domain.PlexShow, error) {
+	query := `
+		SELECT ` + plexShowColumns + `
+		FROM plex_shows
+		ORDER BY title
+	`
+
+	return r.queryPlexShows(query)
 }
 
 func (r *PlexRepository) GetUnmappedShows() ([]domain.PlexShow, error) {
 	query := `
-		SELECT id, plex_id, title, anilist_id, year, episode_count, last_updated
+		SELECT ` + plexShowColumns + `
 		FROM plex_shows
-		WHERE anilist_id IS NULL
+		WHERE anilist_id IS NULL AND ignored = FALSE
 		ORDER BY title
 	`
 
+	return r.queryPlexShows(query)
+}
+
+func (r *PlexRepository) GetIgnoredShows() ([]domain.PlexShow, error) {
+	query := `
+		SELECT ` + plexShowColumns + `
+		FROM plex_shows
+		WHERE ignored = TRUE
+		ORDER BY title
+	`
+
+	return r.queryPlexShows(query)
+}
+
+// GetMappedAnilistIDs returns the distinct AniList IDs mapped to shows that
+// are not ignored.
+func (r *PlexRepository) GetMappedAnilistIDs() ([]int, error) {
+	query := `
+		SELECT DISTINCT anilist_id FROM plex_shows WHERE anilist_id IS NOT NULL AND ignored = FALSE
+	`
+
 	rows, err := r.db.Query(query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var shows []domain.PlexShow
+	var ids []int
 	for rows.Next() {
-		show := domain.PlexShow{}
-		var anilistID *int
-
-		err := rows.Scan(
-			&show.ID,
-			&show.PlexID,
-			&show.Title,
-			&anilistID,
-			&show.Year,
-			&show.EpisodeCount,
-			&show.LastUpdated,
-		)
-		if err != nil {
+		var id int
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-
-		show.AnilistID = anilistID
-		shows = append(shows, show)
+		ids = append(ids, id)
 	}
 
-	return shows, nil
+	return ids, rows.Err()
 }
 
 func (r *PlexRepository) GetShowsOnServer() (int, error) {
-	query := `SELECT COUNT(*) FROM plex_shows`
-	
+	query := `SELECT COUNT(*) FROM plex_shows WHERE ignored = FALSE`
+
 	var count int
 	err := r.db.QueryRow(query).Scan(&count)
 	return count, err
 }
 
 func (r *PlexRepository) GetMappedShowsCount() (int, error) {
-	query := `SELECT COUNT(*) FROM plex_shows WHERE anilist_id IS NOT NULL`
-	
+	query := `SELECT COUNT(*) FROM plex_shows WHERE anilist_id IS NOT NULL AND ignored = FALSE`
+
 	var count int
 	err := r.db.QueryRow(query).Scan(&count)
 	return count, err
 }
 
 func (r *PlexRepository) GetUnmappedShowsCount() (int, error) {
-	query := `SELECT COUNT(*) FROM plex_shows WHERE anilist_id IS NULL`
-	
+	query := `SELECT COUNT(*) FROM plex_shows WHERE anilist_id IS NULL AND ignored = FALSE`
+
+	var count int
+	err := r.db.QueryRow(query).Scan(&count)
+	return count, err
+}
+
+func (r *PlexRepository) GetIgnoredShowsCount() (int, error) {
+	query := `SELECT COUNT(*) FROM plex_shows WHERE ignored = TRUE`
+
 	var count int
 	err := r.db.QueryRow(query).Scan(&count)
 	return count, err
@@ -167,13 +288,242 @@ func (r *PlexRepository) GetUnmappedShowsCount() (int, error) {
 
 func (r *PlexRepository) SearchShowsOnServer(searchTerm string) ([]domain.PlexShow, error) {
 	query := `
-		SELECT id, plex_id, title, anilist_id, year, episode_count, last_updated
+		SELECT ` + plexShowColumns + `
 		FROM plex_shows
 		WHERE title LIKE ?
 		ORDER BY title
 	`
 
-	rows, err := r.db.Query(query, "%"+searchTerm+"%")
+	return r.queryPlexShows(query, "%"+searchTerm+"%")
+}
+
+// SearchShowTitles runs a ranked full-text search over show titles. Every
+// word of the query has to match the start of a word in the title.
+func (r *PlexRepository) SearchShowTitles(text string, limit int) ([]domain.SearchResult, error) {
+	match := ftsQuery(text)
+	if match == "" {
+		return nil, nil
+	}
+
+	query := `
+		SELECT s.plex_id, s.title, s.anilist_id,
+			highlight(plex_shows_fts, 0, '<mark>', '</mark>'), -bm25(plex_shows_fts)
+		FROM plex_shows_fts
+		JOIN plex_shows s ON s.id = plex_shows_fts.rowid
+		WHERE plex_shows_fts MATCH ?
+		ORDER BY bm25(plex_shows_fts)
+		LIMIT ?
+	`
+
+	rows, err := r.db.Query(query, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []domain.SearchResult
+	for rows.Next() {
+		result := domain.SearchResult{Kind: domain.SearchResultShow}
+		if err := rows.Scan(&result.PlexID, &result.Title, &result.AnilistID, &result.Highlight, &result.Score); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// UpdateShowMapping sets the AniList ID of a show. Manual mappings lock the
+// show; other sources are refused with ErrMappingLocked once it is locked.
+func (r *PlexRepository) UpdateShowMapping(plexID int, anilistID int, source string, actor string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	previous, err := getMappingState(tx, plexID)
+	if err != nil {
+		return err
+	}
+
+	if previous.locked && source != domain.MappingSourceManual {
+		return domain.ErrMappingLocked
+	}
+
+	if err := setMapping(tx, plexID, &anilistID, source); err != nil {
+		return err
+	}
+
+	if !sameAnilistID(previous.anilistID, &anilistID) || previous.source != source {
+		err := insertMappingHistory(tx, &domain.MappingHistoryEntry{
+			PlexID:       plexID,
+			Action:       domain.MappingActionMap,
+			OldAnilistID: previous.anilistID,
+			NewAnilistID: &anilistID,
+			OldSource:    previous.source,
+			NewSource:    source,
+			Actor:        actor,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClearShowMapping removes the AniList ID and lock from a show.
+func (r *PlexRepository) ClearShowMapping(plexID int, actor string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	previous, err := getMappingState(tx, plexID)
+	if err != nil {
+		return err
+	}
+
+	if previous.anilistID == nil && !previous.locked {
+		return nil
+	}
+
+	if err := setMapping(tx, plexID, nil, ""); err != nil {
+		return err
+	}
+
+	err = insertMappingHistory(tx, &domain.MappingHistoryEntry{
+		PlexID:       plexID,
+		Action:       domain.MappingActionUnmap,
+		OldAnilistID: previous.anilistID,
+		OldSource:    previous.source,
+		Actor:        actor,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UndoLastMapping restores the mapping from before the most recent change that
+// has not been undone yet, and records the undo itself in history.
+func (r *PlexRepository) UndoLastMapping(plexID int, actor string) (*domain.MappingHistoryEntry, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + mappingHistoryColumns + `
+		FROM mapping_history
+		WHERE plex_id = ? AND undone = FALSE AND action != ?
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	last, err := scanMappingHistory(tx.QueryRow(query, plexID, domain.MappingActionUndo))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNoMappingHistory
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := getMappingState(tx, plexID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setMapping(tx, plexID, last.OldAnilistID, last.OldSource); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE mapping_history SET undone = TRUE WHERE id = ?`, last.ID); err != nil {
+		return nil, err
+	}
+
+	entry := &domain.MappingHistoryEntry{
+		PlexID:       plexID,
+		Action:       domain.MappingActionUndo,
+		OldAnilistID: current.anilistID,
+		NewAnilistID: last.OldAnilistID,
+		OldSource:    current.source,
+		NewSource:    last.OldSource,
+		Actor:        actor,
+	}
+	if err := insertMappingHistory(tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (r *PlexRepository) GetMappingHistory(plexID int) ([]domain.MappingHistoryEntry, error) {
+	query := `
+		SELECT ` + mappingHistoryColumns + `
+		FROM mapping_history
+		WHERE plex_id = ?
+		ORDER BY id DESC
+	`
+
+	rows, err := r.db.Query(query, plexID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []domain.MappingHistoryEntry{}
+	for rows.Next() {
+		entry, err := scanMappingHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *entry)
+	}
+
+	return history, rows.Err()
+}
+
+// MarkAutoMapped records that auto-map has looked at a show, whatever the
+// outcome, so scheduled runs only pick up shows that are new since.
+func (r *PlexRepository) MarkAutoMapped(plexID int) error {
+	_, err := r.db.Exec(`UPDATE plex_shows SET auto_mapped_at = ? WHERE plex_id = ?`, time.Now(), plexID)
+	return err
+}
+
+func (r *PlexRepository) SetShowIgnored(plexID int, ignored bool) error {
+	query := `
+		UPDATE plex_shows
+		SET ignored = ?, last_updated = ?
+		WHERE plex_id = ?
+	`
+
+	result, err := r.db.Exec(query, ignored, time.Now(), plexID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrShowNotFound
+	}
+
+	return nil
+}
+
+func (r *PlexRepository) queryPlexShows(query string, args ...interface{}) ([]domain.PlexShow, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -181,36 +531,150 @@ func (r *PlexRepository) SearchShowsOnServer(searchTerm string) ([]domain.PlexSh
 
 	var shows []domain.PlexShow
 	for rows.Next() {
-		show := domain.PlexShow{}
-		var anilistID *int
-
-		err := rows.Scan(
-			&show.ID,
-			&show.PlexID,
-			&show.Title,
-			&anilistID,
-			&show.Year,
-			&show.EpisodeCount,
-			&show.LastUpdated,
-		)
+		show, err := scanPlexShow(rows)
 		if err != nil {
 			return nil, err
 		}
+		shows = append(shows, *show)
+	}
 
-		show.AnilistID = anilistID
-		shows = append(shows, show)
+	return shows, rows.Err()
+}
+
+func scanPlexShow(row rowScanner) (*domain.PlexShow, error) {
+	show := &domain.PlexShow{}
+	var anilistID *int
+	var externalID, guid, mappingSource sql.NullString
+	var autoMappedAt sql.NullTime
+
+	err := row.Scan(
+		&show.ID,
+		&show.PlexID,
+		&show.Source,
+		&externalID,
+		&show.Title,
+		&guid,
+		&anilistID,
+		&mappingSource,
+		&show.MappingLocked,
+		&show.Ignored,
+		&show.Year,
+		&show.EpisodeCount,
+		&autoMappedAt,
+		&show.LastUpdated,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	show.AnilistID = anilistID
+	show.ExternalID = externalID.String
+	show.GUID = guid.String
+	if autoMappedAt.Valid {
+		show.AutoMappedAt = &autoMappedAt.Time
 	}
+	show.MappingSource = mappingSource.String
+	return show, nil
+}
 
-	return shows, nil
+type mappingState struct {
+	anilistID *int
+	source    string
+	locked    bool
 }
 
-func (r *PlexRepository) UpdateShowMapping(plexID int, anilistID int) error {
+const mappingStateQuery = `SELECT anilist_id, mapping_source, mapping_locked FROM plex_shows WHERE plex_id = ?`
+
+func getMappingState(tx *sql.Tx, plexID int) (*mappingState, error) {
+	return scanMappingState(tx.QueryRow(mappingStateQuery, plexID))
+}
+
+func scanMappingState(row rowScanner) (*mappingState, error) {
+	state := &mappingState{}
+	var source sql.NullString
+
+	err := row.Scan(&state.anilistID, &source, &state.locked)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrShowNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state.source = source.String
+	return state, nil
+}
+
+func setMapping(tx *sql.Tx, plexID int, anilistID *int, source string) error {
 	query := `
-		UPDATE plex_shows 
-		SET anilist_id = ?, last_updated = ?
+		UPDATE plex_shows
+		SET anilist_id = ?, mapping_source = ?, mapping_locked = ?, last_updated = ?
 		WHERE plex_id = ?
 	`
 
-	_, err := r.db.Exec(query, anilistID, time.Now(), plexID)
+	_, err := tx.Exec(query, anilistID, nullString(source), source == domain.MappingSourceManual, time.Now(), plexID)
 	return err
-} 
\ No newline at end of file
+}
+
+const mappingHistoryColumns = `id, plex_id, action, old_anilist_id, new_anilist_id, old_source, new_source, actor, undone, created_at`
+
+const insertMappingHistoryQuery = `
+	INSERT INTO mapping_history (plex_id, action, old_anilist_id, new_anilist_id, old_source, new_source, actor, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+func insertMappingHistory(tx *sql.Tx, entry *domain.MappingHistoryEntry) error {
+	entry.CreatedAt = time.Now()
+	result, err := tx.Exec(insertMappingHistoryQuery, mappingHistoryArgs(entry)...)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	entry.ID = int(id)
+	return nil
+}
+
+func mappingHistoryArgs(entry *domain.MappingHistoryEntry) []interface{} {
+	return []interface{}{entry.PlexID, entry.Action, entry.OldAnilistID, entry.NewAnilistID,
+		nullString(entry.OldSource), nullString(entry.NewSource), entry.Actor, entry.CreatedAt}
+}
+
+func scanMappingHistory(row rowScanner) (*domain.MappingHistoryEntry, error) {
+	entry := &domain.MappingHistoryEntry{}
+	var oldSource, newSource sql.NullString
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.PlexID,
+		&entry.Action,
+		&entry.OldAnilistID,
+		&entry.NewAnilistID,
+		&oldSource,
+		&newSource,
+		&entry.Actor,
+		&entry.Undone,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.OldSource = oldSource.String
+	entry.NewSource = newSource.String
+	return entry, nil
+}
+
+func sameAnilistID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -7,6 +7,8 @@ import (
 	"anime-watchlist/backend/domain"
 )
 
+var _ domain.WatchlistStore = (*WatchlistRepository)(nil)
+
 type WatchlistRepository struct {
 	db *Database
 }
@@ -17,7 +19,7 @@ func NewWatchlistRepository(db *Database) *WatchlistRepository {
 
 func (r *WatchlistRepository) GetWatchlist() ([]domain.WatchlistItem, error) {
 	query := `
-		SELECT id, anilist_id, added_at
+		SELECT id, anilist_id, status, score, progress, started_at, completed_at, added_at
 		FROM watchlist
 		ORDER BY added_at DESC
 	`
@@ -31,7 +33,7 @@ func (r *WatchlistRepository) GetWatchlist() ([]domain.WatchlistItem, error) {
 	var items []domain.WatchlistItem
 	for rows.Next() {
 		var item domain.WatchlistItem
-		err := rows.Scan(&item.ID, &item.AnilistID, &item.AddedAt)
+		err := rows.Scan(&item.ID, &item.AnilistID, &item.Status, &item.Score, &item.Progress, &item.StartedAt, &item.CompletedAt, &item.AddedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan watchlist item: %w", err)
 		}
@@ -42,12 +44,18 @@ func (r *WatchlistRepository) GetWatchlist() ([]domain.WatchlistItem, error) {
 }
 
 func (r *WatchlistRepository) AddToWatchlist(anilistID int) error {
+	return r.AddToWatchlistAt(anilistID, time.Now())
+}
+
+// AddToWatchlistAt adds an entry with the date it was originally added, for
+// imports.
+func (r *WatchlistRepository) AddToWatchlistAt(anilistID int, addedAt time.Time) error {
 	query := `
 		INSERT INTO watchlist (anilist_id, added_at)
 		VALUES (?, ?)
 	`
 
-	_, err := r.db.DB.Exec(query, anilistID, time.Now())
+	_, err := r.db.DB.Exec(query, anilistID, addedAt)
 	if err != nil {
 		return fmt.Errorf("failed to add to watchlist: %w", err)
 	}
@@ -55,6 +63,31 @@ func (r *WatchlistRepository) AddToWatchlist(anilistID int) error {
 	return nil
 }
 
+func (r *WatchlistRepository) SaveWatchlistItem(item *domain.WatchlistItem) error {
+	addedAt := item.AddedAt
+	if addedAt.IsZero() {
+		addedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO watchlist (anilist_id, status, score, progress, started_at, completed_at, added_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(anilist_id) DO UPDATE SET
+			status = excluded.status,
+			score = excluded.score,
+			progress = excluded.progress,
+			started_at = excluded.started_at,
+			completed_at = excluded.completed_at
+	`
+
+	_, err := r.db.DB.Exec(query, item.AnilistID, item.Status, item.Score, item.Progress, item.StartedAt, item.CompletedAt, addedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save watchlist item: %w", err)
+	}
+
+	return nil
+}
+
 func (r *WatchlistRepository) RemoveFromWatchlist(anilistID int) error {
 	query := `
 		DELETE FROM watchlist
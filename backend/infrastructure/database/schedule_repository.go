@@ -0,0 +1,96 @@
+package database
+
+import (
+	"database/sql"
+
+	"anime-watchlist/backend/domain"
+)
+
+type ScheduleRepository struct {
+	db *sql.DB
+}
+
+func NewScheduleRepository(db *sql.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+const scheduleColumns = `name, spec, last_run_at, next_run_at, last_status, last_error, last_duration_ms`
+
+func (r *ScheduleRepository) GetSchedule(name string) (*domain.ScheduleState, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM schedules
+		WHERE name = ?
+	`
+
+	state, err := scanSchedule(r.db.QueryRow(query, name))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return state, err
+}
+
+func (r *ScheduleRepository) GetSchedules() ([]domain.ScheduleState, error) {
+	query := `
+		SELECT ` + scheduleColumns + `
+		FROM schedules
+		ORDER BY name
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var states []domain.ScheduleState
+	for rows.Next() {
+		state, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, *state)
+	}
+
+	return states, rows.Err()
+}
+
+func (r *ScheduleRepository) SaveSchedule(state *domain.ScheduleState) error {
+	query := `
+		INSERT INTO schedules (name, spec, last_run_at, next_run_at, last_status, last_error, last_duration_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			spec = excluded.spec,
+			last_run_at = excluded.last_run_at,
+			next_run_at = excluded.next_run_at,
+			last_status = excluded.last_status,
+			last_error = excluded.last_error,
+			last_duration_ms = excluded.last_duration_ms
+	`
+
+	_, err := r.db.Exec(query, state.Name, state.Spec, state.LastRunAt, state.NextRunAt,
+		nullString(state.LastStatus), nullString(state.LastError), state.DurationMS)
+	return err
+}
+
+func scanSchedule(row rowScanner) (*domain.ScheduleState, error) {
+	var state domain.ScheduleState
+	var lastRunAt, nextRunAt sql.NullTime
+	var lastStatus, lastError sql.NullString
+
+	err := row.Scan(&state.Name, &state.Spec, &lastRunAt, &nextRunAt, &lastStatus, &lastError, &state.DurationMS)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastRunAt.Valid {
+		state.LastRunAt = &lastRunAt.Time
+	}
+	if nextRunAt.Valid {
+		state.NextRunAt = &nextRunAt.Time
+	}
+	state.LastStatus = lastStatus.String
+	state.LastError = lastError.String
+
+	return &state, nil
+}
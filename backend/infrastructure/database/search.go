@@ -0,0 +1,21 @@
+package database
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ftsQuery turns free text into an FTS5 query that matches every word as a
+// prefix. Words are quoted so FTS5 syntax in the input is never interpreted.
+// It returns "" when the text has no words.
+func ftsQuery(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + word + `"*`
+	}
+	return strings.Join(terms, " ")
+}
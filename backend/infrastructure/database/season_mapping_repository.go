@@ -0,0 +1,188 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+type SeasonMappingRepository struct {
+	db *sql.DB
+}
+
+func NewSeasonMappingRepository(db *sql.DB) *SeasonMappingRepository {
+	return &SeasonMappingRepository{db: db}
+}
+
+const seasonMappingColumns = `plex_id, season_number, anilist_id, episode_offset, episode_count, anilist_episodes, source, created_at`
+
+func (r *SeasonMappingRepository) GetSeasonMappings(plexID int) ([]domain.SeasonMapping, error) {
+	query := `
+		SELECT ` + seasonMappingColumns + `
+		FROM plex_season_mappings
+		WHERE plex_id = ?
+		ORDER BY season_number, episode_offset
+	`
+
+	return r.querySeasonMappings(query, plexID)
+}
+
+func (r *SeasonMappingRepository) GetSeasonMappingsByAnilistID(anilistID int) ([]domain.SeasonMapping, error) {
+	query := `
+		SELECT ` + seasonMappingColumns + `
+		FROM plex_season_mappings
+		WHERE anilist_id = ?
+		ORDER BY plex_id, season_number, episode_offset
+	`
+
+	return r.querySeasonMappings(query, anilistID)
+}
+
+func (r *SeasonMappingRepository) GetAllSeasonMappings() ([]domain.SeasonMapping, error) {
+	query := `
+		SELECT ` + seasonMappingColumns + `
+		FROM plex_season_mappings
+		ORDER BY plex_id, season_number, episode_offset
+	`
+
+	return r.querySeasonMappings(query)
+}
+
+// GetMappedAnilistIDs returns the distinct AniList IDs mapped to seasons.
+func (r *SeasonMappingRepository) GetMappedAnilistIDs() ([]int, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT anilist_id FROM plex_season_mappings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// ReplaceProposedMappings swaps the non-manual season mappings of a show for
+// a new proposal. Manual mappings are left untouched, as are proposals for
+// the seasons or AniList entries they already cover.
+func (r *SeasonMappingRepository) ReplaceProposedMappings(plexID int, mappings []domain.SeasonMapping) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM plex_season_mappings WHERE plex_id = ? AND source != ?`, plexID, domain.MappingSourceManual); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT season_number, anilist_id FROM plex_season_mappings WHERE plex_id = ?`, plexID)
+	if err != nil {
+		return err
+	}
+	manualSeasons := make(map[int]bool)
+	manualEntries := make(map[int]bool)
+	for rows.Next() {
+		var season, anilistID int
+		if err := rows.Scan(&season, &anilistID); err != nil {
+			rows.Close()
+			return err
+		}
+		manualSeasons[season] = true
+		manualEntries[anilistID] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range mappings {
+		if manualSeasons[mappings[i].SeasonNumber] || manualEntries[mappings[i].AnilistID] {
+			continue
+		}
+		if err := insertSeasonMapping(tx, &mappings[i]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteProposedMappings removes the non-manual season mappings of a show.
+func (r *SeasonMappingRepository) DeleteProposedMappings(plexID int) error {
+	_, err := r.db.Exec(`DELETE FROM plex_season_mappings WHERE plex_id = ? AND source != ?`, plexID, domain.MappingSourceManual)
+	return err
+}
+
+// SetSeasonMapping stores a manual season mapping, replacing any mapping of
+// the same AniList entry for the show.
+func (r *SeasonMappingRepository) SetSeasonMapping(mapping *domain.SeasonMapping) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM plex_season_mappings WHERE plex_id = ? AND anilist_id = ?`, mapping.PlexID, mapping.AnilistID); err != nil {
+		return err
+	}
+
+	if err := insertSeasonMapping(tx, mapping); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *SeasonMappingRepository) DeleteSeasonMappings(plexID int) error {
+	_, err := r.db.Exec(`DELETE FROM plex_season_mappings WHERE plex_id = ?`, plexID)
+	return err
+}
+
+func (r *SeasonMappingRepository) querySeasonMappings(query string, args ...interface{}) ([]domain.SeasonMapping, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mappings := []domain.SeasonMapping{}
+	for rows.Next() {
+		var mapping domain.SeasonMapping
+		err := rows.Scan(
+			&mapping.PlexID,
+			&mapping.SeasonNumber,
+			&mapping.AnilistID,
+			&mapping.EpisodeOffset,
+			&mapping.EpisodeCount,
+			&mapping.AnilistEpisodes,
+			&mapping.Source,
+			&mapping.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, mapping)
+	}
+
+	return mappings, rows.Err()
+}
+
+func insertSeasonMapping(tx *sql.Tx, mapping *domain.SeasonMapping) error {
+	query := `
+		INSERT INTO plex_season_mappings (plex_id, season_number, anilist_id, episode_offset, episode_count, anilist_episodes, source, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	mapping.CreatedAt = time.Now()
+	_, err := tx.Exec(query, mapping.PlexID, mapping.SeasonNumber, mapping.AnilistID, mapping.EpisodeOffset,
+		mapping.EpisodeCount, mapping.AnilistEpisodes, mapping.Source, mapping.CreatedAt)
+	return err
+}
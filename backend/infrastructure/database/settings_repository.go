@@ -0,0 +1,66 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SettingsRepository stores user settings as string key/value pairs.
+type SettingsRepository struct {
+	db *sql.DB
+}
+
+func NewSettingsRepository(db *sql.DB) *SettingsRepository {
+	return &SettingsRepository{db: db}
+}
+
+func (r *SettingsRepository) GetSettings() (map[string]string, error) {
+	rows, err := r.db.Query(`SELECT key, value FROM settings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		settings[key] = value
+	}
+
+	return settings, rows.Err()
+}
+
+// UpdateSettings sets the given keys and deletes those mapped to nil, all in
+// one transaction. Other keys are left alone.
+func (r *SettingsRepository) UpdateSettings(changes map[string]*string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO settings (key, value, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			value = excluded.value,
+			updated_at = excluded.updated_at
+	`
+
+	now := time.Now()
+	for key, value := range changes {
+		if value == nil {
+			_, err = tx.Exec(`DELETE FROM settings WHERE key = ?`, key)
+		} else {
+			_, err = tx.Exec(query, key, *value, now)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"anime-watchlist/backend/domain"
+)
+
+// ShowQualityRepository stores the per-show media quality summary. List
+// columns hold JSON arrays so filters can use json_each.
+type ShowQualityRepository struct {
+	db *sql.DB
+}
+
+func NewShowQualityRepository(db *sql.DB) *ShowQualityRepository {
+	return &ShowQualityRepository{db: db}
+}
+
+const showQualityColumns = `plex_id, resolutions, max_height, video_codecs, audio_languages, subtitle_languages, episodes_analyzed, updated_at`
+
+func (r *ShowQualityRepository) SaveShowQuality(quality *domain.ShowQuality) error {
+	lists := make([]string, 0, 4)
+	for _, values := range [][]string{quality.Resolutions, quality.VideoCodecs, quality.AudioLanguages, quality.SubtitleLanguages} {
+		if values == nil {
+			values = []string{}
+		}
+		encoded, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		lists = append(lists, string(encoded))
+	}
+
+	query := `
+		INSERT INTO plex_show_quality (` + showQualityColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(plex_id) DO UPDATE SET
+			resolutions = excluded.resolutions,
+			max_height = excluded.max_height,
+			video_codecs = excluded.video_codecs,
+			audio_languages = excluded.audio_languages,
+			subtitle_languages = excluded.subtitle_languages,
+			episodes_analyzed = excluded.episodes_analyzed,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := r.db.Exec(query, quality.PlexID, lists[0], quality.MaxHeight, lists[1], lists[2], lists[3],
+		quality.EpisodesAnalyzed, quality.UpdatedAt)
+	return err
+}
+
+// GetShowQualities returns every stored summary keyed by Plex ID.
+func (r *ShowQualityRepository) GetShowQualities() (map[int]*domain.ShowQuality, error) {
+	query := `
+		SELECT ` + showQualityColumns + `
+		FROM plex_show_quality
+	`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	qualities := make(map[int]*domain.ShowQuality)
+	for rows.Next() {
+		quality, err := scanShowQuality(rows)
+		if err != nil {
+			return nil, err
+		}
+		qualities[quality.PlexID] = quality
+	}
+
+	return qualities, rows.Err()
+}
+
+// GetPlexIDsByQuality returns the shows whose summary matches every set field
+// of the filter. Shows that were never analyzed do not match.
+func (r *ShowQualityRepository) GetPlexIDsByQuality(filter domain.QualityFilter) ([]int, error) {
+	conditions := []string{"1 = 1"}
+	var args []interface{}
+
+	hasValue := func(column, value string) {
+		conditions = append(conditions, `EXISTS (SELECT 1 FROM json_each(`+column+`) WHERE LOWER(value) = LOWER(?))`)
+		args = append(args, value)
+	}
+	if filter.SubtitleLanguage != "" {
+		hasValue("subtitle_languages", filter.SubtitleLanguage)
+	}
+	if filter.AudioLanguage != "" {
+		hasValue("audio_languages", filter.AudioLanguage)
+	}
+	if filter.VideoCodec != "" {
+		hasValue("video_codecs", filter.VideoCodec)
+	}
+	if filter.MinHeight > 0 {
+		conditions = append(conditions, "max_height >= ?")
+		args = append(args, filter.MinHeight)
+	}
+
+	query := `
+		SELECT plex_id
+		FROM plex_show_quality
+		WHERE ` + strings.Join(conditions, " AND ")
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func scanShowQuality(row rowScanner) (*domain.ShowQuality, error) {
+	var quality domain.ShowQuality
+	var resolutions, videoCodecs, audioLanguages, subtitleLanguages string
+
+	err := row.Scan(
+		&quality.PlexID,
+		&resolutions,
+		&quality.MaxHeight,
+		&videoCodecs,
+		&audioLanguages,
+		&subtitleLanguages,
+		&quality.EpisodesAnalyzed,
+		&quality.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := map[*[]string]string{
+		&quality.Resolutions:       resolutions,
+		&quality.VideoCodecs:       videoCodecs,
+		&quality.AudioLanguages:    audioLanguages,
+		&quality.SubtitleLanguages: subtitleLanguages,
+	}
+	for target, encoded := range lists {
+		if err := json.Unmarshal([]byte(encoded), target); err != nil {
+			return nil, err
+		}
+	}
+
+	return &quality, nil
+}
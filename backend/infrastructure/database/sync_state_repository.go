@@ -0,0 +1,57 @@
+package database
+
+import (
+	"database/sql"
+
+	"anime-watchlist/backend/domain"
+)
+
+type SyncStateRepository struct {
+	db *sql.DB
+}
+
+func NewSyncStateRepository(db *sql.DB) *SyncStateRepository {
+	return &SyncStateRepository{db: db}
+}
+
+// GetSyncState returns nil when the library has never been synced.
+func (r *SyncStateRepository) GetSyncState(source string, libraryID string) (*domain.SyncState, error) {
+	query := `
+		SELECT source, library_id, last_sync_at, last_full_sync_at
+		FROM sync_state
+		WHERE source = ? AND library_id = ?
+	`
+
+	var state domain.SyncState
+	var lastSyncAt, lastFullSyncAt sql.NullTime
+
+	err := r.db.QueryRow(query, source, libraryID).Scan(&state.Source, &state.LibraryID, &lastSyncAt, &lastFullSyncAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if lastSyncAt.Valid {
+		state.LastSyncAt = &lastSyncAt.Time
+	}
+	if lastFullSyncAt.Valid {
+		state.LastFullSyncAt = &lastFullSyncAt.Time
+	}
+
+	return &state, nil
+}
+
+func (r *SyncStateRepository) SaveSyncState(state *domain.SyncState) error {
+	query := `
+		INSERT INTO sync_state (source, library_id, last_sync_at, last_full_sync_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(source, library_id) DO UPDATE SET
+			last_sync_at = excluded.last_sync_at,
+			last_full_sync_at = excluded.last_full_sync_at
+	`
+
+	_, err := r.db.Exec(query, state.Source, state.LibraryID, state.LastSyncAt, state.LastFullSyncAt)
+	return err
+}
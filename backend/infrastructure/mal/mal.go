@@ -0,0 +1,263 @@
+// Package mal reads and writes MyAnimeList list exports: the XML file MAL's
+// export page produces, which it serves gzipped, and which its import page
+// accepts back.
+package mal
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses as MAL writes them in my_status.
+const (
+	StatusWatching    = "Watching"
+	StatusCompleted   = "Completed"
+	StatusOnHold      = "On-Hold"
+	StatusDropped     = "Dropped"
+	StatusPlanToWatch = "Plan to Watch"
+)
+
+// numericStatuses are the codes older exports use instead of names.
+var numericStatuses = map[string]string{
+	"1": StatusWatching,
+	"2": StatusCompleted,
+	"3": StatusOnHold,
+	"4": StatusDropped,
+	"6": StatusPlanToWatch,
+}
+
+// MaxExportSize caps an export once unpacked, so a small gzipped upload
+// cannot expand into more than an uncompressed one may be. Even large lists
+// are a few megabytes.
+const MaxExportSize = 32 << 20
+
+// ErrTooLarge is returned by Read for exports over MaxExportSize.
+var ErrTooLarge = errors.New("MAL export is over 32 MB unpacked")
+
+// noDate is how MAL writes a date that was never set.
+const noDate = "0000-00-00"
+
+// Entry is one anime on a MAL list. Score is out of 10, 0 meaning unscored.
+type Entry struct {
+	MalID           int
+	Title           string
+	Type            string
+	Episodes        int
+	Status          string
+	Score           int
+	WatchedEpisodes int
+	StartDate       *time.Time
+	FinishDate      *time.Time
+	TimesWatched    int
+	Rewatching      bool
+}
+
+type document struct {
+	XMLName xml.Name  `xml:"myanimelist"`
+	Info    info      `xml:"myinfo"`
+	Anime   []element `xml:"anime"`
+}
+
+type info struct {
+	ExportType  int `xml:"user_export_type"`
+	Total       int `xml:"user_total_anime"`
+	Watching    int `xml:"user_total_watching"`
+	Completed   int `xml:"user_total_completed"`
+	OnHold      int `xml:"user_total_onhold"`
+	Dropped     int `xml:"user_total_dropped"`
+	PlanToWatch int `xml:"user_total_plantowatch"`
+}
+
+type element struct {
+	MalID           string `xml:"series_animedb_id"`
+	Title           cdata  `xml:"series_title"`
+	Type            string `xml:"series_type"`
+	Episodes        string `xml:"series_episodes"`
+	MyID            int    `xml:"my_id"`
+	WatchedEpisodes string `xml:"my_watched_episodes"`
+	StartDate       string `xml:"my_start_date"`
+	FinishDate      string `xml:"my_finish_date"`
+	Score           string `xml:"my_score"`
+	Status          string `xml:"my_status"`
+	Comments        cdata  `xml:"my_comments"`
+	TimesWatched    string `xml:"my_times_watched"`
+	Tags            cdata  `xml:"my_tags"`
+	Rewatching      string `xml:"my_rewatching"`
+	UpdateOnImport  int    `xml:"update_on_import"`
+}
+
+// cdata keeps free text in CDATA sections like MAL's own exports. Reading
+// accepts plain text as well.
+type cdata struct {
+	Text string `xml:",cdata"`
+}
+
+// Read parses an export, gunzipping it first when it is compressed. Exports
+// over MaxExportSize fail with ErrTooLarge.
+func Read(r io.Reader) ([]Entry, error) {
+	buffered := bufio.NewReader(r)
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzipped export: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = buffered
+	}
+
+	var doc document
+	if err := xml.NewDecoder(&limitReader{r: r, n: MaxExportSize}).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to parse MAL export: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(doc.Anime))
+	for i, el := range doc.Anime {
+		entry, err := el.entry()
+		if err != nil {
+			return nil, fmt.Errorf("anime %d in MAL export: %w", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// limitReader fails once more than n bytes are read. io.LimitReader would
+// end the stream instead, which reads as a truncated export.
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return 0, ErrTooLarge
+	}
+	return n, err
+}
+
+func (el element) entry() (Entry, error) {
+	malID, err := parseInt(el.MalID)
+	if err != nil || malID <= 0 {
+		return Entry{}, fmt.Errorf("invalid series_animedb_id %q", el.MalID)
+	}
+
+	status := strings.TrimSpace(el.Status)
+	if named, ok := numericStatuses[status]; ok {
+		status = named
+	}
+	switch status {
+	case StatusWatching, StatusCompleted, StatusOnHold, StatusDropped, StatusPlanToWatch:
+	default:
+		return Entry{}, fmt.Errorf("unknown my_status %q for %s", el.Status, el.Title.Text)
+	}
+
+	// Counts are optional in hand-edited files, so blanks and junk read as 0
+	episodes, _ := parseInt(el.Episodes)
+	watched, _ := parseInt(el.WatchedEpisodes)
+	score, _ := parseInt(el.Score)
+	timesWatched, _ := parseInt(el.TimesWatched)
+	rewatching, _ := parseInt(el.Rewatching)
+
+	return Entry{
+		MalID:           malID,
+		Title:           strings.TrimSpace(el.Title.Text),
+		Type:            strings.TrimSpace(el.Type),
+		Episodes:        episodes,
+		Status:          status,
+		Score:           score,
+		WatchedEpisodes: watched,
+		StartDate:       parseDate(el.StartDate),
+		FinishDate:      parseDate(el.FinishDate),
+		TimesWatched:    timesWatched,
+		Rewatching:      rewatching == 1,
+	}, nil
+}
+
+// Write writes entries as an export MAL's import page accepts. Every entry
+// is marked to update an existing one on import.
+func Write(w io.Writer, entries []Entry) error {
+	doc := document{Info: info{ExportType: 1, Total: len(entries)}}
+	for _, entry := range entries {
+		switch entry.Status {
+		case StatusWatching:
+			doc.Info.Watching++
+		case StatusCompleted:
+			doc.Info.Completed++
+		case StatusOnHold:
+			doc.Info.OnHold++
+		case StatusDropped:
+			doc.Info.Dropped++
+		case StatusPlanToWatch:
+			doc.Info.PlanToWatch++
+		}
+
+		rewatching := "0"
+		if entry.Rewatching {
+			rewatching = "1"
+		}
+		doc.Anime = append(doc.Anime, element{
+			MalID:           strconv.Itoa(entry.MalID),
+			Title:           cdata{Text: entry.Title},
+			Type:            entry.Type,
+			Episodes:        strconv.Itoa(entry.Episodes),
+			WatchedEpisodes: strconv.Itoa(entry.WatchedEpisodes),
+			StartDate:       formatDate(entry.StartDate),
+			FinishDate:      formatDate(entry.FinishDate),
+			Score:           strconv.Itoa(entry.Score),
+			Status:          entry.Status,
+			TimesWatched:    strconv.Itoa(entry.TimesWatched),
+			Rewatching:      rewatching,
+			UpdateOnImport:  1,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "\t")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write MAL export: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func parseInt(value string) (int, error) {
+	return strconv.Atoi(strings.TrimSpace(value))
+}
+
+// parseDate returns nil for unset dates and for partial ones such as
+// 2019-00-00, which MAL allows.
+func parseDate(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" || value == noDate {
+		return nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil
+	}
+	return &date
+}
+
+func formatDate(date *time.Time) string {
+	if date == nil {
+		return noDate
+	}
+	return date.Format("2006-01-02")
+}
@@ -0,0 +1,170 @@
+package mal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// export is trimmed from a real MAL export, with the numeric statuses older
+// exports use mixed in.
+const export = `<?xml version="1.0" encoding="UTF-8" ?>
+<myanimelist>
+	<myinfo>
+		<user_export_type>1</user_export_type>
+		<user_total_anime>3</user_total_anime>
+	</myinfo>
+	<anime>
+		<series_animedb_id>52991</series_animedb_id>
+		<series_title><![CDATA[Sousou no Frieren]]></series_title>
+		<series_type>TV</series_type>
+		<series_episodes>28</series_episodes>
+		<my_id>0</my_id>
+		<my_watched_episodes>28</my_watched_episodes>
+		<my_start_date>2023-09-29</my_start_date>
+		<my_finish_date>2024-03-22</my_finish_date>
+		<my_score>10</my_score>
+		<my_status>Completed</my_status>
+		<my_comments><![CDATA[]]></my_comments>
+		<my_times_watched>1</my_times_watched>
+		<my_rewatching>0</my_rewatching>
+		<update_on_import>0</update_on_import>
+	</anime>
+	<anime>
+		<series_animedb_id>1</series_animedb_id>
+		<series_title>Cowboy Bebop</series_title>
+		<series_type>TV</series_type>
+		<series_episodes>26</series_episodes>
+		<my_watched_episodes>5</my_watched_episodes>
+		<my_start_date>2019-00-00</my_start_date>
+		<my_finish_date>0000-00-00</my_finish_date>
+		<my_score>0</my_score>
+		<my_status>1</my_status>
+		<my_rewatching>1</my_rewatching>
+	</anime>
+	<anime>
+		<series_animedb_id> 47917 </series_animedb_id>
+		<series_title><![CDATA[Bocchi the Rock!]]></series_title>
+		<series_episodes></series_episodes>
+		<my_watched_episodes>0</my_watched_episodes>
+		<my_start_date>0000-00-00</my_start_date>
+		<my_finish_date>0000-00-00</my_finish_date>
+		<my_status>6</my_status>
+	</anime>
+</myanimelist>
+`
+
+func date(year int, month time.Month, day int) *time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &t
+}
+
+var exportEntries = []Entry{
+	{MalID: 52991, Title: "Sousou no Frieren", Type: "TV", Episodes: 28, Status: StatusCompleted, Score: 10,
+		WatchedEpisodes: 28, StartDate: date(2023, 9, 29), FinishDate: date(2024, 3, 22), TimesWatched: 1},
+	{MalID: 1, Title: "Cowboy Bebop", Type: "TV", Episodes: 26, Status: StatusWatching, WatchedEpisodes: 5, Rewatching: true},
+	{MalID: 47917, Title: "Bocchi the Rock!", Status: StatusPlanToWatch},
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(gz, content); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{"plain", []byte(export)},
+		{"gzipped", gzipped(t, export)},
+	}
+
+	for _, tt := range tests {
+		entries, err := Read(bytes.NewReader(tt.body))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(entries, exportEntries) {
+			t.Errorf("%s: entries = %+v, want %+v", tt.name, entries, exportEntries)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"not XML", "anime list", "failed to parse"},
+		{"another document", "<anime-list></anime-list>", "failed to parse"},
+		{"unknown status", "<myanimelist><anime><series_animedb_id>1</series_animedb_id><my_status>5</my_status></anime></myanimelist>", `unknown my_status "5"`},
+		{"missing ID", "<myanimelist><anime><my_status>Watching</my_status></anime></myanimelist>", "anime 1 in MAL export"},
+	}
+
+	for _, tt := range tests {
+		_, err := Read(strings.NewReader(tt.body))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestReadRefusesGzipBomb(t *testing.T) {
+	// Compresses to a few dozen kilobytes
+	bomb := "<myanimelist>" + strings.Repeat(" ", MaxExportSize) + "</myanimelist>"
+
+	_, err := Read(bytes.NewReader(gzipped(t, bomb)))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("err = %v, want ErrTooLarge", err)
+	}
+
+	// Right at the limit is fine
+	fits := "<myanimelist>" + strings.Repeat(" ", MaxExportSize-len("<myanimelist></myanimelist>")) + "</myanimelist>"
+	if _, err := Read(bytes.NewReader(gzipped(t, fits))); err != nil {
+		t.Errorf("export of exactly MaxExportSize: %v", err)
+	}
+}
+
+func TestWriteReadRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, exportEntries); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"<user_total_anime>3</user_total_anime>",
+		"<user_total_watching>1</user_total_watching>",
+		"<series_title><![CDATA[Sousou no Frieren]]></series_title>",
+		"<my_finish_date>0000-00-00</my_finish_date>",
+		"<update_on_import>1</update_on_import>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("export lacks %s", want)
+		}
+	}
+
+	entries, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, exportEntries) {
+		t.Errorf("read back %+v, want %+v", entries, exportEntries)
+	}
+}
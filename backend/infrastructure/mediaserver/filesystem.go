@@ -0,0 +1,316 @@
+package mediaserver
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+// FileIndex persists scanned files so rescans only parse files whose mtime or
+// size changed.
+type FileIndex interface {
+	GetLibraryFiles(root string) ([]domain.LibraryFile, error)
+	GetShowFiles(showKey string) ([]domain.LibraryFile, error)
+	SaveLibraryFiles(files []domain.LibraryFile) error
+	DeleteLibraryFiles(paths []string) error
+}
+
+var videoExtensions = map[string]bool{
+	".mkv": true, ".mp4": true, ".m4v": true, ".avi": true,
+	".webm": true, ".ts": true, ".mov": true, ".wmv": true,
+}
+
+// Filesystem is a library of plain folders of video files. Each configured
+// directory is a library; shows are found by parsing release-style names.
+// There is no watched state, so every episode reports as unwatched.
+type Filesystem struct {
+	roots []string
+	index FileIndex
+}
+
+func NewFilesystem(roots []string, index FileIndex) *Filesystem {
+	cleaned := make([]string, 0, len(roots))
+	for _, root := range roots {
+		if root = strings.TrimSpace(root); root != "" {
+			cleaned = append(cleaned, filepath.Clean(root))
+		}
+	}
+	return &Filesystem{roots: cleaned, index: index}
+}
+
+func (f *Filesystem) Type() string {
+	return TypeFilesystem
+}
+
+func (f *Filesystem) ListLibraries() ([]domain.MediaLibrary, error) {
+	libraries := make([]domain.MediaLibrary, 0, len(f.roots))
+	for _, root := range f.roots {
+		libraries = append(libraries, domain.MediaLibrary{
+			ID:    root,
+			Title: filepath.Base(root),
+			Type:  "folder",
+		})
+	}
+	return libraries, nil
+}
+
+// ListShows rescans one library directory, or all of them when libraryID is
+// empty, and groups the files into shows.
+func (f *Filesystem) ListShows(libraryID string) ([]domain.PlexShow, error) {
+	roots := f.roots
+	if libraryID != "" {
+		roots = []string{filepath.Clean(libraryID)}
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("no library directories configured")
+	}
+
+	var files []domain.LibraryFile
+	for _, root := range roots {
+		scanned, err := f.scan(root)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, scanned...)
+	}
+
+	shows := make(map[string]*domain.PlexShow)
+	var order []string
+	for _, file := range files {
+		show, ok := shows[file.ShowKey]
+		if !ok {
+			show = &domain.PlexShow{
+				PlexID:      LocalID(file.ShowKey),
+				Source:      TypeFilesystem,
+				ExternalID:  file.ShowKey,
+				Title:       file.Title,
+				Year:        file.Year,
+				LastUpdated: time.Now(),
+			}
+			shows[file.ShowKey] = show
+			order = append(order, file.ShowKey)
+		}
+		show.EpisodeCount++
+		if show.Year == 0 {
+			show.Year = file.Year
+		}
+	}
+
+	result := make([]domain.PlexShow, 0, len(order))
+	for _, key := range order {
+		result = append(result, *shows[key])
+	}
+
+	return result, nil
+}
+
+func (f *Filesystem) ListSeasons(showID string) ([]domain.PlexSeason, error) {
+	files, err := f.index.GetShowFiles(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	bySeason := make(map[int]*domain.PlexSeason)
+	for _, file := range files {
+		season, ok := bySeason[file.Season]
+		if !ok {
+			season = &domain.PlexSeason{
+				PlexID:       LocalID(fmt.Sprintf("%s/season/%d", showID, file.Season)),
+				ShowPlexID:   LocalID(showID),
+				SeasonNumber: file.Season,
+				Title:        fmt.Sprintf("Season %d", file.Season),
+			}
+			bySeason[file.Season] = season
+		}
+		season.EpisodeCount++
+	}
+
+	seasons := make([]domain.PlexSeason, 0, len(bySeason))
+	for _, season := range bySeason {
+		seasons = append(seasons, *season)
+	}
+
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].SeasonNumber < seasons[j].SeasonNumber
+	})
+
+	return seasons, nil
+}
+
+func (f *Filesystem) ListEpisodes(showID string) ([]domain.MediaEpisode, error) {
+	files, err := f.index.GetShowFiles(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := make([]domain.MediaEpisode, 0, len(files))
+	for _, file := range files {
+		episodes = append(episodes, domain.MediaEpisode{
+			ID:            file.Path,
+			SeasonNumber:  file.Season,
+			EpisodeNumber: file.Episode,
+			Title:         filepath.Base(file.Path),
+		})
+	}
+
+	sort.Slice(episodes, func(i, j int) bool {
+		if episodes[i].SeasonNumber != episodes[j].SeasonNumber {
+			return episodes[i].SeasonNumber < episodes[j].SeasonNumber
+		}
+		return episodes[i].EpisodeNumber < episodes[j].EpisodeNumber
+	})
+
+	return episodes, nil
+}
+
+func (f *Filesystem) GetWatchState(showID string) (*domain.WatchState, error) {
+	files, err := f.index.GetShowFiles(showID)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.WatchState{TotalEpisodes: len(files)}, nil
+}
+
+// GetShowQuality reports the resolutions found in release names. Languages
+// and codecs would need the files to be probed, so they stay empty.
+func (f *Filesystem) GetShowQuality(showID string) (*domain.ShowQuality, error) {
+	files, err := f.index.GetShowFiles(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := newQualitySummary()
+	for _, file := range files {
+		summary.episodes++
+		summary.addVideo(file.Resolution, "", 0)
+	}
+
+	quality := summary.result()
+	quality.PlexID = LocalID(showID)
+	return quality, nil
+}
+
+// scan walks a library directory. Files already in the index with the same
+// mtime and size are reused; new and changed files are parsed and saved, and
+// files that have gone are removed from the index.
+func (f *Filesystem) scan(root string) ([]domain.LibraryFile, error) {
+	known, err := f.index.GetLibraryFiles(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load file index: %w", err)
+	}
+
+	byPath := make(map[string]domain.LibraryFile, len(known))
+	for _, file := range known {
+		byPath[file.Path] = file
+	}
+
+	var files, changed []domain.LibraryFile
+	seen := make(map[string]bool)
+
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			log.Printf("Skipping %s: %v", path, err)
+			return nil
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !videoExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			log.Printf("Skipping %s: %v", path, err)
+			return nil
+		}
+		seen[path] = true
+
+		if file, ok := byPath[path]; ok && file.ModTime.Equal(info.ModTime()) && file.Size == info.Size() {
+			files = append(files, file)
+			return nil
+		}
+
+		file := parseLibraryFile(root, path, info)
+		files = append(files, file)
+		changed = append(changed, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+
+	if err := f.index.SaveLibraryFiles(changed); err != nil {
+		return nil, fmt.Errorf("failed to save file index: %w", err)
+	}
+
+	var removed []string
+	for path := range byPath {
+		if !seen[path] {
+			removed = append(removed, path)
+		}
+	}
+	if err := f.index.DeleteLibraryFiles(removed); err != nil {
+		return nil, fmt.Errorf("failed to prune file index: %w", err)
+	}
+
+	return files, nil
+}
+
+func parseLibraryFile(root string, path string, info os.FileInfo) domain.LibraryFile {
+	release := ParseReleaseName(filepath.Base(path))
+
+	// Files named only by episode take the show from their folder
+	if release.Title == "" || filepath.Dir(path) != root {
+		folder, folderSeason := folderInfo(path)
+		if release.Title == "" {
+			release.Title = folder.Title
+			if folderSeason > 0 {
+				release.Season = folderSeason
+			}
+		}
+		if release.Year == 0 {
+			release.Year = folder.Year
+		}
+	}
+	if release.Title == "" {
+		release.Title = filepath.Base(filepath.Dir(path))
+	}
+
+	return domain.LibraryFile{
+		Path:        path,
+		Root:        root,
+		ModTime:     info.ModTime(),
+		Size:        info.Size(),
+		ShowKey:     showKey(release.Title),
+		ReleaseInfo: release,
+	}
+}
+
+// showKey groups files into shows by title, ignoring case and punctuation so
+// releases from different groups land in the same show.
+func showKey(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127 {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return strings.ToLower(title)
+	}
+	return b.String()
+}
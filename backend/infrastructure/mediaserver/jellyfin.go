@@ -0,0 +1,274 @@
+package mediaserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+// itemPageSize is how many items are requested per page when listing shows.
+const itemPageSize = 500
+
+// EmbyCompatible talks to Jellyfin and Emby, which share most of their API
+// but authenticate differently.
+type EmbyCompatible struct {
+	serverType string
+	config     Config
+	client     *http.Client
+
+	mu     sync.Mutex
+	userID string
+}
+
+type embyItems struct {
+	Items            []embyItem `json:"Items"`
+	TotalRecordCount int        `json:"TotalRecordCount"`
+}
+
+type embyItem struct {
+	ID                 string            `json:"Id"`
+	Name               string            `json:"Name"`
+	ProductionYear     int               `json:"ProductionYear"`
+	IndexNumber        int               `json:"IndexNumber"`
+	ParentIndexNumber  int               `json:"ParentIndexNumber"`
+	SeasonID           string            `json:"SeasonId"`
+	RecursiveItemCount int               `json:"RecursiveItemCount"`
+	ProviderIDs        map[string]string `json:"ProviderIds"`
+	Tags               []string          `json:"Tags"`
+	UserData           struct {
+		Played bool `json:"Played"`
+	} `json:"UserData"`
+}
+
+type embyVirtualFolder struct {
+	Name           string `json:"Name"`
+	ItemID         string `json:"ItemId"`
+	CollectionType string `json:"CollectionType"`
+}
+
+type embyUser struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Policy struct {
+		IsAdministrator bool `json:"IsAdministrator"`
+	} `json:"Policy"`
+}
+
+func newEmbyCompatible(serverType string, config Config, client *http.Client) *EmbyCompatible {
+	return &EmbyCompatible{
+		serverType: serverType,
+		config:     config,
+		client:     client,
+	}
+}
+
+func (e *EmbyCompatible) Type() string {
+	return e.serverType
+}
+
+func (e *EmbyCompatible) ListLibraries() ([]domain.MediaLibrary, error) {
+	var folders []embyVirtualFolder
+	if err := e.get("/Library/VirtualFolders", nil, &folders); err != nil {
+		return nil, err
+	}
+
+	libraries := make([]domain.MediaLibrary, 0, len(folders))
+	for _, folder := range folders {
+		libraries = append(libraries, domain.MediaLibrary{
+			ID:    folder.ItemID,
+			Title: folder.Name,
+			Type:  folder.CollectionType,
+		})
+	}
+
+	return libraries, nil
+}
+
+func (e *EmbyCompatible) ListShows(libraryID string) ([]domain.PlexShow, error) {
+	var shows []domain.PlexShow
+
+	for start := 0; ; start += itemPageSize {
+		params := url.Values{
+			"ParentId":         {libraryID},
+			"IncludeItemTypes": {"Series"},
+			"Recursive":        {"true"},
+			"Fields":           {"ProviderIds,ProductionYear,RecursiveItemCount,Tags"},
+			"StartIndex":       {strconv.Itoa(start)},
+			"Limit":            {strconv.Itoa(itemPageSize)},
+		}
+
+		var page embyItems
+		if err := e.get("/Items", params, &page); err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			show := domain.PlexShow{
+				PlexID:       LocalID(item.ID),
+				Source:       e.serverType,
+				ExternalID:   item.ID,
+				Title:        item.Name,
+				Year:         item.ProductionYear,
+				EpisodeCount: item.RecursiveItemCount,
+				LastUpdated:  time.Now(),
+			}
+
+			if id, err := strconv.Atoi(item.ProviderIDs["AniList"]); err == nil && id > 0 {
+				show.AnilistID = &id
+				show.MappingSource = domain.MappingSourceGUID
+			} else if id := anilistIDFromTags(item.Tags...); id != 0 {
+				show.AnilistID = &id
+				show.MappingSource = domain.MappingSourceGUID
+			}
+
+			shows = append(shows, show)
+		}
+
+		if len(page.Items) < itemPageSize || start+len(page.Items) >= page.TotalRecordCount {
+			break
+		}
+	}
+
+	return shows, nil
+}
+
+// ListSeasons groups the show's episodes by season, since season items do
+// not carry episode counts.
+func (e *EmbyCompatible) ListSeasons(showID string) ([]domain.PlexSeason, error) {
+	episodes, err := e.listEpisodeItems(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	bySeason := make(map[int]*domain.PlexSeason)
+	for _, episode := range episodes {
+		season, ok := bySeason[episode.ParentIndexNumber]
+		if !ok {
+			season = &domain.PlexSeason{
+				PlexID:       LocalID(episode.SeasonID),
+				ShowPlexID:   LocalID(showID),
+				SeasonNumber: episode.ParentIndexNumber,
+				Title:        fmt.Sprintf("Season %d", episode.ParentIndexNumber),
+			}
+			bySeason[episode.ParentIndexNumber] = season
+		}
+		season.EpisodeCount++
+	}
+
+	seasons := make([]domain.PlexSeason, 0, len(bySeason))
+	for _, season := range bySeason {
+		seasons = append(seasons, *season)
+	}
+
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].SeasonNumber < seasons[j].SeasonNumber
+	})
+
+	return seasons, nil
+}
+
+func (e *EmbyCompatible) ListEpisodes(showID string) ([]domain.MediaEpisode, error) {
+	items, err := e.listEpisodeItems(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	episodes := make([]domain.MediaEpisode, 0, len(items))
+	for _, item := range items {
+		episodes = append(episodes, domain.MediaEpisode{
+			ID:            item.ID,
+			SeasonNumber:  item.ParentIndexNumber,
+			EpisodeNumber: item.IndexNumber,
+			Title:         item.Name,
+			Watched:       item.UserData.Played,
+		})
+	}
+
+	return episodes, nil
+}
+
+func (e *EmbyCompatible) GetWatchState(showID string) (*domain.WatchState, error) {
+	episodes, err := e.ListEpisodes(showID)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &domain.WatchState{TotalEpisodes: len(episodes)}
+	for _, episode := range episodes {
+		if episode.Watched {
+			state.WatchedEpisodes++
+		}
+	}
+
+	return state, nil
+}
+
+func (e *EmbyCompatible) listEpisodeItems(showID string) ([]embyItem, error) {
+	userID, err := e.resolveUser()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp embyItems
+	params := url.Values{"UserId": {userID}}
+	if err := e.get(fmt.Sprintf("/Shows/%s/Episodes", url.PathEscape(showID)), params, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Items, nil
+}
+
+// resolveUser finds the user whose watched state is reported. API keys are
+// not tied to a user, so one has to be picked explicitly.
+func (e *EmbyCompatible) resolveUser() (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.userID != "" {
+		return e.userID, nil
+	}
+
+	var users []embyUser
+	if err := e.get("/Users", nil, &users); err != nil {
+		return "", err
+	}
+
+	for _, user := range users {
+		if (e.config.UserName != "" && user.Name == e.config.UserName) ||
+			(e.config.UserName == "" && user.Policy.IsAdministrator) {
+			e.userID = user.ID
+			return user.ID, nil
+		}
+	}
+
+	if e.config.UserName != "" {
+		return "", fmt.Errorf("%s user %q not found", e.serverType, e.config.UserName)
+	}
+	return "", fmt.Errorf("no %s administrator found to read watched state from", e.serverType)
+}
+
+func (e *EmbyCompatible) get(path string, params url.Values, out interface{}) error {
+	target := e.config.ServerURL + path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if e.serverType == TypeJellyfin {
+		req.Header.Set("Authorization", fmt.Sprintf(`MediaBrowser Token="%s"`, e.config.Token))
+	} else {
+		req.Header.Set("X-Emby-Token", e.config.Token)
+	}
+
+	return getJSON(e.client, req, out)
+}
@@ -0,0 +1,199 @@
+package mediaserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// fakeEmby serves the parts of the Jellyfin and Emby API the client uses,
+// rejecting requests that do not authenticate the way serverType expects.
+func fakeEmby(t *testing.T, serverType string, shows int) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/Library/VirtualFolders", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]string{
+			{"Name": "Anime", "ItemId": "lib-1", "CollectionType": "tvshows"},
+			{"Name": "Movies", "ItemId": "lib-2", "CollectionType": "movies"},
+		})
+	})
+	mux.HandleFunc("/Items", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("ParentId") != "lib-1" || query.Get("IncludeItemTypes") != "Series" {
+			t.Errorf("unexpected items query %s", r.URL.RawQuery)
+		}
+		start, _ := strconv.Atoi(query.Get("StartIndex"))
+		limit, _ := strconv.Atoi(query.Get("Limit"))
+
+		items := []map[string]interface{}{}
+		for i := start; i < shows && i < start+limit; i++ {
+			item := map[string]interface{}{
+				"Id":                 fmt.Sprintf("show-%d", i),
+				"Name":               fmt.Sprintf("Show %d", i),
+				"ProductionYear":     2000 + i%20,
+				"RecursiveItemCount": 12,
+			}
+			switch i {
+			case 0:
+				item["ProviderIds"] = map[string]string{"AniList": "21"}
+			case 1:
+				item["Tags"] = []string{"favourite", "anilist-154587"}
+			}
+			items = append(items, item)
+		}
+		writeJSON(w, map[string]interface{}{"Items": items, "TotalRecordCount": shows})
+	})
+	mux.HandleFunc("/Users", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]interface{}{
+			{"Id": "user-1", "Name": "guest", "Policy": map[string]bool{"IsAdministrator": false}},
+			{"Id": "user-2", "Name": "admin", "Policy": map[string]bool{"IsAdministrator": true}},
+		})
+	})
+	mux.HandleFunc("/Shows/show-0/Episodes", func(w http.ResponseWriter, r *http.Request) {
+		if user := r.URL.Query().Get("UserId"); user != "user-2" {
+			t.Errorf("episodes requested for user %q, want the administrator", user)
+		}
+		episode := func(id string, season, number int, played bool) map[string]interface{} {
+			return map[string]interface{}{
+				"Id": id, "Name": "Episode " + id, "SeasonId": fmt.Sprintf("season-%d", season),
+				"ParentIndexNumber": season, "IndexNumber": number,
+				"UserData": map[string]bool{"Played": played},
+			}
+		}
+		writeJSON(w, map[string]interface{}{"Items": []interface{}{
+			episode("e1", 1, 1, true),
+			episode("e2", 1, 2, true),
+			episode("e3", 2, 1, false),
+			episode("s1", 0, 1, false),
+		}})
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorized := r.Header.Get("X-Emby-Token") == "secret"
+		if serverType == TypeJellyfin {
+			authorized = r.Header.Get("Authorization") == `MediaBrowser Token="secret"`
+		}
+		if !authorized {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func newTestEmby(t *testing.T, serverType string, shows int) MediaServer {
+	t.Helper()
+
+	srv := fakeEmby(t, serverType, shows)
+	t.Cleanup(srv.Close)
+
+	server, err := New(Config{Type: serverType, ServerURL: srv.URL, Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func TestEmbyCompatibleListShows(t *testing.T) {
+	for _, serverType := range []string{TypeJellyfin, TypeEmby} {
+		t.Run(serverType, func(t *testing.T) {
+			// More than one page of shows
+			server := newTestEmby(t, serverType, itemPageSize+3)
+
+			libraries, err := server.ListLibraries()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(libraries) != 2 || libraries[0].ID != "lib-1" || libraries[0].Type != "tvshows" {
+				t.Fatalf("libraries = %+v", libraries)
+			}
+
+			shows, err := server.ListShows("lib-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(shows) != itemPageSize+3 {
+				t.Fatalf("got %d shows, want %d", len(shows), itemPageSize+3)
+			}
+
+			first := shows[0]
+			if first.Source != serverType || first.ExternalID != "show-0" || first.PlexID != LocalID("show-0") {
+				t.Errorf("first show = %+v", first)
+			}
+			if first.AnilistID == nil || *first.AnilistID != 21 {
+				t.Errorf("provider ID not read: %v", first.AnilistID)
+			}
+			if shows[1].AnilistID == nil || *shows[1].AnilistID != 154587 {
+				t.Errorf("tag ID not read: %v", shows[1].AnilistID)
+			}
+			if shows[2].AnilistID != nil {
+				t.Errorf("unexpected AniList ID %d", *shows[2].AnilistID)
+			}
+		})
+	}
+}
+
+func TestEmbyCompatibleEpisodes(t *testing.T) {
+	for _, serverType := range []string{TypeJellyfin, TypeEmby} {
+		t.Run(serverType, func(t *testing.T) {
+			server := newTestEmby(t, serverType, 1)
+
+			seasons, err := server.ListSeasons("show-0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []struct{ number, episodes int }{{0, 1}, {1, 2}, {2, 1}}
+			if len(seasons) != len(want) {
+				t.Fatalf("seasons = %+v", seasons)
+			}
+			for i, season := range seasons {
+				if season.SeasonNumber != want[i].number || season.EpisodeCount != want[i].episodes {
+					t.Errorf("season %d = %+v, want number %d with %d episodes", i, season, want[i].number, want[i].episodes)
+				}
+			}
+
+			state, err := server.GetWatchState("show-0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if state.TotalEpisodes != 4 || state.WatchedEpisodes != 2 {
+				t.Errorf("watch state = %+v", state)
+			}
+		})
+	}
+}
+
+func TestEmbyCompatibleRejectedToken(t *testing.T) {
+	srv := fakeEmby(t, TypeJellyfin, 1)
+	defer srv.Close()
+
+	server, err := New(Config{Type: TypeJellyfin, ServerURL: srv.URL, Token: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.ListLibraries(); err == nil {
+		t.Fatal("expected an error for a rejected token")
+	}
+}
+
+func TestEmbyCompatibleUnknownUser(t *testing.T) {
+	srv := fakeEmby(t, TypeEmby, 1)
+	defer srv.Close()
+
+	server, err := New(Config{Type: TypeEmby, ServerURL: srv.URL, Token: "secret", UserName: "nobody"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.ListEpisodes("show-0"); err == nil {
+		t.Fatal("expected an error for an unknown user")
+	}
+}
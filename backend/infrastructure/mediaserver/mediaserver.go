@@ -0,0 +1,153 @@
+package mediaserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+const (
+	TypePlex       = "plex"
+	TypeJellyfin   = "jellyfin"
+	TypeEmby       = "emby"
+	TypeFilesystem = "filesystem"
+)
+
+// MediaServer is a library backend that shows are synced from. Show, season
+// and episode IDs are the server's own IDs as strings.
+type MediaServer interface {
+	Type() string
+	ListLibraries() ([]domain.MediaLibrary, error)
+	ListShows(libraryID string) ([]domain.PlexShow, error)
+	ListSeasons(showID string) ([]domain.PlexSeason, error)
+	ListEpisodes(showID string) ([]domain.MediaEpisode, error)
+	GetWatchState(showID string) (*domain.WatchState, error)
+}
+
+// IncrementalLister is implemented by servers that can list only the shows
+// added or updated since a point in time, including shows that gained new
+// episodes.
+type IncrementalLister interface {
+	ListShowsChangedSince(libraryID string, since time.Time) ([]domain.PlexShow, error)
+}
+
+// QualityReader is implemented by servers that report the resolution, codecs
+// and audio and subtitle languages of episode files.
+type QualityReader interface {
+	GetShowQuality(showID string) (*domain.ShowQuality, error)
+}
+
+// CollectionManager is implemented by servers that can group shows into a
+// named collection.
+type CollectionManager interface {
+	GetCollectionItems(libraryID string, name string) ([]string, error)
+	AddToCollection(libraryID string, name string, itemIDs []string) error
+	RemoveFromCollection(libraryID string, name string, itemIDs []string) error
+}
+
+// MetadataWriter is implemented by servers whose item labels, genres and
+// rating can be edited.
+type MetadataWriter interface {
+	GetItemMetadata(itemID string) (*domain.ItemMetadata, error)
+	UpdateItemMetadata(libraryID string, itemID string, update domain.MetadataUpdate) error
+}
+
+type Config struct {
+	Type      string
+	ServerURL string
+	Token     string
+	// UserName picks whose watched state Jellyfin and Emby report. When empty
+	// the first administrator is used.
+	UserName string
+	// LibraryPaths and Index are used by the filesystem source.
+	LibraryPaths []string
+	Index        FileIndex
+}
+
+func New(config Config) (MediaServer, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	switch config.Type {
+	case "", TypePlex:
+		return &Plex{config: config, client: client}, nil
+	case TypeJellyfin:
+		return newEmbyCompatible(TypeJellyfin, config, client), nil
+	case TypeEmby:
+		return newEmbyCompatible(TypeEmby, config, client), nil
+	case TypeFilesystem:
+		if config.Index == nil {
+			return nil, fmt.Errorf("filesystem library needs a file index")
+		}
+		return NewFilesystem(config.LibraryPaths, config.Index), nil
+	default:
+		return nil, fmt.Errorf("unknown media server type %q", config.Type)
+	}
+}
+
+// LocalID turns a server item ID into the integer ID shows are stored under.
+// Plex rating keys are already numeric; other servers use GUIDs, which are
+// hashed into the positive int32 range. Hashes can collide, so sync keeps
+// each item on the ID it was first stored under and moves a new item whose
+// hash is taken to a free ID.
+func LocalID(itemID string) int {
+	if id, err := strconv.Atoi(itemID); err == nil && id > 0 {
+		return id
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(itemID))
+	return int(h.Sum32() & 0x7fffffff)
+}
+
+// anilistIDPattern finds AniList references in GUIDs, provider IDs and
+// labels, such as "anilist://12345" from agents or an "anilist-12345" label.
+var anilistIDPattern = regexp.MustCompile(`(?i)anilist(?:://|-|:)(\d+)`)
+
+func anilistIDFromTags(tags ...string) int {
+	for _, tag := range tags {
+		if match := anilistIDPattern.FindStringSubmatch(tag); match != nil {
+			if id, err := strconv.Atoi(match[1]); err == nil && id > 0 {
+				return id
+			}
+		}
+	}
+	return 0
+}
+
+func getJSON(client *http.Client, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := fetch(client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", req.URL.Path, err)
+	}
+
+	return nil
+}
+
+// fetch sends the request and fails on anything but 200 OK. The caller
+// closes the body.
+func fetch(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", req.URL.Path, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("media server returned status %d for %s", resp.StatusCode, req.URL.Path)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,504 @@
+package mediaserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+type Plex struct {
+	config Config
+	client *http.Client
+
+	mu        sync.Mutex
+	machineID string
+}
+
+func (p *Plex) Type() string {
+	return TypePlex
+}
+
+func (p *Plex) ListLibraries() ([]domain.MediaLibrary, error) {
+	var resp plexResponse
+	if err := p.get("/library/sections", &resp); err != nil {
+		return nil, err
+	}
+
+	libraries := make([]domain.MediaLibrary, 0, len(resp.MediaContainer.Directory))
+	for _, dir := range resp.MediaContainer.Directory {
+		libraries = append(libraries, domain.MediaLibrary{
+			ID:    dir.Key,
+			Title: dir.Title,
+			Type:  dir.Type,
+		})
+	}
+
+	return libraries, nil
+}
+
+func (p *Plex) ListShows(libraryID string) ([]domain.PlexShow, error) {
+	var resp plexResponse
+	if err := p.get(fmt.Sprintf("/library/sections/%s/all?includeGuids=1", libraryID), &resp); err != nil {
+		return nil, err
+	}
+
+	var shows []domain.PlexShow
+	for _, metadata := range resp.MediaContainer.Metadata {
+		shows = append(shows, plexShow(metadata))
+	}
+
+	return shows, nil
+}
+
+// ListShowsChangedSince combines shows whose metadata was updated with shows
+// that had episodes added, since adding an episode does not always bump the
+// show's own updatedAt.
+func (p *Plex) ListShowsChangedSince(libraryID string, since time.Time) ([]domain.PlexShow, error) {
+	// Plex filters dates with the ">>=" operator, escaped here
+	filter := func(field string) string {
+		return fmt.Sprintf("%s%%3E%%3E=%d", field, since.Unix())
+	}
+
+	var updated plexResponse
+	if err := p.get(fmt.Sprintf("/library/sections/%s/all?type=2&includeGuids=1&%s", libraryID, filter("updatedAt")), &updated); err != nil {
+		return nil, err
+	}
+
+	var added plexResponse
+	if err := p.get(fmt.Sprintf("/library/sections/%s/all?type=4&%s", libraryID, filter("addedAt")), &added); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var shows []domain.PlexShow
+	for _, metadata := range updated.MediaContainer.Metadata {
+		seen[metadata.RatingKey] = true
+		shows = append(shows, plexShow(metadata))
+	}
+
+	var missing []string
+	for _, episode := range added.MediaContainer.Metadata {
+		key := episode.GrandparentRatingKey
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		missing = append(missing, key)
+	}
+
+	// The metadata endpoint takes a comma-separated list of rating keys
+	for start := 0; start < len(missing); start += plexMetadataBatch {
+		end := start + plexMetadataBatch
+		if end > len(missing) {
+			end = len(missing)
+		}
+
+		var resp plexResponse
+		if err := p.get(fmt.Sprintf("/library/metadata/%s?includeGuids=1", strings.Join(missing[start:end], ",")), &resp); err != nil {
+			return nil, err
+		}
+		for _, metadata := range resp.MediaContainer.Metadata {
+			shows = append(shows, plexShow(metadata))
+		}
+	}
+
+	return shows, nil
+}
+
+// plexMetadataBatch keeps multi-item metadata URLs to a sensible length.
+const plexMetadataBatch = 50
+
+func plexShow(metadata plexMetadata) domain.PlexShow {
+	show := domain.PlexShow{
+		PlexID:       LocalID(metadata.RatingKey),
+		Source:       TypePlex,
+		ExternalID:   metadata.RatingKey,
+		Title:        metadata.Title,
+		GUID:         metadata.GUID,
+		Year:         metadata.Year,
+		EpisodeCount: metadata.LeafCount,
+		LastUpdated:  time.Now(),
+	}
+
+	tags := []string{metadata.GUID}
+	for _, guid := range metadata.Guids {
+		tags = append(tags, guid.ID)
+	}
+	for _, label := range metadata.Labels {
+		tags = append(tags, label.Tag)
+	}
+	if anilistID := anilistIDFromTags(tags...); anilistID != 0 {
+		show.AnilistID = &anilistID
+		show.MappingSource = domain.MappingSourceGUID
+	}
+
+	return show
+}
+
+func (p *Plex) ListSeasons(showID string) ([]domain.PlexSeason, error) {
+	var resp plexResponse
+	if err := p.get(fmt.Sprintf("/library/metadata/%s/children", showID), &resp); err != nil {
+		return nil, err
+	}
+
+	var seasons []domain.PlexSeason
+	for _, metadata := range resp.MediaContainer.Metadata {
+		seasons = append(seasons, domain.PlexSeason{
+			PlexID:       LocalID(metadata.RatingKey),
+			ShowPlexID:   LocalID(showID),
+			SeasonNumber: metadata.Index,
+			Title:        metadata.Title,
+			EpisodeCount: metadata.LeafCount,
+		})
+	}
+
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].SeasonNumber < seasons[j].SeasonNumber
+	})
+
+	return seasons, nil
+}
+
+func (p *Plex) ListEpisodes(showID string) ([]domain.MediaEpisode, error) {
+	var resp plexResponse
+	if err := p.get(fmt.Sprintf("/library/metadata/%s/allLeaves", showID), &resp); err != nil {
+		return nil, err
+	}
+
+	episodes := make([]domain.MediaEpisode, 0, len(resp.MediaContainer.Metadata))
+	for _, metadata := range resp.MediaContainer.Metadata {
+		episodes = append(episodes, domain.MediaEpisode{
+			ID:            metadata.RatingKey,
+			SeasonNumber:  metadata.ParentIndex,
+			EpisodeNumber: metadata.Index,
+			Title:         metadata.Title,
+			Watched:       metadata.ViewCount > 0,
+		})
+	}
+
+	return episodes, nil
+}
+
+func (p *Plex) GetWatchState(showID string) (*domain.WatchState, error) {
+	var resp plexResponse
+	if err := p.get(fmt.Sprintf("/library/metadata/%s", showID), &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.MediaContainer.Metadata) == 0 {
+		return nil, domain.ErrShowNotFound
+	}
+
+	metadata := resp.MediaContainer.Metadata[0]
+	return &domain.WatchState{
+		WatchedEpisodes: metadata.ViewedLeafCount,
+		TotalEpisodes:   metadata.LeafCount,
+	}, nil
+}
+
+// GetShowQuality summarizes the files of every episode of a show. Episode
+// lists leave out streams, so episodes are re-fetched in batches for their
+// languages.
+func (p *Plex) GetShowQuality(showID string) (*domain.ShowQuality, error) {
+	var leaves plexResponse
+	if err := p.get(fmt.Sprintf("/library/metadata/%s/allLeaves", showID), &leaves); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(leaves.MediaContainer.Metadata))
+	for _, episode := range leaves.MediaContainer.Metadata {
+		keys = append(keys, episode.RatingKey)
+	}
+
+	summary := newQualitySummary()
+	for start := 0; start < len(keys); start += plexMetadataBatch {
+		end := start + plexMetadataBatch
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		var resp plexResponse
+		if err := p.get(fmt.Sprintf("/library/metadata/%s", strings.Join(keys[start:end], ",")), &resp); err != nil {
+			return nil, err
+		}
+		for _, episode := range resp.MediaContainer.Metadata {
+			summary.addEpisode(episode)
+		}
+	}
+
+	quality := summary.result()
+	quality.PlexID = LocalID(showID)
+	return quality, nil
+}
+
+// collectionBatchSize caps how many shows are added per request, keeping the
+// item URI to a reasonable length.
+const collectionBatchSize = 100
+
+// GetCollectionItems returns the rating keys of the shows in a collection, or
+// none if the collection does not exist yet.
+func (p *Plex) GetCollectionItems(libraryID string, name string) ([]string, error) {
+	key, err := p.findCollection(libraryID, name)
+	if err != nil || key == "" {
+		return nil, err
+	}
+
+	var children plexResponse
+	if err := p.get(fmt.Sprintf("/library/collections/%s/children", key), &children); err != nil {
+		return nil, err
+	}
+
+	items := make([]string, 0, len(children.MediaContainer.Metadata))
+	for _, child := range children.MediaContainer.Metadata {
+		items = append(items, child.RatingKey)
+	}
+	return items, nil
+}
+
+// AddToCollection adds shows to a collection, creating it on first use. Only
+// the collection's item list changes, so other collections the shows are in
+// are kept.
+func (p *Plex) AddToCollection(libraryID string, name string, itemIDs []string) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+
+	key, err := p.findCollection(libraryID, name)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(itemIDs); start += collectionBatchSize {
+		uri, err := p.itemsURI(itemIDs[start:min(start+collectionBatchSize, len(itemIDs))])
+		if err != nil {
+			return err
+		}
+
+		if key != "" {
+			if err := p.send("PUT", fmt.Sprintf("/library/collections/%s/items", key), url.Values{"uri": {uri}}, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		params := url.Values{
+			"type":      {"2"},
+			"title":     {name},
+			"smart":     {"0"},
+			"sectionId": {libraryID},
+			"uri":       {uri},
+		}
+		var created plexResponse
+		if err := p.send("POST", "/library/collections", params, &created); err != nil {
+			return err
+		}
+		if len(created.MediaContainer.Metadata) == 0 {
+			return fmt.Errorf("media server did not return the new collection %s", name)
+		}
+		key = created.MediaContainer.Metadata[0].RatingKey
+	}
+	return nil
+}
+
+func (p *Plex) RemoveFromCollection(libraryID string, name string, itemIDs []string) error {
+	if len(itemIDs) == 0 {
+		return nil
+	}
+
+	key, err := p.findCollection(libraryID, name)
+	if err != nil || key == "" {
+		return err
+	}
+
+	for _, itemID := range itemIDs {
+		path := fmt.Sprintf("/library/collections/%s/items/%s", key, url.PathEscape(itemID))
+		if err := p.send("DELETE", path, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findCollection returns the rating key of a library's collection, or an
+// empty key if there is none by that name.
+func (p *Plex) findCollection(libraryID string, name string) (string, error) {
+	if libraryID == "" {
+		return "", domain.ErrLibraryNotSet
+	}
+
+	var collections plexResponse
+	if err := p.get(fmt.Sprintf("/library/sections/%s/collections", url.PathEscape(libraryID)), &collections); err != nil {
+		return "", err
+	}
+
+	for _, collection := range collections.MediaContainer.Metadata {
+		if collection.Title == name {
+			return collection.RatingKey, nil
+		}
+	}
+	return "", nil
+}
+
+// itemsURI refers to library items the way collection edits expect.
+func (p *Plex) itemsURI(itemIDs []string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.machineID == "" {
+		var identity plexResponse
+		if err := p.get("/identity", &identity); err != nil {
+			return "", err
+		}
+		if identity.MediaContainer.MachineIdentifier == "" {
+			return "", fmt.Errorf("media server did not report its machine identifier")
+		}
+		p.machineID = identity.MediaContainer.MachineIdentifier
+	}
+
+	return fmt.Sprintf("server://%s/com.plexapp.plugins.library/library/metadata/%s", p.machineID, strings.Join(itemIDs, ",")), nil
+}
+
+func (p *Plex) GetItemMetadata(itemID string) (*domain.ItemMetadata, error) {
+	var resp plexResponse
+	if err := p.get(fmt.Sprintf("/library/metadata/%s", itemID), &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.MediaContainer.Metadata) == 0 {
+		return nil, domain.ErrShowNotFound
+	}
+
+	metadata := resp.MediaContainer.Metadata[0]
+	item := &domain.ItemMetadata{Rating: metadata.Rating}
+	for _, label := range metadata.Labels {
+		item.Labels = append(item.Labels, label.Tag)
+	}
+	for _, genre := range metadata.Genres {
+		item.Genres = append(item.Genres, genre.Tag)
+	}
+
+	return item, nil
+}
+
+// UpdateItemMetadata edits a show through the library edit API. Changed
+// fields are locked so Plex agents do not overwrite them on refresh.
+func (p *Plex) UpdateItemMetadata(libraryID string, itemID string, update domain.MetadataUpdate) error {
+	if libraryID == "" {
+		return domain.ErrLibraryNotSet
+	}
+
+	current, err := p.GetItemMetadata(itemID)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{
+		"type": {"2"},
+		"id":   {itemID},
+	}
+
+	editTags(params, "label", current.Labels, update.AddLabels, update.RemoveLabels)
+	editTags(params, "genre", current.Genres, update.AddGenres, update.RemoveGenres)
+	if update.Rating != nil {
+		params.Set("rating.value", strconv.FormatFloat(*update.Rating, 'f', 1, 64))
+		params.Set("rating.locked", "1")
+	}
+
+	return p.put(fmt.Sprintf("/library/sections/%s/all", url.PathEscape(libraryID)), params)
+}
+
+// editTags adds and removes tags of one field. An indexed tag list replaces
+// the whole field, so every tag that is kept is sent along with the new
+// ones; removals are also sent on their own for servers that only apply
+// those.
+func editTags(params url.Values, field string, current []string, add []string, remove []string) {
+	if len(add) == 0 && len(remove) == 0 {
+		return
+	}
+
+	removed := make(map[string]bool, len(remove))
+	for _, tag := range remove {
+		removed[strings.ToLower(tag)] = true
+	}
+
+	seen := make(map[string]bool, len(current)+len(add))
+	i := 0
+	for _, tag := range append(current[:len(current):len(current)], add...) {
+		key := strings.ToLower(tag)
+		if removed[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		params.Set(fmt.Sprintf("%s[%d].tag.tag", field, i), tag)
+		i++
+	}
+
+	if len(remove) > 0 {
+		params.Set(field+"[].tag.tag-", strings.Join(remove, ","))
+	}
+	params.Set(field+".locked", "1")
+}
+
+func (p *Plex) put(path string, params url.Values) error {
+	return p.send("PUT", path, params, nil)
+}
+
+// send makes a request that changes the library, decoding the response into
+// out unless it is nil.
+func (p *Plex) send(method string, path string, params url.Values, out interface{}) error {
+	target := p.config.ServerURL + path
+	if len(params) > 0 {
+		target += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Plex-Token", p.config.Token)
+	req.Header.Set("Accept", "application/json, application/xml;q=0.9")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("media server returned status %d for %s", resp.StatusCode, path)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := decodePlexResponse(resp.Header.Get("Content-Type"), resp.Body, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", path, err)
+	}
+	return nil
+}
+
+func (p *Plex) get(path string, out interface{}) error {
+	req, err := http.NewRequest("GET", p.config.ServerURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Plex-Token", p.config.Token)
+	// Older servers and some proxies ignore this and answer in XML
+	req.Header.Set("Accept", "application/json, application/xml;q=0.9")
+
+	resp, err := fetch(p.client, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := decodePlexResponse(resp.Header.Get("Content-Type"), resp.Body, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", path, err)
+	}
+
+	return nil
+}
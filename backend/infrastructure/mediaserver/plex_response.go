@@ -0,0 +1,170 @@
+package mediaserver
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"strings"
+)
+
+// Plex answers with a MediaContainer in either JSON or XML depending on the
+// Accept header, its version and any proxy in front of it. Both shapes decode
+// into the same structs.
+type plexResponse struct {
+	MediaContainer plexContainer `json:"MediaContainer"`
+}
+
+type plexContainer struct {
+	// MachineIdentifier is only set by /identity
+	MachineIdentifier string          `json:"machineIdentifier"`
+	Directory         []plexDirectory `json:"Directory"`
+	Metadata          []plexMetadata  `json:"Metadata"`
+}
+
+type plexDirectory struct {
+	Key   string `json:"key" xml:"key,attr"`
+	Title string `json:"title" xml:"title,attr"`
+	Type  string `json:"type" xml:"type,attr"`
+}
+
+type plexMetadata struct {
+	RatingKey string `json:"ratingKey" xml:"ratingKey,attr"`
+	// GrandparentRatingKey is the show of an episode
+	GrandparentRatingKey string      `json:"grandparentRatingKey" xml:"grandparentRatingKey,attr"`
+	Index                int         `json:"index" xml:"index,attr"`
+	ParentIndex          int         `json:"parentIndex" xml:"parentIndex,attr"`
+	GUID                 string      `json:"guid" xml:"guid,attr"`
+	Title                string      `json:"title" xml:"title,attr"`
+	Year                 int         `json:"year" xml:"year,attr"`
+	ChildCount           int         `json:"childCount" xml:"childCount,attr"`
+	LeafCount            int         `json:"leafCount" xml:"leafCount,attr"`
+	ViewedLeafCount      int         `json:"viewedLeafCount" xml:"viewedLeafCount,attr"`
+	ViewCount            int         `json:"viewCount" xml:"viewCount,attr"`
+	Rating               float64     `json:"rating" xml:"rating,attr"`
+	Guids                []plexTag   `json:"Guid" xml:"Guid"`
+	Labels               []plexTag   `json:"Label" xml:"Label"`
+	Genres               []plexTag   `json:"Genre" xml:"Genre"`
+	Media                []plexMedia `json:"Media" xml:"Media"`
+}
+
+// plexMedia is one version of an episode's file. Streams are only included
+// when items are fetched through /library/metadata.
+type plexMedia struct {
+	VideoResolution string     `json:"videoResolution" xml:"videoResolution,attr"`
+	VideoCodec      string     `json:"videoCodec" xml:"videoCodec,attr"`
+	Height          int        `json:"height" xml:"height,attr"`
+	Parts           []plexPart `json:"Part" xml:"Part"`
+}
+
+type plexPart struct {
+	Streams []plexStream `json:"Stream" xml:"Stream"`
+}
+
+// Plex stream types
+const (
+	plexStreamVideo    = 1
+	plexStreamAudio    = 2
+	plexStreamSubtitle = 3
+)
+
+type plexStream struct {
+	StreamType   int    `json:"streamType" xml:"streamType,attr"`
+	Codec        string `json:"codec" xml:"codec,attr"`
+	LanguageCode string `json:"languageCode" xml:"languageCode,attr"`
+}
+
+type plexTag struct {
+	ID  string `json:"id" xml:"id,attr"`
+	Tag string `json:"tag" xml:"tag,attr"`
+}
+
+// plexXMLItem holds the attributes of any child element of an XML
+// MediaContainer until we know which list it belongs in.
+type plexXMLItem struct {
+	plexMetadata
+	Key  string `xml:"key,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// UnmarshalXML lets the XML root element, which is the MediaContainer
+// itself, fill the same struct as the JSON wrapper object.
+func (r *plexResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return d.DecodeElement(&r.MediaContainer, &start)
+}
+
+// UnmarshalXML sorts the container's children the way the JSON API does. XML
+// names items by kind (Directory for shows and seasons, Video for episodes),
+// while JSON puts every library item under Metadata and keeps Directory for
+// sections. Anything with a rating key is a library item.
+func (c *plexContainer) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "machineIdentifier" {
+			c.MachineIdentifier = attr.Value
+		}
+	}
+
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			var item plexXMLItem
+			if err := d.DecodeElement(&item, &t); err != nil {
+				return err
+			}
+
+			if item.RatingKey != "" {
+				c.Metadata = append(c.Metadata, item.plexMetadata)
+			} else if t.Name.Local == "Directory" {
+				c.Directory = append(c.Directory, plexDirectory{
+					Key:   item.Key,
+					Title: item.Title,
+					Type:  item.Type,
+				})
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// decodePlexResponse decodes a body by its content type, falling back to
+// sniffing the first byte when the type is missing or generic.
+func decodePlexResponse(contentType string, body io.Reader, out interface{}) error {
+	reader := bufio.NewReader(body)
+
+	isXML := false
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case strings.HasSuffix(mediaType, "xml"):
+		isXML = true
+	case strings.HasSuffix(mediaType, "json"):
+	default:
+		isXML = firstNonSpace(reader) == '<'
+	}
+
+	if isXML {
+		return xml.NewDecoder(reader).Decode(out)
+	}
+	return json.NewDecoder(reader).Decode(out)
+}
+
+func firstNonSpace(reader *bufio.Reader) byte {
+	for n := 1; ; n++ {
+		peek, err := reader.Peek(n)
+		if len(peek) < n {
+			return 0
+		}
+		if c := peek[n-1]; c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			return c
+		}
+		if err != nil {
+			return 0
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package mediaserver
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Each fixture is one Plex response in both formats, trimmed to the fields
+// the client reads.
+var plexFixtures = []struct {
+	name string
+	json string
+	xml  string
+}{
+	{
+		name: "sections",
+		json: `{"MediaContainer": {"size": 2, "Directory": [
+			{"key": "1", "title": "Anime", "type": "show"},
+			{"key": "2", "title": "Movies", "type": "movie"}
+		]}}`,
+		xml: `<?xml version="1.0" encoding="UTF-8"?>
+<MediaContainer size="2">
+  <Directory key="1" title="Anime" type="show"><Location id="1" path="/anime"/></Directory>
+  <Directory key="2" title="Movies" type="movie"></Directory>
+</MediaContainer>`,
+	},
+	{
+		name: "shows",
+		json: `{"MediaContainer": {"size": 2, "Metadata": [
+			{"ratingKey": "10", "guid": "plex://show/abc", "title": "Frieren", "year": 2023,
+			 "childCount": 1, "leafCount": 28, "viewedLeafCount": 5, "rating": 9.1,
+			 "Guid": [{"id": "tvdb://424536"}, {"id": "anilist://154587"}],
+			 "Label": [{"tag": "anilist-154587"}, {"tag": "favourite"}],
+			 "Genre": [{"tag": "Fantasy"}]},
+			{"ratingKey": "11", "guid": "com.plexapp.agents.hama://anidb-69?lang=en", "title": "One Piece", "year": 1999}
+		]}}`,
+		xml: `<MediaContainer size="2">
+  <Directory ratingKey="10" key="/library/metadata/10/children" guid="plex://show/abc" type="show" title="Frieren" year="2023"
+      childCount="1" leafCount="28" viewedLeafCount="5" rating="9.1">
+    <Genre tag="Fantasy"/>
+    <Guid id="tvdb://424536"/>
+    <Guid id="anilist://154587"/>
+    <Label tag="anilist-154587"/>
+    <Label tag="favourite"/>
+  </Directory>
+  <Directory ratingKey="11" key="/library/metadata/11/children" guid="com.plexapp.agents.hama://anidb-69?lang=en" type="show" title="One Piece" year="1999"/>
+</MediaContainer>`,
+	},
+	{
+		name: "seasons",
+		json: `{"MediaContainer": {"size": 3, "Metadata": [
+			{"ratingKey": "20", "index": 0, "title": "Specials", "leafCount": 2},
+			{"ratingKey": "21", "index": 1, "title": "Season 1", "leafCount": 12, "viewedLeafCount": 12},
+			{"ratingKey": "22", "index": 2, "title": "Season 2", "leafCount": 12}
+		]}}`,
+		xml: `<MediaContainer size="3">
+  <Directory ratingKey="20" index="0" title="Specials" leafCount="2"/>
+  <Directory ratingKey="21" index="1" title="Season 1" leafCount="12" viewedLeafCount="12"/>
+  <Directory ratingKey="22" index="2" title="Season 2" leafCount="12"/>
+</MediaContainer>`,
+	},
+	{
+		name: "episodes",
+		json: `{"MediaContainer": {"size": 2, "Metadata": [
+			{"ratingKey": "30", "grandparentRatingKey": "10", "index": 1, "parentIndex": 1, "title": "The Journey's End", "viewCount": 2,
+			 "Media": [{"videoResolution": "1080", "videoCodec": "hevc", "height": 1080, "Part": [{"Stream": [
+				{"streamType": 1, "codec": "hevc"},
+				{"streamType": 2, "codec": "aac", "languageCode": "jpn"},
+				{"streamType": 3, "codec": "ass", "languageCode": "eng"}
+			 ]}]}]},
+			{"ratingKey": "31", "grandparentRatingKey": "10", "index": 2, "parentIndex": 1, "title": "It Didn't Have to Be Magic",
+			 "Media": [{"videoResolution": "720", "videoCodec": "h264", "height": 720, "Part": [{}]}]}
+		]}}`,
+		xml: `<MediaContainer size="2">
+  <Video ratingKey="30" grandparentRatingKey="10" index="1" parentIndex="1" title="The Journey's End" viewCount="2">
+    <Media videoResolution="1080" videoCodec="hevc" height="1080">
+      <Part file="/anime/Frieren/01.mkv">
+        <Stream streamType="1" codec="hevc"/>
+        <Stream streamType="2" codec="aac" languageCode="jpn"/>
+        <Stream streamType="3" codec="ass" languageCode="eng"/>
+      </Part>
+    </Media>
+  </Video>
+  <Video ratingKey="31" grandparentRatingKey="10" index="2" parentIndex="1" title="It Didn't Have to Be Magic">
+    <Media videoResolution="720" videoCodec="h264" height="720"><Part file="/anime/Frieren/02.mkv"></Part></Media>
+  </Video>
+</MediaContainer>`,
+	},
+	{
+		name: "identity",
+		json: `{"MediaContainer": {"size": 0, "machineIdentifier": "abc123", "version": "1.40.0"}}`,
+		xml:  `<MediaContainer size="0" machineIdentifier="abc123" version="1.40.0"></MediaContainer>`,
+	},
+}
+
+func TestPlexResponseFormatsMatch(t *testing.T) {
+	for _, fixture := range plexFixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			var fromJSON, fromXML plexResponse
+			if err := decodePlexResponse("application/json", strings.NewReader(fixture.json), &fromJSON); err != nil {
+				t.Fatalf("JSON: %v", err)
+			}
+			if err := decodePlexResponse("application/xml", strings.NewReader(fixture.xml), &fromXML); err != nil {
+				t.Fatalf("XML: %v", err)
+			}
+
+			if !reflect.DeepEqual(fromJSON, fromXML) {
+				t.Errorf("formats decode differently\nJSON %+v\n XML %+v", fromJSON, fromXML)
+			}
+		})
+	}
+}
+
+func TestPlexResponseFields(t *testing.T) {
+	decode := func(name string) plexContainer {
+		t.Helper()
+		for _, fixture := range plexFixtures {
+			if fixture.name == name {
+				var response plexResponse
+				if err := decodePlexResponse("text/xml", strings.NewReader(fixture.xml), &response); err != nil {
+					t.Fatal(err)
+				}
+				return response.MediaContainer
+			}
+		}
+		t.Fatalf("no fixture %s", name)
+		return plexContainer{}
+	}
+
+	sections := decode("sections")
+	if len(sections.Directory) != 2 || sections.Directory[0] != (plexDirectory{Key: "1", Title: "Anime", Type: "show"}) {
+		t.Errorf("sections = %+v", sections.Directory)
+	}
+	if len(sections.Metadata) != 0 {
+		t.Errorf("sections decoded as items: %+v", sections.Metadata)
+	}
+
+	shows := decode("shows")
+	if len(shows.Metadata) != 2 {
+		t.Fatalf("shows = %+v", shows.Metadata)
+	}
+	show := shows.Metadata[0]
+	if show.Year != 2023 || show.LeafCount != 28 || show.Rating != 9.1 {
+		t.Errorf("show = %+v", show)
+	}
+	if len(show.Guids) != 2 || show.Guids[1].ID != "anilist://154587" {
+		t.Errorf("guids = %+v", show.Guids)
+	}
+	if len(show.Labels) != 2 || show.Labels[0].Tag != "anilist-154587" {
+		t.Errorf("labels = %+v", show.Labels)
+	}
+
+	seasons := decode("seasons")
+	if len(seasons.Metadata) != 3 || seasons.Metadata[1].Index != 1 || seasons.Metadata[1].ViewedLeafCount != 12 {
+		t.Errorf("seasons = %+v", seasons.Metadata)
+	}
+
+	// The "All episodes" entry has no rating key and is not a season
+	var allLeaves plexResponse
+	body := `<MediaContainer><Directory leafCount="26" title="All episodes" key="/library/metadata/10/allLeaves"/><Directory ratingKey="21" index="1"/></MediaContainer>`
+	if err := decodePlexResponse("text/xml", strings.NewReader(body), &allLeaves); err != nil {
+		t.Fatal(err)
+	}
+	if len(allLeaves.MediaContainer.Metadata) != 1 || allLeaves.MediaContainer.Metadata[0].RatingKey != "21" {
+		t.Errorf("seasons with all episodes = %+v", allLeaves.MediaContainer.Metadata)
+	}
+
+	episodes := decode("episodes")
+	if len(episodes.Metadata) != 2 {
+		t.Fatalf("episodes = %+v", episodes.Metadata)
+	}
+	episode := episodes.Metadata[0]
+	if episode.GrandparentRatingKey != "10" || episode.ParentIndex != 1 || episode.ViewCount != 2 {
+		t.Errorf("episode = %+v", episode)
+	}
+	if len(episode.Media) != 1 || episode.Media[0].Height != 1080 || len(episode.Media[0].Parts) != 1 {
+		t.Fatalf("media = %+v", episode.Media)
+	}
+	streams := episode.Media[0].Parts[0].Streams
+	if len(streams) != 3 || streams[1] != (plexStream{StreamType: plexStreamAudio, Codec: "aac", LanguageCode: "jpn"}) {
+		t.Errorf("streams = %+v", streams)
+	}
+
+	if identity := decode("identity"); identity.MachineIdentifier != "abc123" {
+		t.Errorf("machine identifier = %q", identity.MachineIdentifier)
+	}
+}
+
+func TestDecodePlexResponseContentType(t *testing.T) {
+	xmlBody := `<MediaContainer machineIdentifier="xml"/>`
+	jsonBody := `{"MediaContainer": {"machineIdentifier": "json"}}`
+
+	tests := []struct {
+		contentType string
+		body        string
+		want        string
+		wantErr     bool
+	}{
+		{"application/json", jsonBody, "json", false},
+		{"application/json; charset=utf-8", jsonBody, "json", false},
+		{"application/xml", xmlBody, "xml", false},
+		{"text/xml;charset=utf-8", xmlBody, "xml", false},
+		// Generic or missing types are sniffed
+		{"", xmlBody, "xml", false},
+		{"", jsonBody, "json", false},
+		{"text/plain", "\r\n  " + xmlBody, "xml", false},
+		{"application/octet-stream", "  " + jsonBody, "json", false},
+		// A declared type is trusted over the body
+		{"application/json", xmlBody, "", true},
+		{"application/xml", jsonBody, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType+" "+tt.want, func(t *testing.T) {
+			var response plexResponse
+			err := decodePlexResponse(tt.contentType, strings.NewReader(tt.body), &response)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("decoded %q as %q, want an error", tt.body, tt.contentType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := response.MediaContainer.MachineIdentifier; got != tt.want {
+				t.Errorf("decoded as %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
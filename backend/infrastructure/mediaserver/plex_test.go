@@ -0,0 +1,242 @@
+package mediaserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"anime-watchlist/backend/domain"
+)
+
+// fakePlex keeps collections and show tags in memory and serves the parts of
+// the Plex API used to edit them.
+type fakePlex struct {
+	mu          sync.Mutex
+	collections map[string][]string // items of each collection by rating key
+	keys        map[string]string   // rating key of each collection title
+	tags        map[string]map[string][]string
+	edits       []string
+}
+
+func newFakePlex(t *testing.T) (*fakePlex, *Plex) {
+	t.Helper()
+
+	fake := &fakePlex{
+		collections: make(map[string][]string),
+		keys:        make(map[string]string),
+		tags:        make(map[string]map[string][]string),
+	}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	server, err := New(Config{Type: TypePlex, ServerURL: srv.URL, Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, server.(*Plex)
+}
+
+const fakeMachineID = "abc123"
+
+func (f *fakePlex) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("X-Plex-Token") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	f.edits = append(f.edits, r.Method+" "+r.URL.Path)
+	query := r.URL.Query()
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case r.URL.Path == "/identity":
+		writeJSON(w, map[string]interface{}{"MediaContainer": map[string]string{"machineIdentifier": fakeMachineID}})
+
+	case r.Method == "GET" && len(parts) == 4 && parts[1] == "sections" && parts[3] == "collections":
+		var metadata []map[string]string
+		for title, key := range f.keys {
+			metadata = append(metadata, map[string]string{"ratingKey": key, "title": title})
+		}
+		writeJSON(w, map[string]interface{}{"MediaContainer": map[string]interface{}{"Metadata": metadata}})
+
+	case r.Method == "GET" && len(parts) == 4 && parts[1] == "collections" && parts[3] == "children":
+		var metadata []map[string]string
+		for _, item := range f.collections[parts[2]] {
+			metadata = append(metadata, map[string]string{"ratingKey": item})
+		}
+		writeJSON(w, map[string]interface{}{"MediaContainer": map[string]interface{}{"Metadata": metadata}})
+
+	case r.Method == "POST" && r.URL.Path == "/library/collections":
+		items, ok := f.itemsFromURI(query.Get("uri"))
+		if !ok || query.Get("sectionId") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		key := fmt.Sprintf("9%d", len(f.keys))
+		f.keys[query.Get("title")] = key
+		f.collections[key] = items
+		writeJSON(w, map[string]interface{}{"MediaContainer": map[string]interface{}{
+			"Metadata": []map[string]string{{"ratingKey": key, "title": query.Get("title")}},
+		}})
+
+	case r.Method == "PUT" && len(parts) == 4 && parts[1] == "collections" && parts[3] == "items":
+		items, ok := f.itemsFromURI(query.Get("uri"))
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.collections[parts[2]] = append(f.collections[parts[2]], items...)
+
+	case r.Method == "DELETE" && len(parts) == 5 && parts[1] == "collections" && parts[3] == "items":
+		items := f.collections[parts[2]]
+		for i, item := range items {
+			if item == parts[4] {
+				f.collections[parts[2]] = append(items[:i:i], items[i+1:]...)
+				break
+			}
+		}
+
+	case r.Method == "GET" && len(parts) == 3 && parts[1] == "metadata":
+		tags := f.tags[parts[2]]
+		toJSON := func(field string) []map[string]string {
+			var out []map[string]string
+			for _, tag := range tags[field] {
+				out = append(out, map[string]string{"tag": tag})
+			}
+			return out
+		}
+		writeJSON(w, map[string]interface{}{"MediaContainer": map[string]interface{}{
+			"Metadata": []map[string]interface{}{{"ratingKey": parts[2], "Label": toJSON("label"), "Genre": toJSON("genre")}},
+		}})
+
+	case r.Method == "PUT" && len(parts) == 4 && parts[1] == "sections" && parts[3] == "all":
+		// Like Plex, an indexed tag list replaces the field and tag- removes
+		// from it
+		id := query.Get("id")
+		if f.tags[id] == nil {
+			f.tags[id] = make(map[string][]string)
+		}
+		for _, field := range []string{"label", "genre", "collection"} {
+			var list []string
+			for i := 0; ; i++ {
+				value, ok := query[fmt.Sprintf("%s[%d].tag.tag", field, i)]
+				if !ok {
+					break
+				}
+				list = append(list, value[0])
+			}
+			if list != nil {
+				f.tags[id][field] = list
+			}
+			if remove := query.Get(field + "[].tag.tag-"); remove != "" {
+				var kept []string
+				for _, tag := range f.tags[id][field] {
+					if !strings.Contains(","+remove+",", ","+tag+",") {
+						kept = append(kept, tag)
+					}
+				}
+				f.tags[id][field] = kept
+			}
+		}
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakePlex) itemsFromURI(uri string) ([]string, bool) {
+	prefix := "server://" + fakeMachineID + "/com.plexapp.plugins.library/library/metadata/"
+	if !strings.HasPrefix(uri, prefix) {
+		return nil, false
+	}
+	return strings.Split(strings.TrimPrefix(uri, prefix), ","), true
+}
+
+func TestPlexCollections(t *testing.T) {
+	fake, plex := newFakePlex(t)
+
+	items, err := plex.GetCollectionItems("1", "Watchlist")
+	if err != nil || len(items) != 0 {
+		t.Fatalf("missing collection = %v, %v", items, err)
+	}
+
+	if err := plex.AddToCollection("1", "Watchlist", []string{"10", "11"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := plex.AddToCollection("1", "Watchlist", []string{"12"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := plex.RemoveFromCollection("1", "Watchlist", []string{"11"}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err = plex.GetCollectionItems("1", "Watchlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(items)
+	if strings.Join(items, ",") != "10,12" {
+		t.Errorf("collection items = %v, want [10 12]", items)
+	}
+	if len(fake.keys) != 1 {
+		t.Errorf("created %d collections, want 1", len(fake.keys))
+	}
+
+	// Membership is edited through the collection, never the show's tags,
+	// so the user's other collections are kept
+	for _, edit := range fake.edits {
+		if strings.HasSuffix(edit, "/all") {
+			t.Errorf("collection change edited show tags: %s", edit)
+		}
+	}
+}
+
+func TestPlexCollectionsNeedLibrary(t *testing.T) {
+	_, plex := newFakePlex(t)
+
+	if err := plex.AddToCollection("", "Watchlist", []string{"10"}); !errors.Is(err, domain.ErrLibraryNotSet) {
+		t.Errorf("AddToCollection error = %v, want ErrLibraryNotSet", err)
+	}
+	if _, err := plex.GetCollectionItems("", "Watchlist"); !errors.Is(err, domain.ErrLibraryNotSet) {
+		t.Errorf("GetCollectionItems error = %v, want ErrLibraryNotSet", err)
+	}
+}
+
+func TestPlexUpdateItemMetadataKeepsTags(t *testing.T) {
+	fake, plex := newFakePlex(t)
+	fake.tags["10"] = map[string][]string{
+		"label": {"favourite", "anilist-1"},
+		"genre": {"Action", "Drama"},
+	}
+
+	err := plex.UpdateItemMetadata("1", "10", domain.MetadataUpdate{
+		AddLabels:    []string{"anilist-21"},
+		RemoveLabels: []string{"anilist-1"},
+		AddGenres:    []string{"Comedy", "action"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := plex.GetItemMetadata("10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(metadata.Labels, ","); got != "favourite,anilist-21" {
+		t.Errorf("labels = %s, want favourite,anilist-21", got)
+	}
+	if got := strings.Join(metadata.Genres, ","); got != "Action,Drama,Comedy" {
+		t.Errorf("genres = %s, want Action,Drama,Comedy", got)
+	}
+
+	if err := plex.UpdateItemMetadata("", "10", domain.MetadataUpdate{AddLabels: []string{"x"}}); !errors.Is(err, domain.ErrLibraryNotSet) {
+		t.Errorf("UpdateItemMetadata error = %v, want ErrLibraryNotSet", err)
+	}
+}
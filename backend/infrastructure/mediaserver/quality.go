@@ -0,0 +1,124 @@
+package mediaserver
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+// resolutionHeights maps the named resolutions media servers and release
+// names use to a line count.
+var resolutionHeights = map[string]int{
+	"sd":  480,
+	"hd":  720,
+	"fhd": 1080,
+	"2k":  1440,
+	"4k":  2160,
+	"uhd": 2160,
+	"8k":  4320,
+}
+
+// ResolutionHeight turns "1080", "1080p", "4k" and the like into a line count.
+// It returns zero for anything it does not recognize.
+func ResolutionHeight(resolution string) int {
+	resolution = strings.ToLower(strings.TrimSpace(resolution))
+	if height, ok := resolutionHeights[resolution]; ok {
+		return height
+	}
+
+	height, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(resolution, "p"), "i"))
+	if err != nil || height <= 0 {
+		return 0
+	}
+	return height
+}
+
+type qualitySummary struct {
+	resolutions       map[string]bool
+	videoCodecs       map[string]bool
+	audioLanguages    map[string]bool
+	subtitleLanguages map[string]bool
+	maxHeight         int
+	episodes          int
+}
+
+func newQualitySummary() *qualitySummary {
+	return &qualitySummary{
+		resolutions:       make(map[string]bool),
+		videoCodecs:       make(map[string]bool),
+		audioLanguages:    make(map[string]bool),
+		subtitleLanguages: make(map[string]bool),
+	}
+}
+
+func (s *qualitySummary) addEpisode(episode plexMetadata) {
+	if len(episode.Media) == 0 {
+		return
+	}
+	s.episodes++
+
+	for _, media := range episode.Media {
+		s.addVideo(media.VideoResolution, media.VideoCodec, media.Height)
+
+		for _, part := range media.Parts {
+			for _, stream := range part.Streams {
+				switch stream.StreamType {
+				case plexStreamAudio:
+					s.audioLanguages[streamLanguage(stream.LanguageCode)] = true
+				case plexStreamSubtitle:
+					s.subtitleLanguages[streamLanguage(stream.LanguageCode)] = true
+				}
+			}
+		}
+	}
+}
+
+// addVideo records one file's video. A zero height is derived from the
+// resolution name.
+func (s *qualitySummary) addVideo(resolution string, codec string, height int) {
+	if height == 0 {
+		height = ResolutionHeight(resolution)
+	}
+	if height > s.maxHeight {
+		s.maxHeight = height
+	}
+	if resolution != "" {
+		s.resolutions[strings.ToLower(resolution)] = true
+	}
+	if codec != "" {
+		s.videoCodecs[strings.ToLower(codec)] = true
+	}
+}
+
+func (s *qualitySummary) result() *domain.ShowQuality {
+	return &domain.ShowQuality{
+		Resolutions:       sortedKeys(s.resolutions),
+		MaxHeight:         s.maxHeight,
+		VideoCodecs:       sortedKeys(s.videoCodecs),
+		AudioLanguages:    sortedKeys(s.audioLanguages),
+		SubtitleLanguages: sortedKeys(s.subtitleLanguages),
+		EpisodesAnalyzed:  s.episodes,
+		UpdatedAt:         time.Now(),
+	}
+}
+
+// streamLanguage reports streams without a language tag as "und", the ISO
+// 639-2 code for undetermined.
+func streamLanguage(code string) string {
+	if code == "" {
+		return "und"
+	}
+	return strings.ToLower(code)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
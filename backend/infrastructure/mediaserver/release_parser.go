@@ -0,0 +1,141 @@
+package mediaserver
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"anime-watchlist/backend/domain"
+)
+
+var (
+	bracketPattern    = regexp.MustCompile(`\[([^\]]*)\]|\(([^)]*)\)|\{([^}]*)\}`)
+	resolutionPattern = regexp.MustCompile(`(?i)^(?:\d{3,4}p|\d{3,4}x(\d{3,4}))$`)
+	crcPattern        = regexp.MustCompile(`^[0-9A-Fa-f]{8}$`)
+	yearPattern       = regexp.MustCompile(`^(19|20)\d{2}$`)
+	inlineResolution  = regexp.MustCompile(`(?i)\b(\d{3,4}p)\b`)
+
+	// Episode patterns, tried in order. Each captures the title before the
+	// episode marker, an optional season and the episode number. " - 05"
+	// comes before "2x05" and the latter takes the last match, so titles such
+	// as "3x3 Eyes" are not read as an episode.
+	episodePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^(.*?)\bS(\d{1,2})\s?E(\d{1,4})(?:v\d)?\b`),
+		regexp.MustCompile(`(?i)^(.*?)\s-\s()(\d{1,4})(?:v\d)?\b`),
+		regexp.MustCompile(`(?i)^(.*)\b(\d{1,2})x(\d{1,4})(?:v\d)?\b`),
+		regexp.MustCompile(`(?i)^(.*?)\b(?:E|EP|Episode)\s?()(\d{1,4})(?:v\d)?\b`),
+		regexp.MustCompile(`(?i)^(.*?)\s()(\d{1,4})(?:v\d)?(?:\s|$)`),
+	}
+
+	// Season markers left at the end of the title, such as "S2", "Season 2"
+	// or "2nd Season".
+	titleSeasonPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^(.*?)\s+S(\d{1,2})$`),
+		regexp.MustCompile(`(?i)^(.*?)\s+Season\s+(\d{1,2})$`),
+		regexp.MustCompile(`(?i)^(.*?)\s+(\d{1,2})(?:st|nd|rd|th)\s+Season$`),
+	}
+
+	seasonFolderPattern = regexp.MustCompile(`(?i)^(?:Season|S)\s?(\d{1,2})$`)
+	bareEpisodePattern  = regexp.MustCompile(`(?i)^(?:E|EP)?(\d{1,4})(?:v\d)?$`)
+)
+
+// ParseReleaseName reads the group, title, season, episode, resolution, CRC
+// and year from a release-style file name. The title is empty when the name
+// does not contain one, such as "S01E05.mkv".
+func ParseReleaseName(fileName string) domain.ReleaseInfo {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	info := domain.ReleaseInfo{Season: 1}
+
+	if strings.HasPrefix(name, "[") {
+		if end := strings.Index(name, "]"); end > 0 {
+			info.Group = strings.TrimSpace(name[1:end])
+			name = name[end+1:]
+		}
+	}
+
+	// Bracketed tags carry metadata but never the title or episode
+	name = bracketPattern.ReplaceAllStringFunc(name, func(tag string) string {
+		for _, part := range strings.FieldsFunc(tag[1:len(tag)-1], func(r rune) bool { return r == ' ' || r == ',' }) {
+			switch {
+			case resolutionPattern.MatchString(part):
+				info.Resolution = normalizeResolution(part)
+			case crcPattern.MatchString(part):
+				info.CRC = strings.ToUpper(part)
+			case yearPattern.MatchString(part):
+				info.Year, _ = strconv.Atoi(part)
+			}
+		}
+		return " "
+	})
+
+	// Scene-style names use dots or underscores instead of spaces
+	if !strings.Contains(name, " ") || strings.Count(name, "_") > strings.Count(name, " ") {
+		name = strings.NewReplacer(".", " ", "_", " ").Replace(name)
+	}
+	name = strings.Join(strings.Fields(name), " ")
+
+	if info.Resolution == "" {
+		if match := inlineResolution.FindStringSubmatch(name); match != nil {
+			info.Resolution = strings.ToLower(match[1])
+		}
+	}
+
+	if match := bareEpisodePattern.FindStringSubmatch(name); match != nil {
+		info.Episode, _ = strconv.Atoi(match[1])
+		return info
+	}
+
+	title := name
+	for _, pattern := range episodePatterns {
+		match := pattern.FindStringSubmatch(name)
+		if match == nil || isResolutionOrYear(match[3]) {
+			continue
+		}
+		title = match[1]
+		if match[2] != "" {
+			info.Season, _ = strconv.Atoi(match[2])
+		}
+		info.Episode, _ = strconv.Atoi(match[3])
+		break
+	}
+
+	title = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(title), "-"))
+	for _, pattern := range titleSeasonPatterns {
+		if match := pattern.FindStringSubmatch(title); match != nil {
+			title = match[1]
+			info.Season, _ = strconv.Atoi(match[2])
+			break
+		}
+	}
+
+	info.Title = strings.TrimSpace(title)
+	return info
+}
+
+// folderInfo reads the show title and year from the folder a file is in,
+// looking past "Season 2" style folders, whose number is returned as season.
+func folderInfo(path string) (domain.ReleaseInfo, int) {
+	dir := filepath.Dir(path)
+	season := 0
+
+	if match := seasonFolderPattern.FindStringSubmatch(filepath.Base(dir)); match != nil {
+		season, _ = strconv.Atoi(match[1])
+		dir = filepath.Dir(dir)
+	}
+
+	// Folder names have no extension, so keep dots such as "Dr. Stone"
+	return ParseReleaseName(filepath.Base(dir) + ".dir"), season
+}
+
+func isResolutionOrYear(value string) bool {
+	return yearPattern.MatchString(value) || value == "480" || value == "720" || value == "1080" || value == "2160"
+}
+
+func normalizeResolution(value string) string {
+	value = strings.ToLower(value)
+	if match := resolutionPattern.FindStringSubmatch(value); match != nil && match[1] != "" {
+		return match[1] + "p"
+	}
+	return value
+}
@@ -0,0 +1,94 @@
+package mediaserver
+
+import (
+	"testing"
+
+	"anime-watchlist/backend/domain"
+)
+
+func TestParseReleaseName(t *testing.T) {
+	tests := []struct {
+		name string
+		want domain.ReleaseInfo
+	}{
+		{
+			"[SubsPlease] Sousou no Frieren - 05 (1080p) [A1B2C3D4].mkv",
+			domain.ReleaseInfo{Group: "SubsPlease", Title: "Sousou no Frieren", Season: 1, Episode: 5, Resolution: "1080p", CRC: "A1B2C3D4"},
+		},
+		{
+			"[Group] 3x3 Eyes - 05 [1080p].mkv",
+			domain.ReleaseInfo{Group: "Group", Title: "3x3 Eyes", Season: 1, Episode: 5, Resolution: "1080p"},
+		},
+		{
+			"3x3 Eyes 2x05.mkv",
+			domain.ReleaseInfo{Title: "3x3 Eyes", Season: 2, Episode: 5},
+		},
+		{
+			"Show Name 2x05.mkv",
+			domain.ReleaseInfo{Title: "Show Name", Season: 2, Episode: 5},
+		},
+		{
+			"Show.Name.S02E07.720p.WEB.mkv",
+			domain.ReleaseInfo{Title: "Show Name", Season: 2, Episode: 7, Resolution: "720p"},
+		},
+		{
+			"[Erai-raws] Oshi no Ko 2nd Season - 03v2 [1080p].mkv",
+			domain.ReleaseInfo{Group: "Erai-raws", Title: "Oshi no Ko", Season: 2, Episode: 3, Resolution: "1080p"},
+		},
+		{
+			"Mob Psycho 100 S3 - 12.mkv",
+			domain.ReleaseInfo{Title: "Mob Psycho 100", Season: 3, Episode: 12},
+		},
+		{
+			"Show_Name_Episode_11_(1920x1080).mkv",
+			domain.ReleaseInfo{Title: "Show Name", Season: 1, Episode: 11, Resolution: "1080p"},
+		},
+		{
+			"Cowboy Bebop 05 (1998).mkv",
+			domain.ReleaseInfo{Title: "Cowboy Bebop", Season: 1, Episode: 5, Year: 1998},
+		},
+		{
+			"Show Name - 1080p.mkv",
+			domain.ReleaseInfo{Title: "Show Name - 1080p", Season: 1, Resolution: "1080p"},
+		},
+		{
+			"S01E05.mkv",
+			domain.ReleaseInfo{Season: 1, Episode: 5},
+		},
+		{
+			"E07.mkv",
+			domain.ReleaseInfo{Season: 1, Episode: 7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseReleaseName(tt.name); got != tt.want {
+				t.Errorf("ParseReleaseName(%q)\n got %+v\nwant %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFolderInfo(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantTitle  string
+		wantYear   int
+		wantSeason int
+	}{
+		{"/anime/Dr. Stone (2019)/Season 2/Dr. Stone - 03.mkv", "Dr. Stone", 2019, 2},
+		{"/anime/Frieren/Frieren - 01.mkv", "Frieren", 0, 0},
+		{"/anime/Show Name/S3/E01.mkv", "Show Name", 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			info, season := folderInfo(tt.path)
+			if info.Title != tt.wantTitle || info.Year != tt.wantYear || season != tt.wantSeason {
+				t.Errorf("folderInfo(%q) = %q (%d), season %d; want %q (%d), season %d",
+					tt.path, info.Title, info.Year, season, tt.wantTitle, tt.wantYear, tt.wantSeason)
+			}
+		})
+	}
+}
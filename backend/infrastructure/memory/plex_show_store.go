@@ -0,0 +1,392 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+var _ domain.PlexShowStore = (*PlexShowStore)(nil)
+
+// PlexShowStore mirrors the mapping rules of the SQLite store: locked
+// mappings survive syncs and non-manual changes, and every mapping change is
+// recorded in history so it can be undone.
+type PlexShowStore struct {
+	mu            sync.RWMutex
+	shows         map[int]*domain.PlexShow
+	history       []domain.MappingHistoryEntry
+	nextShowID    int
+	nextHistoryID int
+}
+
+func NewPlexShowStore() *PlexShowStore {
+	return &PlexShowStore{shows: make(map[int]*domain.PlexShow)}
+}
+
+func (s *PlexShowStore) UpsertPlexShow(show *domain.PlexShow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.upsert(show)
+}
+
+func (s *PlexShowStore) UpsertPlexShows(shows []domain.PlexShow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check every show first so a collision saves none of them
+	for i := range shows {
+		if err := s.checkOwner(&shows[i]); err != nil {
+			return err
+		}
+	}
+	for i := range shows {
+		if err := s.upsert(&shows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PlexShowStore) checkOwner(show *domain.PlexShow) error {
+	stored, ok := s.shows[show.PlexID]
+	if ok && !domain.RefOf(stored).SameItem(domain.RefOf(show)) {
+		return fmt.Errorf("%w: %d is %s item %s", domain.ErrShowIDCollision, show.PlexID, stored.Source, stored.ExternalID)
+	}
+	return nil
+}
+
+// upsert saves a show; the caller holds the lock.
+func (s *PlexShowStore) upsert(show *domain.PlexShow) error {
+	if err := s.checkOwner(show); err != nil {
+		return err
+	}
+
+	stored, exists := s.shows[show.PlexID]
+	if !exists {
+		s.nextShowID++
+		stored = &domain.PlexShow{ID: s.nextShowID, PlexID: show.PlexID}
+		s.shows[show.PlexID] = stored
+	}
+
+	previousID, previousSource, locked := stored.AnilistID, stored.MappingSource, stored.MappingLocked
+
+	stored.Source = show.Source
+	if stored.Source == "" {
+		stored.Source = "plex"
+	}
+	stored.ExternalID = show.ExternalID
+	stored.Title = show.Title
+	stored.GUID = show.GUID
+	stored.Year = show.Year
+	stored.EpisodeCount = show.EpisodeCount
+	stored.LastUpdated = show.LastUpdated
+
+	if show.AnilistID == nil || locked {
+		return nil
+	}
+
+	stored.AnilistID = copyID(show.AnilistID)
+	stored.MappingSource = show.MappingSource
+
+	if !exists || !sameID(previousID, show.AnilistID) {
+		s.addHistory(domain.MappingHistoryEntry{
+			PlexID:       show.PlexID,
+			Action:       domain.MappingActionMap,
+			OldAnilistID: previousID,
+			NewAnilistID: copyID(show.AnilistID),
+			OldSource:    previousSource,
+			NewSource:    show.MappingSource,
+			Actor:        "sync",
+		})
+	}
+	return nil
+}
+
+func (s *PlexShowStore) GetShowRefs() (map[int]domain.ShowRef, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	refs := make(map[int]domain.ShowRef, len(s.shows))
+	for plexID, show := range s.shows {
+		refs[plexID] = domain.RefOf(show)
+	}
+	return refs, nil
+}
+
+func (s *PlexShowStore) GetPlexShowByPlexID(plexID int) (*domain.PlexShow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	show, ok := s.shows[plexID]
+	if !ok {
+		return nil, domain.ErrShowNotFound
+	}
+
+	copied := copyShow(show)
+	return &copied, nil
+}
+
+func (s *PlexShowStore) GetAllPlexShows() ([]domain.PlexShow, error) {
+	return s.filter(func(show *domain.PlexShow) bool { return true }), nil
+}
+
+func (s *PlexShowStore) GetUnmappedShows() ([]domain.PlexShow, error) {
+	return s.filter(func(show *domain.PlexShow) bool {
+		return show.AnilistID == nil && !show.Ignored
+	}), nil
+}
+
+func (s *PlexShowStore) GetIgnoredShows() ([]domain.PlexShow, error) {
+	return s.filter(func(show *domain.PlexShow) bool { return show.Ignored }), nil
+}
+
+func (s *PlexShowStore) SearchShowsOnServer(searchTerm string) ([]domain.PlexShow, error) {
+	searchTerm = strings.ToLower(searchTerm)
+	return s.filter(func(show *domain.PlexShow) bool {
+		return strings.Contains(strings.ToLower(show.Title), searchTerm)
+	}), nil
+}
+
+func (s *PlexShowStore) GetMappedAnilistIDs() ([]int, error) {
+	seen := make(map[int]bool)
+	var ids []int
+	for _, show := range s.filter(isMapped) {
+		if !seen[*show.AnilistID] {
+			seen[*show.AnilistID] = true
+			ids = append(ids, *show.AnilistID)
+		}
+	}
+	return ids, nil
+}
+
+func (s *PlexShowStore) GetShowsOnServer() (int, error) {
+	return s.count(func(show *domain.PlexShow) bool { return !show.Ignored }), nil
+}
+
+func (s *PlexShowStore) GetMappedShowsCount() (int, error) {
+	return s.count(isMapped), nil
+}
+
+func (s *PlexShowStore) GetUnmappedShowsCount() (int, error) {
+	return s.count(func(show *domain.PlexShow) bool {
+		return show.AnilistID == nil && !show.Ignored
+	}), nil
+}
+
+func (s *PlexShowStore) GetIgnoredShowsCount() (int, error) {
+	return s.count(func(show *domain.PlexShow) bool { return show.Ignored }), nil
+}
+
+func (s *PlexShowStore) UpdateShowMapping(plexID int, anilistID int, source string, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	show, ok := s.shows[plexID]
+	if !ok {
+		return domain.ErrShowNotFound
+	}
+	if show.MappingLocked && source != domain.MappingSourceManual {
+		return domain.ErrMappingLocked
+	}
+
+	previousID, previousSource := show.AnilistID, show.MappingSource
+	setMapping(show, &anilistID, source)
+
+	if !sameID(previousID, &anilistID) || previousSource != source {
+		s.addHistory(domain.MappingHistoryEntry{
+			PlexID:       plexID,
+			Action:       domain.MappingActionMap,
+			OldAnilistID: previousID,
+			NewAnilistID: copyID(&anilistID),
+			OldSource:    previousSource,
+			NewSource:    source,
+			Actor:        actor,
+		})
+	}
+
+	return nil
+}
+
+func (s *PlexShowStore) ClearShowMapping(plexID int, actor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	show, ok := s.shows[plexID]
+	if !ok {
+		return domain.ErrShowNotFound
+	}
+	if show.AnilistID == nil && !show.MappingLocked {
+		return nil
+	}
+
+	previousID, previousSource := show.AnilistID, show.MappingSource
+	setMapping(show, nil, "")
+
+	s.addHistory(domain.MappingHistoryEntry{
+		PlexID:       plexID,
+		Action:       domain.MappingActionUnmap,
+		OldAnilistID: previousID,
+		OldSource:    previousSource,
+		Actor:        actor,
+	})
+	return nil
+}
+
+func (s *PlexShowStore) UndoLastMapping(plexID int, actor string) (*domain.MappingHistoryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := -1
+	for i := len(s.history) - 1; i >= 0; i-- {
+		entry := s.history[i]
+		if entry.PlexID == plexID && !entry.Undone && entry.Action != domain.MappingActionUndo {
+			last = i
+			break
+		}
+	}
+	if last == -1 {
+		return nil, domain.ErrNoMappingHistory
+	}
+
+	show, ok := s.shows[plexID]
+	if !ok {
+		return nil, domain.ErrShowNotFound
+	}
+
+	previous := s.history[last]
+	currentID, currentSource := show.AnilistID, show.MappingSource
+	setMapping(show, copyID(previous.OldAnilistID), previous.OldSource)
+	s.history[last].Undone = true
+
+	entry := s.addHistory(domain.MappingHistoryEntry{
+		PlexID:       plexID,
+		Action:       domain.MappingActionUndo,
+		OldAnilistID: currentID,
+		NewAnilistID: copyID(previous.OldAnilistID),
+		OldSource:    currentSource,
+		NewSource:    previous.OldSource,
+		Actor:        actor,
+	})
+	return &entry, nil
+}
+
+// GetMappingHistory returns the newest change first.
+func (s *PlexShowStore) GetMappingHistory(plexID int) ([]domain.MappingHistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := []domain.MappingHistoryEntry{}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].PlexID == plexID {
+			history = append(history, s.history[i])
+		}
+	}
+	return history, nil
+}
+
+func (s *PlexShowStore) MarkAutoMapped(plexID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if show, ok := s.shows[plexID]; ok {
+		now := time.Now()
+		show.AutoMappedAt = &now
+	}
+	return nil
+}
+
+func (s *PlexShowStore) SetShowIgnored(plexID int, ignored bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	show, ok := s.shows[plexID]
+	if !ok {
+		return domain.ErrShowNotFound
+	}
+
+	show.Ignored = ignored
+	show.LastUpdated = time.Now()
+	return nil
+}
+
+// filter returns copies of the matching shows ordered by title.
+func (s *PlexShowStore) filter(match func(show *domain.PlexShow) bool) []domain.PlexShow {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var shows []domain.PlexShow
+	for _, show := range s.shows {
+		if match(show) {
+			shows = append(shows, copyShow(show))
+		}
+	}
+
+	sort.Slice(shows, func(i, j int) bool {
+		return shows[i].Title < shows[j].Title
+	})
+	return shows
+}
+
+func (s *PlexShowStore) count(match func(show *domain.PlexShow) bool) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, show := range s.shows {
+		if match(show) {
+			count++
+		}
+	}
+	return count
+}
+
+// addHistory must be called with s.mu held.
+func (s *PlexShowStore) addHistory(entry domain.MappingHistoryEntry) domain.MappingHistoryEntry {
+	s.nextHistoryID++
+	entry.ID = s.nextHistoryID
+	entry.CreatedAt = time.Now()
+	s.history = append(s.history, entry)
+	return entry
+}
+
+func setMapping(show *domain.PlexShow, anilistID *int, source string) {
+	show.AnilistID = anilistID
+	show.MappingSource = source
+	show.MappingLocked = source == domain.MappingSourceManual
+	show.LastUpdated = time.Now()
+}
+
+func isMapped(show *domain.PlexShow) bool {
+	return show.AnilistID != nil && !show.Ignored
+}
+
+// copyShow detaches a show from the store so callers cannot change it.
+func copyShow(show *domain.PlexShow) domain.PlexShow {
+	copied := *show
+	copied.AnilistID = copyID(show.AnilistID)
+	if show.AutoMappedAt != nil {
+		autoMappedAt := *show.AutoMappedAt
+		copied.AutoMappedAt = &autoMappedAt
+	}
+	return copied
+}
+
+func copyID(id *int) *int {
+	if id == nil {
+		return nil
+	}
+	value := *id
+	return &value
+}
+
+func sameID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
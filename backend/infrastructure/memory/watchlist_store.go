@@ -0,0 +1,114 @@
+// Package memory holds in-memory stores for tests and the ephemeral demo
+// mode. Nothing is persisted; all data is lost when the process exits.
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+var _ domain.WatchlistStore = (*WatchlistStore)(nil)
+
+type WatchlistStore struct {
+	mu     sync.RWMutex
+	items  map[int]domain.WatchlistItem
+	nextID int
+}
+
+func NewWatchlistStore() *WatchlistStore {
+	return &WatchlistStore{items: make(map[int]domain.WatchlistItem)}
+}
+
+// GetWatchlist returns the newest additions first, like the SQLite store.
+func (s *WatchlistStore) GetWatchlist() ([]domain.WatchlistItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items := make([]domain.WatchlistItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].AddedAt.Equal(items[j].AddedAt) {
+			return items[i].AddedAt.After(items[j].AddedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+
+	return items, nil
+}
+
+func (s *WatchlistStore) AddToWatchlist(anilistID int) error {
+	return s.AddToWatchlistAt(anilistID, time.Now())
+}
+
+func (s *WatchlistStore) AddToWatchlistAt(anilistID int, addedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[anilistID]; ok {
+		return fmt.Errorf("failed to add to watchlist: anime %d is already in the watchlist", anilistID)
+	}
+
+	s.nextID++
+	s.items[anilistID] = domain.WatchlistItem{
+		ID:        s.nextID,
+		AnilistID: anilistID,
+		Status:    domain.WatchStatusCurrent,
+		AddedAt:   addedAt,
+	}
+	return nil
+}
+
+func (s *WatchlistStore) SaveWatchlistItem(item *domain.WatchlistItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := *item
+	saved.Anime = nil
+	if existing, ok := s.items[item.AnilistID]; ok {
+		saved.ID = existing.ID
+		saved.AddedAt = existing.AddedAt
+	} else {
+		s.nextID++
+		saved.ID = s.nextID
+		if saved.AddedAt.IsZero() {
+			saved.AddedAt = time.Now()
+		}
+	}
+
+	s.items[item.AnilistID] = saved
+	return nil
+}
+
+func (s *WatchlistStore) RemoveFromWatchlist(anilistID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.items[anilistID]; !ok {
+		return fmt.Errorf("anime not in watchlist")
+	}
+
+	delete(s.items, anilistID)
+	return nil
+}
+
+func (s *WatchlistStore) IsInWatchlist(anilistID int) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.items[anilistID]
+	return ok, nil
+}
+
+func (s *WatchlistStore) GetWatchlistCount() (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.items), nil
+}
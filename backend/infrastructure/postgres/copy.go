@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CopyResult counts the rows copied from SQLite.
+type CopyResult struct {
+	Watchlist      int `json:"watchlist"`
+	Shows          int `json:"shows"`
+	MappingHistory int `json:"mapping_history"`
+}
+
+// copyTables lists the tables CopyFromSQLite moves, in insert order.
+var copyTables = []string{"watchlist", "plex_shows", "mapping_history"}
+
+// CopyFromSQLite copies the watchlist, shows and mapping history from an
+// existing SQLite database, keeping their IDs. Everything is copied in one
+// transaction, and the target must not hold any of that data yet so a copy
+// is never merged into a database already in use.
+func CopyFromSQLite(src *sql.DB, dst *Database) (*CopyResult, error) {
+	tx, err := dst.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, table := range copyTables {
+		var exists bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM ` + table + `)`).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", table, err)
+		}
+		if exists {
+			return nil, fmt.Errorf("postgres table %s is not empty", table)
+		}
+	}
+
+	result := &CopyResult{}
+
+	if result.Watchlist, err = copyWatchlist(src, tx); err != nil {
+		return nil, fmt.Errorf("failed to copy watchlist: %w", err)
+	}
+	if result.Shows, err = copyShows(src, tx); err != nil {
+		return nil, fmt.Errorf("failed to copy shows: %w", err)
+	}
+	if result.MappingHistory, err = copyMappingHistory(src, tx); err != nil {
+		return nil, fmt.Errorf("failed to copy mapping history: %w", err)
+	}
+
+	// Explicit IDs do not advance the identity sequences, so move them past
+	// the copied rows before anything new is inserted
+	for _, table := range copyTables {
+		query := `SELECT setval(pg_get_serial_sequence('` + table + `', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM ` + table
+		if _, err := tx.Exec(query); err != nil {
+			return nil, fmt.Errorf("failed to reset %s id sequence: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func copyWatchlist(src *sql.DB, tx *sql.Tx) (int, error) {
+	rows, err := src.Query(`
+		SELECT id, anilist_id, added_at, status, score, progress, started_at, completed_at
+		FROM watchlist
+		ORDER BY id
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var id, anilistID, progress int
+		var addedAt time.Time
+		var status string
+		var score float64
+		var startedAt, completedAt sql.NullTime
+		if err := rows.Scan(&id, &anilistID, &addedAt, &status, &score, &progress, &startedAt, &completedAt); err != nil {
+			return 0, err
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO watchlist (id, anilist_id, added_at, status, score, progress, started_at, completed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, id, anilistID, addedAt, status, score, progress, startedAt, completedAt)
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
+
+func copyShows(src *sql.DB, tx *sql.Tx) (int, error) {
+	rows, err := src.Query(`
+		SELECT id, plex_id, COALESCE(source, 'plex'), external_id, title, guid, anilist_id, mapping_source,
+			COALESCE(mapping_locked, FALSE), COALESCE(ignored, FALSE), COALESCE(year, 0), COALESCE(episode_count, 0),
+			auto_mapped_at, last_updated
+		FROM plex_shows
+		ORDER BY id
+	`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	query := `
+		INSERT INTO plex_shows (id, plex_id, source, external_id, title, guid, anilist_id, mapping_source,
+			mapping_locked, ignored, year, episode_count, auto_mapped_at, last_updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	count := 0
+	for rows.Next() {
+		show, err := scanPlexShow(rows)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(query, show.ID, show.PlexID, show.Source, nullString(show.ExternalID), show.Title,
+			nullString(show.GUID), show.AnilistID, nullString(show.MappingSource), show.MappingLocked, show.Ignored,
+			show.Year, show.EpisodeCount, show.AutoMappedAt, show.LastUpdated)
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
+
+func copyMappingHistory(src *sql.DB, tx *sql.Tx) (int, error) {
+	rows, err := src.Query(`SELECT ` + mappingHistoryColumns + ` FROM mapping_history ORDER BY id`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	query := `
+		INSERT INTO mapping_history (id, plex_id, action, old_anilist_id, new_anilist_id, old_source, new_source, actor, undone, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	count := 0
+	for rows.Next() {
+		entry, err := scanMappingHistory(rows)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(query, entry.ID, entry.PlexID, entry.Action, entry.OldAnilistID, entry.NewAnilistID,
+			nullString(entry.OldSource), nullString(entry.NewSource), entry.Actor, entry.Undone, entry.CreatedAt)
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	return count, rows.Err()
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"anime-watchlist/backend/domain"
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+func TestCopyFromSQLite(t *testing.T) {
+	dst := newTestDatabase(t)
+
+	src, err := database.New(filepath.Join(t.TempDir(), "anime.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	watchlist := database.NewWatchlistRepository(src)
+	if err := watchlist.AddToWatchlistAt(1, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	started := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	err = watchlist.SaveWatchlistItem(&domain.WatchlistItem{AnilistID: 2, Status: domain.WatchStatusCompleted, Score: 9, Progress: 24, StartedAt: &started})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shows := database.NewPlexRepository(src.DB)
+	err = shows.UpsertPlexShows([]domain.PlexShow{
+		{PlexID: 10, ExternalID: "10", Title: "Frieren", GUID: "plex://show/10", Year: 2023, AnilistID: intPtr(154587), MappingSource: domain.MappingSourceGUID},
+		{PlexID: 11, Source: "jellyfin", ExternalID: "abc", Title: "Bocchi the Rock"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shows.UpdateShowMapping(11, 130003, domain.MappingSourceManual, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := shows.SetShowIgnored(10, true); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := CopyFromSQLite(src.DB, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *result != (CopyResult{Watchlist: 2, Shows: 2, MappingHistory: 2}) {
+		t.Errorf("copied %+v", result)
+	}
+
+	copiedWatchlist := NewWatchlistStore(dst)
+	items, err := copiedWatchlist.GetWatchlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("watchlist = %+v", items)
+	}
+	for _, item := range items {
+		switch item.AnilistID {
+		case 1:
+			if !item.AddedAt.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) {
+				t.Errorf("added at = %v", item.AddedAt)
+			}
+		case 2:
+			if item.Status != domain.WatchStatusCompleted || item.Score != 9 || item.Progress != 24 ||
+				item.StartedAt == nil || item.StartedAt.Format("2006-01-02") != "2024-03-01" || item.CompletedAt != nil {
+				t.Errorf("copied entry = %+v", item)
+			}
+		}
+	}
+
+	copiedShows := NewPlexShowStore(dst.DB)
+	show, err := copiedShows.GetPlexShowByPlexID(11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.Source != "jellyfin" || show.ExternalID != "abc" || show.AnilistID == nil || *show.AnilistID != 130003 || !show.MappingLocked {
+		t.Errorf("copied show = %+v", show)
+	}
+	if show, err := copiedShows.GetPlexShowByPlexID(10); err != nil || !show.Ignored || show.GUID != "plex://show/10" {
+		t.Errorf("copied ignored show = %+v, %v", show, err)
+	}
+	history, err := copiedShows.GetMappingHistory(11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Actor != "alice" || history[0].NewSource != domain.MappingSourceManual {
+		t.Errorf("copied history = %+v", history)
+	}
+
+	// The ID sequences continue after the copied rows
+	if err := copiedWatchlist.AddToWatchlist(3); err != nil {
+		t.Errorf("adding after the copy: %v", err)
+	}
+	if err := copiedShows.UpsertPlexShow(&domain.PlexShow{PlexID: 12, ExternalID: "12", Title: "New"}); err != nil {
+		t.Errorf("saving a show after the copy: %v", err)
+	}
+	if err := copiedShows.ClearShowMapping(11, "alice"); err != nil {
+		t.Errorf("recording history after the copy: %v", err)
+	}
+
+	// A database in use is never merged into
+	if _, err := CopyFromSQLite(src.DB, dst); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("second copy error = %v", err)
+	}
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS mapping_history;
+DROP TABLE IF EXISTS plex_shows;
+DROP TABLE IF EXISTS watchlist;
@@ -0,0 +1,42 @@
+-- Tables behind the watchlist and show stores. Everything else the server
+-- keeps (jobs, schedules, caches) stays in SQLite.
+
+CREATE TABLE IF NOT EXISTS watchlist (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	anilist_id INTEGER UNIQUE NOT NULL,
+	added_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_watchlist_added_at ON watchlist(added_at);
+
+CREATE TABLE IF NOT EXISTS plex_shows (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	plex_id INTEGER UNIQUE NOT NULL,
+	source TEXT NOT NULL DEFAULT 'plex',
+	external_id TEXT,
+	title TEXT NOT NULL,
+	guid TEXT,
+	anilist_id INTEGER,
+	mapping_source TEXT,
+	mapping_locked BOOLEAN NOT NULL DEFAULT FALSE,
+	ignored BOOLEAN NOT NULL DEFAULT FALSE,
+	year INTEGER NOT NULL DEFAULT 0,
+	episode_count INTEGER NOT NULL DEFAULT 0,
+	auto_mapped_at TIMESTAMPTZ,
+	last_updated TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_plex_shows_anilist_id ON plex_shows(anilist_id);
+CREATE INDEX IF NOT EXISTS idx_plex_shows_title ON plex_shows(title);
+
+CREATE TABLE IF NOT EXISTS mapping_history (
+	id BIGINT GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+	plex_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	old_anilist_id INTEGER,
+	new_anilist_id INTEGER,
+	old_source TEXT,
+	new_source TEXT,
+	actor TEXT NOT NULL,
+	undone BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_mapping_history_plex_id ON mapping_history(plex_id);
@@ -0,0 +1,6 @@
+ALTER TABLE watchlist
+	DROP COLUMN completed_at,
+	DROP COLUMN started_at,
+	DROP COLUMN progress,
+	DROP COLUMN score,
+	DROP COLUMN status;
@@ -0,0 +1,6 @@
+-- Per-entry list state, matching SQLite migration 0004.
+ALTER TABLE watchlist ADD COLUMN status TEXT NOT NULL DEFAULT 'CURRENT';
+ALTER TABLE watchlist ADD COLUMN score DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE watchlist ADD COLUMN progress INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE watchlist ADD COLUMN started_at DATE;
+ALTER TABLE watchlist ADD COLUMN completed_at DATE;
@@ -0,0 +1,594 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+var _ domain.PlexShowStore = (*PlexShowStore)(nil)
+
+// PlexShowStore follows the same mapping rules as the SQLite repository.
+// Mapping changes and their history entries are written in one transaction,
+// with the show row locked so concurrent writers cannot interleave.
+type PlexShowStore struct {
+	db *sql.DB
+}
+
+func NewPlexShowStore(db *sql.DB) *PlexShowStore {
+	return &PlexShowStore{db: db}
+}
+
+const plexShowColumns = `id, plex_id, source, external_id, title, guid, anilist_id, mapping_source, mapping_locked, ignored, year, episode_count, auto_mapped_at, last_updated`
+
+func (s *PlexShowStore) UpsertPlexShow(show *domain.PlexShow) error {
+	return s.UpsertPlexShows([]domain.PlexShow{*show})
+}
+
+func (s *PlexShowStore) UpsertPlexShows(shows []domain.PlexShow) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	upserter, err := prepareShowUpsert(tx)
+	if err != nil {
+		return err
+	}
+	defer upserter.Close()
+
+	for i := range shows {
+		if err := upserter.upsert(&shows[i]); err != nil {
+			return fmt.Errorf("failed to save show %s: %w", shows[i].Title, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+type showUpsert struct {
+	owner   *sql.Stmt
+	state   *sql.Stmt
+	save    *sql.Stmt
+	history *sql.Stmt
+}
+
+func prepareShowUpsert(tx *sql.Tx) (*showUpsert, error) {
+	query := `
+		INSERT INTO plex_shows (plex_id, source, external_id, title, guid, anilist_id, mapping_source, year, episode_count, last_updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (plex_id) DO UPDATE SET
+			source = excluded.source,
+			external_id = excluded.external_id,
+			title = excluded.title,
+			guid = excluded.guid,
+			anilist_id = CASE
+				WHEN plex_shows.mapping_locked OR excluded.anilist_id IS NULL THEN plex_shows.anilist_id
+				ELSE excluded.anilist_id
+			END,
+			mapping_source = CASE
+				WHEN plex_shows.mapping_locked OR excluded.anilist_id IS NULL THEN plex_shows.mapping_source
+				ELSE excluded.mapping_source
+			END,
+			year = excluded.year,
+			episode_count = excluded.episode_count,
+			last_updated = excluded.last_updated
+	`
+
+	u := &showUpsert{}
+	var err error
+	if u.owner, err = tx.Prepare(`SELECT source, external_id FROM plex_shows WHERE plex_id = $1`); err != nil {
+		return nil, err
+	}
+	if u.state, err = tx.Prepare(mappingStateQuery); err != nil {
+		u.Close()
+		return nil, err
+	}
+	if u.save, err = tx.Prepare(query); err != nil {
+		u.Close()
+		return nil, err
+	}
+	if u.history, err = tx.Prepare(insertMappingHistoryQuery); err != nil {
+		u.Close()
+		return nil, err
+	}
+	return u, nil
+}
+
+func (u *showUpsert) upsert(show *domain.PlexShow) error {
+	var owner domain.ShowRef
+	var externalID sql.NullString
+	err := u.owner.QueryRow(show.PlexID).Scan(&owner.Source, &externalID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	owner.ExternalID = externalID.String
+	if err == nil && !owner.SameItem(domain.RefOf(show)) {
+		return fmt.Errorf("%w: %d is %s item %s", domain.ErrShowIDCollision, show.PlexID, owner.Source, owner.ExternalID)
+	}
+
+	var previous *mappingState
+	if show.AnilistID != nil {
+		var err error
+		previous, err = scanMappingState(u.state.QueryRow(show.PlexID))
+		if err != nil && err != domain.ErrShowNotFound {
+			return err
+		}
+	}
+
+	source := show.Source
+	if source == "" {
+		source = "plex"
+	}
+
+	_, err = u.save.Exec(show.PlexID, source, nullString(show.ExternalID), show.Title, show.GUID,
+		show.AnilistID, nullString(show.MappingSource), show.Year, show.EpisodeCount, show.LastUpdated)
+	if err != nil {
+		return err
+	}
+
+	if show.AnilistID != nil && (previous == nil || (!previous.locked && !sameAnilistID(previous.anilistID, show.AnilistID))) {
+		entry := &domain.MappingHistoryEntry{
+			PlexID:       show.PlexID,
+			Action:       domain.MappingActionMap,
+			NewAnilistID: show.AnilistID,
+			NewSource:    show.MappingSource,
+			Actor:        "sync",
+		}
+		if previous != nil {
+			entry.OldAnilistID = previous.anilistID
+			entry.OldSource = previous.source
+		}
+		entry.CreatedAt = time.Now()
+		if err := u.history.QueryRow(mappingHistoryArgs(entry)...).Scan(&entry.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (u *showUpsert) Close() {
+	for _, stmt := range []*sql.Stmt{u.owner, u.state, u.save, u.history} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
+
+func (s *PlexShowStore) GetShowRefs() (map[int]domain.ShowRef, error) {
+	rows, err := s.db.Query(`SELECT plex_id, source, external_id FROM plex_shows`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refs := make(map[int]domain.ShowRef)
+	for rows.Next() {
+		var plexID int
+		var ref domain.ShowRef
+		var externalID sql.NullString
+		if err := rows.Scan(&plexID, &ref.Source, &externalID); err != nil {
+			return nil, err
+		}
+		ref.ExternalID = externalID.String
+		refs[plexID] = ref
+	}
+
+	return refs, rows.Err()
+}
+
+func (s *PlexShowStore) GetPlexShowByPlexID(plexID int) (*domain.PlexShow, error) {
+	query := `SELECT ` + plexShowColumns + ` FROM plex_shows WHERE plex_id = $1`
+
+	show, err := scanPlexShow(s.db.QueryRow(query, plexID))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrShowNotFound
+	}
+	return show, err
+}
+
+func (s *PlexShowStore) GetAllPlexShows() ([]domain.PlexShow, error) {
+	return s.queryPlexShows(`SELECT ` + plexShowColumns + ` FROM plex_shows ORDER BY title`)
+}
+
+func (s *PlexShowStore) GetUnmappedShows() ([]domain.PlexShow, error) {
+	return s.queryPlexShows(`
+		SELECT ` + plexShowColumns + `
+		FROM plex_shows
+		WHERE anilist_id IS NULL AND NOT ignored
+		ORDER BY title
+	`)
+}
+
+func (s *PlexShowStore) GetIgnoredShows() ([]domain.PlexShow, error) {
+	return s.queryPlexShows(`SELECT ` + plexShowColumns + ` FROM plex_shows WHERE ignored ORDER BY title`)
+}
+
+// SearchShowsOnServer matches case-insensitively, like LIKE does in SQLite.
+func (s *PlexShowStore) SearchShowsOnServer(searchTerm string) ([]domain.PlexShow, error) {
+	query := `
+		SELECT ` + plexShowColumns + `
+		FROM plex_shows
+		WHERE title ILIKE $1
+		ORDER BY title
+	`
+
+	return s.queryPlexShows(query, "%"+searchTerm+"%")
+}
+
+func (s *PlexShowStore) GetMappedAnilistIDs() ([]int, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT anilist_id FROM plex_shows WHERE anilist_id IS NOT NULL AND NOT ignored`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (s *PlexShowStore) GetShowsOnServer() (int, error) {
+	return s.count(`SELECT COUNT(*) FROM plex_shows WHERE NOT ignored`)
+}
+
+func (s *PlexShowStore) GetMappedShowsCount() (int, error) {
+	return s.count(`SELECT COUNT(*) FROM plex_shows WHERE anilist_id IS NOT NULL AND NOT ignored`)
+}
+
+func (s *PlexShowStore) GetUnmappedShowsCount() (int, error) {
+	return s.count(`SELECT COUNT(*) FROM plex_shows WHERE anilist_id IS NULL AND NOT ignored`)
+}
+
+func (s *PlexShowStore) GetIgnoredShowsCount() (int, error) {
+	return s.count(`SELECT COUNT(*) FROM plex_shows WHERE ignored`)
+}
+
+func (s *PlexShowStore) UpdateShowMapping(plexID int, anilistID int, source string, actor string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	previous, err := getMappingState(tx, plexID)
+	if err != nil {
+		return err
+	}
+
+	if previous.locked && source != domain.MappingSourceManual {
+		return domain.ErrMappingLocked
+	}
+
+	if err := setMapping(tx, plexID, &anilistID, source); err != nil {
+		return err
+	}
+
+	if !sameAnilistID(previous.anilistID, &anilistID) || previous.source != source {
+		err := insertMappingHistory(tx, &domain.MappingHistoryEntry{
+			PlexID:       plexID,
+			Action:       domain.MappingActionMap,
+			OldAnilistID: previous.anilistID,
+			NewAnilistID: &anilistID,
+			OldSource:    previous.source,
+			NewSource:    source,
+			Actor:        actor,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *PlexShowStore) ClearShowMapping(plexID int, actor string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	previous, err := getMappingState(tx, plexID)
+	if err != nil {
+		return err
+	}
+
+	if previous.anilistID == nil && !previous.locked {
+		return nil
+	}
+
+	if err := setMapping(tx, plexID, nil, ""); err != nil {
+		return err
+	}
+
+	err = insertMappingHistory(tx, &domain.MappingHistoryEntry{
+		PlexID:       plexID,
+		Action:       domain.MappingActionUnmap,
+		OldAnilistID: previous.anilistID,
+		OldSource:    previous.source,
+		Actor:        actor,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *PlexShowStore) UndoLastMapping(plexID int, actor string) (*domain.MappingHistoryEntry, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the show first so two undos cannot pick the same entry
+	current, err := getMappingState(tx, plexID)
+	if err == domain.ErrShowNotFound {
+		return nil, domain.ErrNoMappingHistory
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + mappingHistoryColumns + `
+		FROM mapping_history
+		WHERE plex_id = $1 AND NOT undone AND action != $2
+		ORDER BY id DESC
+		LIMIT 1
+	`
+
+	last, err := scanMappingHistory(tx.QueryRow(query, plexID, domain.MappingActionUndo))
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrNoMappingHistory
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setMapping(tx, plexID, last.OldAnilistID, last.OldSource); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(`UPDATE mapping_history SET undone = TRUE WHERE id = $1`, last.ID); err != nil {
+		return nil, err
+	}
+
+	entry := &domain.MappingHistoryEntry{
+		PlexID:       plexID,
+		Action:       domain.MappingActionUndo,
+		OldAnilistID: current.anilistID,
+		NewAnilistID: last.OldAnilistID,
+		OldSource:    current.source,
+		NewSource:    last.OldSource,
+		Actor:        actor,
+	}
+	if err := insertMappingHistory(tx, entry); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
+
+func (s *PlexShowStore) GetMappingHistory(plexID int) ([]domain.MappingHistoryEntry, error) {
+	query := `
+		SELECT ` + mappingHistoryColumns + `
+		FROM mapping_history
+		WHERE plex_id = $1
+		ORDER BY id DESC
+	`
+
+	rows, err := s.db.Query(query, plexID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []domain.MappingHistoryEntry{}
+	for rows.Next() {
+		entry, err := scanMappingHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *entry)
+	}
+
+	return history, rows.Err()
+}
+
+func (s *PlexShowStore) MarkAutoMapped(plexID int) error {
+	_, err := s.db.Exec(`UPDATE plex_shows SET auto_mapped_at = $1 WHERE plex_id = $2`, time.Now(), plexID)
+	return err
+}
+
+func (s *PlexShowStore) SetShowIgnored(plexID int, ignored bool) error {
+	result, err := s.db.Exec(`UPDATE plex_shows SET ignored = $1, last_updated = $2 WHERE plex_id = $3`, ignored, time.Now(), plexID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return domain.ErrShowNotFound
+	}
+
+	return nil
+}
+
+func (s *PlexShowStore) queryPlexShows(query string, args ...interface{}) ([]domain.PlexShow, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shows []domain.PlexShow
+	for rows.Next() {
+		show, err := scanPlexShow(rows)
+		if err != nil {
+			return nil, err
+		}
+		shows = append(shows, *show)
+	}
+
+	return shows, rows.Err()
+}
+
+func (s *PlexShowStore) count(query string) (int, error) {
+	var count int
+	err := s.db.QueryRow(query).Scan(&count)
+	return count, err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanPlexShow(row rowScanner) (*domain.PlexShow, error) {
+	show := &domain.PlexShow{}
+	var externalID, guid, mappingSource sql.NullString
+	var autoMappedAt sql.NullTime
+
+	err := row.Scan(
+		&show.ID,
+		&show.PlexID,
+		&show.Source,
+		&externalID,
+		&show.Title,
+		&guid,
+		&show.AnilistID,
+		&mappingSource,
+		&show.MappingLocked,
+		&show.Ignored,
+		&show.Year,
+		&show.EpisodeCount,
+		&autoMappedAt,
+		&show.LastUpdated,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	show.ExternalID = externalID.String
+	show.GUID = guid.String
+	show.MappingSource = mappingSource.String
+	if autoMappedAt.Valid {
+		show.AutoMappedAt = &autoMappedAt.Time
+	}
+	return show, nil
+}
+
+type mappingState struct {
+	anilistID *int
+	source    string
+	locked    bool
+}
+
+// mappingStateQuery reads a show's mapping and locks its row until the
+// transaction ends.
+const mappingStateQuery = `SELECT anilist_id, mapping_source, mapping_locked FROM plex_shows WHERE plex_id = $1 FOR UPDATE`
+
+func getMappingState(tx *sql.Tx, plexID int) (*mappingState, error) {
+	return scanMappingState(tx.QueryRow(mappingStateQuery, plexID))
+}
+
+func scanMappingState(row rowScanner) (*mappingState, error) {
+	state := &mappingState{}
+	var source sql.NullString
+
+	err := row.Scan(&state.anilistID, &source, &state.locked)
+	if err == sql.ErrNoRows {
+		return nil, domain.ErrShowNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state.source = source.String
+	return state, nil
+}
+
+func setMapping(tx *sql.Tx, plexID int, anilistID *int, source string) error {
+	query := `
+		UPDATE plex_shows
+		SET anilist_id = $1, mapping_source = $2, mapping_locked = $3, last_updated = $4
+		WHERE plex_id = $5
+	`
+
+	_, err := tx.Exec(query, anilistID, nullString(source), source == domain.MappingSourceManual, time.Now(), plexID)
+	return err
+}
+
+const mappingHistoryColumns = `id, plex_id, action, old_anilist_id, new_anilist_id, old_source, new_source, actor, undone, created_at`
+
+const insertMappingHistoryQuery = `
+	INSERT INTO mapping_history (plex_id, action, old_anilist_id, new_anilist_id, old_source, new_source, actor, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id
+`
+
+func insertMappingHistory(tx *sql.Tx, entry *domain.MappingHistoryEntry) error {
+	entry.CreatedAt = time.Now()
+	return tx.QueryRow(insertMappingHistoryQuery, mappingHistoryArgs(entry)...).Scan(&entry.ID)
+}
+
+func mappingHistoryArgs(entry *domain.MappingHistoryEntry) []interface{} {
+	return []interface{}{entry.PlexID, entry.Action, entry.OldAnilistID, entry.NewAnilistID,
+		nullString(entry.OldSource), nullString(entry.NewSource), entry.Actor, entry.CreatedAt}
+}
+
+func scanMappingHistory(row rowScanner) (*domain.MappingHistoryEntry, error) {
+	entry := &domain.MappingHistoryEntry{}
+	var oldSource, newSource sql.NullString
+
+	err := row.Scan(
+		&entry.ID,
+		&entry.PlexID,
+		&entry.Action,
+		&entry.OldAnilistID,
+		&entry.NewAnilistID,
+		&oldSource,
+		&newSource,
+		&entry.Actor,
+		&entry.Undone,
+		&entry.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.OldSource = oldSource.String
+	entry.NewSource = newSource.String
+	return entry, nil
+}
+
+func sameAnilistID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,249 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestPlexShowStoreUpsert(t *testing.T) {
+	store := NewPlexShowStore(newTestDatabase(t).DB)
+
+	now := time.Now().Truncate(time.Second)
+	err := store.UpsertPlexShows([]domain.PlexShow{
+		{PlexID: 1, ExternalID: "1", Title: "Frieren", GUID: "plex://show/1", Year: 2023, EpisodeCount: 28, LastUpdated: now,
+			AnilistID: intPtr(154587), MappingSource: domain.MappingSourceGUID},
+		{PlexID: 2, Source: "jellyfin", ExternalID: "abc", Title: "Bocchi the Rock", LastUpdated: now},
+		{PlexID: 3, Source: "jellyfin", ExternalID: "def", Title: "Another Show", LastUpdated: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	show, err := store.GetPlexShowByPlexID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.Source != "plex" || show.GUID != "plex://show/1" || show.Year != 2023 || show.EpisodeCount != 28 ||
+		show.AnilistID == nil || *show.AnilistID != 154587 || show.MappingSource != domain.MappingSourceGUID {
+		t.Errorf("show = %+v", show)
+	}
+	if _, err := store.GetPlexShowByPlexID(99); !errors.Is(err, domain.ErrShowNotFound) {
+		t.Errorf("missing show error = %v", err)
+	}
+
+	refs, err := store.GetShowRefs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs[2] != (domain.ShowRef{Source: "jellyfin", ExternalID: "abc"}) || len(refs) != 3 {
+		t.Errorf("refs = %+v", refs)
+	}
+
+	// A resync without an ID keeps the mapping and updates the rest
+	err = store.UpsertPlexShow(&domain.PlexShow{PlexID: 1, ExternalID: "1", Title: "Sousou no Frieren", Year: 2023, EpisodeCount: 30, LastUpdated: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	show, err = store.GetPlexShowByPlexID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.Title != "Sousou no Frieren" || show.EpisodeCount != 30 || show.AnilistID == nil || *show.AnilistID != 154587 {
+		t.Errorf("resynced show = %+v", show)
+	}
+
+	// A different item under a used ID is refused and the batch rolled back
+	err = store.UpsertPlexShows([]domain.PlexShow{
+		{PlexID: 4, Source: "jellyfin", ExternalID: "ghi", Title: "New"},
+		{PlexID: 2, Source: "jellyfin", ExternalID: "xyz", Title: "Collision"},
+	})
+	if !errors.Is(err, domain.ErrShowIDCollision) {
+		t.Errorf("collision error = %v", err)
+	}
+	if _, err := store.GetPlexShowByPlexID(4); !errors.Is(err, domain.ErrShowNotFound) {
+		t.Error("failed batch saved a show")
+	}
+
+	// The sync that found the GUID mapping is recorded once
+	history, err := store.GetMappingHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Actor != "sync" || history[0].Action != domain.MappingActionMap {
+		t.Errorf("history = %+v", history)
+	}
+}
+
+func TestPlexShowStoreQueries(t *testing.T) {
+	store := NewPlexShowStore(newTestDatabase(t).DB)
+
+	err := store.UpsertPlexShows([]domain.PlexShow{
+		{PlexID: 1, ExternalID: "1", Title: "Cowboy Bebop", AnilistID: intPtr(1), MappingSource: domain.MappingSourceGUID},
+		{PlexID: 2, ExternalID: "2", Title: "Akira"},
+		{PlexID: 3, ExternalID: "3", Title: "Bebop Extras", AnilistID: intPtr(1), MappingSource: domain.MappingSourceGUID},
+		{PlexID: 4, ExternalID: "4", Title: "Trailers"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetShowIgnored(4, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetShowIgnored(99, true); !errors.Is(err, domain.ErrShowNotFound) {
+		t.Errorf("ignoring a missing show = %v", err)
+	}
+
+	titles := func(shows []domain.PlexShow, err error) []string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, show := range shows {
+			out = append(out, show.Title)
+		}
+		return out
+	}
+	check := func(name string, got []string, want ...string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Errorf("%s = %v, want %v", name, got, want)
+			return
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s = %v, want %v", name, got, want)
+				return
+			}
+		}
+	}
+
+	check("GetAllPlexShows", titles(store.GetAllPlexShows()), "Akira", "Bebop Extras", "Cowboy Bebop", "Trailers")
+	check("GetUnmappedShows", titles(store.GetUnmappedShows()), "Akira")
+	check("GetIgnoredShows", titles(store.GetIgnoredShows()), "Trailers")
+	check("SearchShowsOnServer", titles(store.SearchShowsOnServer("BEBOP")), "Bebop Extras", "Cowboy Bebop")
+
+	ids, err := store.GetMappedAnilistIDs()
+	if err != nil || len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("GetMappedAnilistIDs = %v, %v", ids, err)
+	}
+
+	counts := []struct {
+		name  string
+		count func() (int, error)
+		want  int
+	}{
+		{"GetShowsOnServer", store.GetShowsOnServer, 3},
+		{"GetMappedShowsCount", store.GetMappedShowsCount, 2},
+		{"GetUnmappedShowsCount", store.GetUnmappedShowsCount, 1},
+		{"GetIgnoredShowsCount", store.GetIgnoredShowsCount, 1},
+	}
+	for _, c := range counts {
+		if got, err := c.count(); err != nil || got != c.want {
+			t.Errorf("%s = %d, %v; want %d", c.name, got, err, c.want)
+		}
+	}
+
+	if err := store.MarkAutoMapped(2); err != nil {
+		t.Fatal(err)
+	}
+	if show, err := store.GetPlexShowByPlexID(2); err != nil || show.AutoMappedAt == nil {
+		t.Errorf("MarkAutoMapped not saved: %+v, %v", show, err)
+	}
+}
+
+func TestPlexShowStoreMappingHistory(t *testing.T) {
+	store := NewPlexShowStore(newTestDatabase(t).DB)
+
+	if err := store.UpsertPlexShow(&domain.PlexShow{PlexID: 1, ExternalID: "1", Title: "Show"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateShowMapping(99, 1, domain.MappingSourceManual, "alice"); !errors.Is(err, domain.ErrShowNotFound) {
+		t.Errorf("mapping a missing show = %v", err)
+	}
+	if _, err := store.UndoLastMapping(1, "alice"); !errors.Is(err, domain.ErrNoMappingHistory) {
+		t.Errorf("undo without history = %v", err)
+	}
+
+	if err := store.UpdateShowMapping(1, 10, domain.MappingSourceAuto, "auto"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateShowMapping(1, 20, domain.MappingSourceManual, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A manual mapping locks the show against automatic changes and syncs
+	if err := store.UpdateShowMapping(1, 30, domain.MappingSourceAuto, "auto"); !errors.Is(err, domain.ErrMappingLocked) {
+		t.Errorf("auto mapping a locked show = %v", err)
+	}
+	if err := store.UpsertPlexShow(&domain.PlexShow{PlexID: 1, ExternalID: "1", Title: "Show", AnilistID: intPtr(40), MappingSource: domain.MappingSourceGUID}); err != nil {
+		t.Fatal(err)
+	}
+	show, err := store.GetPlexShowByPlexID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !show.MappingLocked || show.AnilistID == nil || *show.AnilistID != 20 {
+		t.Errorf("locked show = %+v", show)
+	}
+
+	if err := store.ClearShowMapping(1, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	show, err = store.GetPlexShowByPlexID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.AnilistID != nil || show.MappingLocked || show.MappingSource != "" {
+		t.Errorf("cleared show = %+v", show)
+	}
+
+	entry, err := store.UndoLastMapping(1, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Action != domain.MappingActionUndo || entry.NewAnilistID == nil || *entry.NewAnilistID != 20 || entry.NewSource != domain.MappingSourceManual {
+		t.Errorf("undo entry = %+v", entry)
+	}
+	show, err = store.GetPlexShowByPlexID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.AnilistID == nil || *show.AnilistID != 20 || !show.MappingLocked {
+		t.Errorf("show after undo = %+v", show)
+	}
+
+	history, err := store.GetMappingHistory(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := []string{domain.MappingActionUndo, domain.MappingActionUnmap, domain.MappingActionMap, domain.MappingActionMap}
+	if len(history) != len(actions) {
+		t.Fatalf("history = %+v", history)
+	}
+	for i, action := range actions {
+		if history[i].Action != action {
+			t.Errorf("history[%d] = %s, want %s", i, history[i].Action, action)
+		}
+	}
+	if !history[1].Undone || history[1].Actor != "bob" {
+		t.Errorf("unmap entry = %+v", history[1])
+	}
+
+	// The next undo skips the undone unmap and reverts the manual mapping
+	if _, err := store.UndoLastMapping(1, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	show, err = store.GetPlexShowByPlexID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if show.AnilistID == nil || *show.AnilistID != 10 || show.MappingSource != domain.MappingSourceAuto || show.MappingLocked {
+		t.Errorf("show after second undo = %+v", show)
+	}
+}
@@ -0,0 +1,67 @@
+// Package postgres stores the watchlist and synced shows in PostgreSQL, for
+// deployments shared by several people.
+package postgres
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"log"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"anime-watchlist/backend/infrastructure/database"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type Database struct {
+	DB *sql.DB
+}
+
+// New connects to the database at url and applies pending migrations.
+func New(url string) (*Database, error) {
+	db, err := Open(url)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := db.Migrator()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	count, err := migrator.Up()
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate postgres: %w", err)
+	}
+
+	log.Printf("Postgres migration completed successfully (schema version %d, %d applied)", migrator.LatestVersion(), count)
+	return db, nil
+}
+
+// Open connects without migrating.
+func Open(url string) (*Database, error) {
+	db, err := sql.Open("pgx", url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres: %w", err)
+	}
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	return &Database{DB: db}, nil
+}
+
+func (d *Database) Migrator() (*database.Migrator, error) {
+	return database.NewMigratorFrom(d.DB, migrationFiles, database.PostgresParams)
+}
+
+func (d *Database) Close() error {
+	return d.DB.Close()
+}
@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+)
+
+// testDatabaseEnv names a throwaway PostgreSQL database for these tests.
+// Every test drops and recreates the schema, so never point it at real
+// data. Without it the tests are skipped.
+const testDatabaseEnv = "POSTGRES_TEST_URL"
+
+// newTestDatabase connects to the test database and migrates it from an
+// empty schema.
+func newTestDatabase(t *testing.T) *Database {
+	t.Helper()
+
+	url := os.Getenv(testDatabaseEnv)
+	if url == "" {
+		t.Skipf("set %s to run the postgres tests", testDatabaseEnv)
+	}
+
+	db, err := Open(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := db.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrations(t *testing.T) {
+	db := newTestDatabase(t)
+
+	migrator, err := db.Migrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := migrator.CurrentVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != migrator.LatestVersion() {
+		t.Fatalf("schema version %d, want %d", current, migrator.LatestVersion())
+	}
+
+	if count, err := migrator.Up(); err != nil || count != 0 {
+		t.Errorf("second Up applied %d migrations, err %v", count, err)
+	}
+
+	// Every down file reverts its migration so the schema can be rebuilt
+	reverted, err := migrator.Down(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reverted != migrator.LatestVersion() {
+		t.Errorf("reverted %d migrations, want %d", reverted, migrator.LatestVersion())
+	}
+	for _, table := range copyTables {
+		var exists bool
+		if err := db.DB.QueryRow(`SELECT to_regclass($1) IS NOT NULL`, table).Scan(&exists); err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Errorf("table %s left after reverting every migration", table)
+		}
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != migrator.LatestVersion() {
+		t.Errorf("applied %d migrations, want %d", applied, migrator.LatestVersion())
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil || !status.HasDown {
+			t.Errorf("migration %d_%s: %+v", status.Version, status.Name, status)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+var _ domain.WatchlistStore = (*WatchlistStore)(nil)
+
+type WatchlistStore struct {
+	db *sql.DB
+}
+
+func NewWatchlistStore(db *Database) *WatchlistStore {
+	return &WatchlistStore{db: db.DB}
+}
+
+func (s *WatchlistStore) GetWatchlist() ([]domain.WatchlistItem, error) {
+	query := `
+		SELECT id, anilist_id, status, score, progress, started_at, completed_at, added_at
+		FROM watchlist
+		ORDER BY added_at DESC
+	`
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watchlist: %w", err)
+	}
+	defer rows.Close()
+
+	var items []domain.WatchlistItem
+	for rows.Next() {
+		var item domain.WatchlistItem
+		if err := rows.Scan(&item.ID, &item.AnilistID, &item.Status, &item.Score, &item.Progress, &item.StartedAt, &item.CompletedAt, &item.AddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watchlist item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+func (s *WatchlistStore) AddToWatchlist(anilistID int) error {
+	return s.AddToWatchlistAt(anilistID, time.Now())
+}
+
+func (s *WatchlistStore) AddToWatchlistAt(anilistID int, addedAt time.Time) error {
+	query := `
+		INSERT INTO watchlist (anilist_id, added_at)
+		VALUES ($1, $2)
+	`
+
+	if _, err := s.db.Exec(query, anilistID, addedAt); err != nil {
+		return fmt.Errorf("failed to add to watchlist: %w", err)
+	}
+
+	return nil
+}
+
+func (s *WatchlistStore) SaveWatchlistItem(item *domain.WatchlistItem) error {
+	addedAt := item.AddedAt
+	if addedAt.IsZero() {
+		addedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO watchlist (anilist_id, status, score, progress, started_at, completed_at, added_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (anilist_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			score = EXCLUDED.score,
+			progress = EXCLUDED.progress,
+			started_at = EXCLUDED.started_at,
+			completed_at = EXCLUDED.completed_at
+	`
+
+	_, err := s.db.Exec(query, item.AnilistID, item.Status, item.Score, item.Progress, item.StartedAt, item.CompletedAt, addedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save watchlist item: %w", err)
+	}
+
+	return nil
+}
+
+func (s *WatchlistStore) RemoveFromWatchlist(anilistID int) error {
+	result, err := s.db.Exec(`DELETE FROM watchlist WHERE anilist_id = $1`, anilistID)
+	if err != nil {
+		return fmt.Errorf("failed to remove from watchlist: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("anime not in watchlist")
+	}
+
+	return nil
+}
+
+func (s *WatchlistStore) IsInWatchlist(anilistID int) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM watchlist WHERE anilist_id = $1)`, anilistID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check watchlist: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (s *WatchlistStore) GetWatchlistCount() (int, error) {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM watchlist`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to get watchlist count: %w", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"anime-watchlist/backend/domain"
+)
+
+func TestWatchlistStore(t *testing.T) {
+	store := NewWatchlistStore(newTestDatabase(t))
+
+	older := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := store.AddToWatchlistAt(1, older); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddToWatchlist(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddToWatchlist(2); err == nil {
+		t.Error("adding an entry twice succeeded")
+	}
+
+	if in, err := store.IsInWatchlist(1); err != nil || !in {
+		t.Errorf("IsInWatchlist(1) = %v, %v", in, err)
+	}
+	if in, err := store.IsInWatchlist(3); err != nil || in {
+		t.Errorf("IsInWatchlist(3) = %v, %v", in, err)
+	}
+	if count, err := store.GetWatchlistCount(); err != nil || count != 2 {
+		t.Errorf("GetWatchlistCount = %d, %v", count, err)
+	}
+
+	items, err := store.GetWatchlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].AnilistID != 2 || items[1].AnilistID != 1 {
+		t.Fatalf("watchlist = %+v, want newest first", items)
+	}
+	if !items[1].AddedAt.Equal(older) || items[1].Status != domain.WatchStatusCurrent {
+		t.Errorf("defaults = %+v", items[1])
+	}
+
+	started := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	completed := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	err = store.SaveWatchlistItem(&domain.WatchlistItem{
+		AnilistID: 1, Status: domain.WatchStatusCompleted, Score: 8.5, Progress: 12,
+		StartedAt: &started, CompletedAt: &completed,
+		// Ignored for an existing entry
+		AddedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveWatchlistItem(&domain.WatchlistItem{AnilistID: 3, Status: domain.WatchStatusPlanning}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err = store.GetWatchlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := make(map[int]domain.WatchlistItem)
+	for _, item := range items {
+		byID[item.AnilistID] = item
+	}
+	saved := byID[1]
+	if saved.Status != domain.WatchStatusCompleted || saved.Score != 8.5 || saved.Progress != 12 || !saved.AddedAt.Equal(older) {
+		t.Errorf("saved entry = %+v", saved)
+	}
+	if saved.StartedAt == nil || saved.StartedAt.Format("2006-01-02") != "2024-03-01" ||
+		saved.CompletedAt == nil || saved.CompletedAt.Format("2006-01-02") != "2024-03-20" {
+		t.Errorf("saved dates = %v, %v", saved.StartedAt, saved.CompletedAt)
+	}
+	if added, ok := byID[3]; !ok || added.Status != domain.WatchStatusPlanning || added.AddedAt.IsZero() {
+		t.Errorf("new entry = %+v", added)
+	}
+
+	if err := store.RemoveFromWatchlist(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RemoveFromWatchlist(1); err == nil {
+		t.Error("removing a missing entry succeeded")
+	}
+	if count, err := store.GetWatchlistCount(); err != nil || count != 2 {
+		t.Errorf("GetWatchlistCount after remove = %d, %v", count, err)
+	}
+}